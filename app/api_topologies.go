@@ -12,9 +12,20 @@ type APITopologyDesc struct {
 	Name          string            `json:"name"`
 	URL           string            `json:"url"`
 	SubTopologies []APITopologyDesc `json:"sub_topologies,omitempty"`
+	Options       topologyOptions   `json:"options,omitempty"`
 	Stats         *topologyStats    `json:"stats,omitempty"`
 }
 
+// APITopologyOption is one value a topology option can take. Options are
+// selected by passing ?<param>=<value> to the topology's URL.
+type APITopologyOption struct {
+	Value   string `json:"value"`
+	Display string `json:"display"`
+	Default bool   `json:"default,omitempty"`
+}
+
+type topologyOptions map[string][]APITopologyOption
+
 type topologyStats struct {
 	NodeCount          int `json:"node_count"`
 	NonpseudoNodeCount int `json:"nonpseudo_node_count"`
@@ -36,9 +47,10 @@ func makeTopologyList(rep xfer.Reporter) func(w http.ResponseWriter, r *http.Req
 			for subName, subDef := range topologyRegistry {
 				if subDef.parent == name {
 					subTopologies = append(subTopologies, APITopologyDesc{
-						Name:  subDef.human,
-						URL:   "/api/topology/" + subName,
-						Stats: stats(subDef.renderer.Render(rpt)),
+						Name:    subDef.human,
+						URL:     "/api/topology/" + subName,
+						Options: describeOptions(subDef.options),
+						Stats:   stats(subDef.withOptions(r.URL.Query()).renderer.Render(rpt)),
 					})
 				}
 			}
@@ -46,13 +58,31 @@ func makeTopologyList(rep xfer.Reporter) func(w http.ResponseWriter, r *http.Req
 				Name:          def.human,
 				URL:           "/api/topology/" + name,
 				SubTopologies: subTopologies,
-				Stats:         stats(def.renderer.Render(rpt)),
+				Options:       describeOptions(def.options),
+				Stats:         stats(def.withOptions(r.URL.Query()).renderer.Render(rpt)),
 			})
 		}
 		respondWith(w, http.StatusOK, topologies)
 	}
 }
 
+func describeOptions(params optionParams) topologyOptions {
+	if len(params) == 0 {
+		return nil
+	}
+	result := topologyOptions{}
+	for param, options := range params {
+		for i, o := range options {
+			result[param] = append(result[param], APITopologyOption{
+				Value:   o.value,
+				Display: o.human,
+				Default: i == 0,
+			})
+		}
+	}
+	return result
+}
+
 func stats(r render.RenderableNodes) *topologyStats {
 	var (
 		nodes     int
@@ -16,7 +16,7 @@ func TestAPITopology(t *testing.T) {
 	if err := json.Unmarshal(body, &topologies); err != nil {
 		t.Fatalf("JSON parse error: %s", err)
 	}
-	equals(t, 3, len(topologies))
+	equals(t, 6, len(topologies))
 
 	for _, topology := range topologies {
 		is200(t, ts, topology.URL)
@@ -25,6 +25,12 @@ func TestAPITopology(t *testing.T) {
 			is200(t, ts, subTopology.URL)
 		}
 
+		for param, options := range topology.Options {
+			for _, option := range options {
+				is200(t, ts, topology.URL+"?"+param+"="+option.Value)
+			}
+		}
+
 		if have := topology.Stats.EdgeCount; have <= 0 {
 			t.Errorf("EdgeCount isn't positive for %s: %d", topology.Name, have)
 		}
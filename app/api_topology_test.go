@@ -77,6 +77,17 @@ func TestAPITopologyApplications(t *testing.T) {
 			t.Error(test.Diff(want, have))
 		}
 	}
+	{
+		body := getRawJSON(t, ts, "/api/topology/applications?unconnected=show")
+		var topo APITopology
+		if err := json.Unmarshal(body, &topo); err != nil {
+			t.Fatal(err)
+		}
+
+		if want, have := expected.RenderedProcesses, fixNodeMetadatas(topo.Nodes); !reflect.DeepEqual(want, have) {
+			t.Error(test.Diff(want, have))
+		}
+	}
 	{
 		body := getRawJSON(t, ts, "/api/topology/applications/"+expected.ServerProcessID)
 		var node APINode
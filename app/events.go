@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	// eventRetention is how long we remember the events of a container
+	// after the last report which mentioned it.
+	eventRetention = time.Hour
+
+	// maxStoredEvents is the number of events we keep for each container.
+	maxStoredEvents = 100
+)
+
+// APIEvent is an element of the list returned by the /api/events handler.
+type APIEvent struct {
+	HostID      string `json:"host_id"`
+	ContainerID string `json:"container_id"`
+	docker.Event
+}
+
+// eventStore is a collector which remembers the container events in the
+// reports added to it, so they outlive both the collector's window and the
+// containers themselves. The reports it returns carry every event we have
+// for each container, not just the probe's latest few.
+type eventStore struct {
+	collector
+	now func() time.Time
+
+	sync.RWMutex
+	containers map[string]*containerEvents // keyed by container node ID
+}
+
+type containerEvents struct {
+	events   []docker.Event
+	lastSeen time.Time
+}
+
+func newEventStore(c collector) *eventStore {
+	return &eventStore{
+		collector:  c,
+		now:        time.Now,
+		containers: map[string]*containerEvents{},
+	}
+}
+
+// Add implements xfer.Adder.
+func (s *eventStore) Add(rpt report.Report) {
+	s.collector.Add(rpt)
+
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	for nodeID, nmd := range rpt.Container.NodeMetadatas {
+		encoded, ok := nmd.Metadata[docker.ContainerEvents]
+		if !ok {
+			continue
+		}
+		c, ok := s.containers[nodeID]
+		if !ok {
+			c = &containerEvents{}
+			s.containers[nodeID] = c
+		}
+		c.events = mergeEvents(c.events, docker.DecodeEvents(encoded))
+		c.lastSeen = now
+	}
+
+	for nodeID, c := range s.containers {
+		if now.Sub(c.lastSeen) > eventRetention {
+			delete(s.containers, nodeID)
+		}
+	}
+}
+
+// Report implements xfer.Reporter.
+func (s *eventStore) Report() report.Report {
+	rpt := s.collector.Report()
+
+	s.RLock()
+	defer s.RUnlock()
+
+	nmds := report.NodeMetadatas{}
+	for nodeID, nmd := range rpt.Container.NodeMetadatas {
+		if c, ok := s.containers[nodeID]; ok {
+			nmd = nmd.Copy()
+			nmd.Metadata[docker.ContainerEvents] = docker.EncodeEvents(c.events)
+		}
+		nmds[nodeID] = nmd
+	}
+	rpt.Container.NodeMetadatas = nmds
+	return rpt
+}
+
+// Events returns the events we have for every container, oldest first.
+func (s *eventStore) Events() []APIEvent {
+	s.RLock()
+	defer s.RUnlock()
+
+	result := []APIEvent{}
+	for nodeID, c := range s.containers {
+		hostID, containerID, _ := report.ParseNodeID(nodeID)
+		for _, e := range c.events {
+			result = append(result, APIEvent{HostID: hostID, ContainerID: containerID, Event: e})
+		}
+	}
+	sort.Sort(apiEvents(result))
+	return result
+}
+
+// mergeEvents adds the events we haven't seen before to existing, keeping
+// them in time order and dropping the oldest beyond maxStoredEvents.
+func mergeEvents(existing, events []docker.Event) []docker.Event {
+	seen := map[docker.Event]struct{}{}
+	for _, e := range existing {
+		seen[e] = struct{}{}
+	}
+	for _, e := range events {
+		if _, ok := seen[e]; !ok {
+			existing = append(existing, e)
+			seen[e] = struct{}{}
+		}
+	}
+	sort.Stable(byTime(existing))
+	if len(existing) > maxStoredEvents {
+		existing = existing[len(existing)-maxStoredEvents:]
+	}
+	return existing
+}
+
+type byTime []docker.Event
+
+func (e byTime) Len() int           { return len(e) }
+func (e byTime) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byTime) Less(i, j int) bool { return e[i].Time.Before(e[j].Time) }
+
+type apiEvents []APIEvent
+
+func (e apiEvents) Len() int      { return len(e) }
+func (e apiEvents) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e apiEvents) Less(i, j int) bool {
+	if !e[i].Time.Equal(e[j].Time) {
+		return e[i].Time.Before(e[j].Time)
+	}
+	return e[i].ContainerID < e[j].ContainerID
+}
+
+// makeEventsHandler serves the events of every container, or with the
+// container query parameter, of a single container.
+func makeEventsHandler(s *eventStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			containerID = r.URL.Query().Get("container")
+			result      = []APIEvent{}
+		)
+		for _, e := range s.Events() {
+			if containerID == "" || e.ContainerID == containerID {
+				result = append(result, e)
+			}
+		}
+		respondWith(w, http.StatusOK, result)
+	}
+}
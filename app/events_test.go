@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+func reportWithEvents(events string) report.Report {
+	rpt := report.MakeReport()
+	rpt.Container.NodeMetadatas[test.ServerContainerNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ContainerID:     test.ServerContainerID,
+		docker.ContainerEvents: events,
+	})
+	return rpt
+}
+
+func TestEventStore(t *testing.T) {
+	var (
+		now   = time.Unix(1440000000, 0)
+		store = newEventStore(StaticReport{})
+		start = docker.Event{Time: time.Date(2015, 8, 19, 16, 0, 0, 0, time.UTC), Status: "start"}
+		die   = docker.Event{Time: time.Date(2015, 8, 19, 16, 1, 0, 0, time.UTC), Status: "die", ExitCode: "137"}
+	)
+	store.now = func() time.Time { return now }
+
+	// The probe repeats its recent events in every report.
+	store.Add(reportWithEvents("2015-08-19T16:00:00Z start"))
+	store.Add(reportWithEvents("2015-08-19T16:00:00Z start,2015-08-19T16:01:00Z die 137"))
+
+	want := []APIEvent{
+		{HostID: test.ServerHostID, ContainerID: test.ServerContainerID, Event: start},
+		{HostID: test.ServerHostID, ContainerID: test.ServerContainerID, Event: die},
+	}
+	if have := store.Events(); !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+
+	// Reports are decorated with the stored events, without touching the
+	// collector's report.
+	rpt := store.Report()
+	if want, have := "2015-08-19T16:00:00Z start,2015-08-19T16:01:00Z die 137", rpt.Container.NodeMetadatas[test.ServerContainerNodeID].Metadata[docker.ContainerEvents]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if _, ok := test.Report.Container.NodeMetadatas[test.ServerContainerNodeID].Metadata[docker.ContainerEvents]; ok {
+		t.Errorf("fixture report was modified")
+	}
+
+	// Events are forgotten some time after we last heard of the container.
+	now = now.Add(eventRetention + time.Second)
+	store.Add(report.MakeReport())
+	if have := store.Events(); len(have) != 0 {
+		t.Errorf("want no events, have %v", have)
+	}
+}
+
+func TestAPIEvents(t *testing.T) {
+	ts := httptest.NewServer(Router(StaticReport{}))
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(reportWithEvents("2015-08-19T16:01:00Z die 137")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(ts.URL+"/api/report", "application/gob", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for path, want := range map[string]int{
+		"/api/events": 1,
+		"/api/events?container=" + test.ServerContainerID: 1,
+		"/api/events?container=doesntexist":               0,
+	} {
+		var events []APIEvent
+		if err := json.Unmarshal(getRawJSON(t, ts, path), &events); err != nil {
+			t.Fatalf("JSON parse error: %s", err)
+		}
+		if have := len(events); want != have {
+			t.Errorf("%s: want %d events, have %d", path, want, have)
+		}
+	}
+}
@@ -15,10 +15,24 @@ import (
 // some data in the system. The struct is returned by the /api/origin/{id}
 // handler.
 type OriginHost struct {
-	Hostname string   `json:"hostname"`
-	OS       string   `json:"os"`
-	Networks []string `json:"networks"`
-	Load     string   `json:"load"`
+	Hostname        string          `json:"hostname"`
+	OS              string          `json:"os"`
+	Networks        []string        `json:"networks"`
+	Load            string          `json:"load"`
+	CPUUsage        string          `json:"cpu_usage_percent,omitempty"`
+	MemoryUsed      string          `json:"memory_used_bytes,omitempty"`
+	MemoryAvailable string          `json:"memory_available_bytes,omitempty"`
+	DiskReadRate    string          `json:"disk_read_bytes_per_second,omitempty"`
+	DiskWriteRate   string          `json:"disk_write_bytes_per_second,omitempty"`
+	Interfaces      []OriginHostNIC `json:"interfaces,omitempty"`
+}
+
+// OriginHostNIC is the traffic through one of an OriginHost's network
+// interfaces.
+type OriginHostNIC struct {
+	Name   string `json:"name"`
+	RxRate string `json:"rx_bytes_per_second"`
+	TxRate string `json:"tx_bytes_per_second"`
 }
 
 func getOriginHost(t report.Topology, nodeID string) (OriginHost, bool) {
@@ -27,11 +41,26 @@ func getOriginHost(t report.Topology, nodeID string) (OriginHost, bool) {
 		return OriginHost{}, false
 	}
 
+	interfaces := []OriginHostNIC{}
+	for _, name := range host.Interfaces(h) {
+		interfaces = append(interfaces, OriginHostNIC{
+			Name:   name,
+			RxRate: h.Metadata[host.RxRatePrefix+name],
+			TxRate: h.Metadata[host.TxRatePrefix+name],
+		})
+	}
+
 	return OriginHost{
-		Hostname: h.Metadata[host.HostName],
-		OS:       h.Metadata[host.OS],
-		Networks: strings.Split(h.Metadata[host.LocalNetworks], " "),
-		Load:     h.Metadata[host.Load],
+		Hostname:        h.Metadata[host.HostName],
+		OS:              h.Metadata[host.OS],
+		Networks:        strings.Split(h.Metadata[host.LocalNetworks], " "),
+		Load:            h.Metadata[host.Load],
+		CPUUsage:        h.Metadata[host.CPUUsage],
+		MemoryUsed:      h.Metadata[host.MemoryUsed],
+		MemoryAvailable: h.Metadata[host.MemoryAvailable],
+		DiskReadRate:    h.Metadata[host.DiskReadRate],
+		DiskWriteRate:   h.Metadata[host.DiskWriteRate],
+		Interfaces:      interfaces,
 	}, true
 }
 
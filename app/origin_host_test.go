@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test"
 )
 
@@ -31,3 +34,42 @@ func TestAPIOriginHost(t *testing.T) {
 		}
 	}
 }
+
+func TestOriginHostMetrics(t *testing.T) {
+	topology := report.NewTopology()
+	topology.NodeMetadatas[test.ServerHostNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		host.HostName:               test.ServerHostName,
+		host.OS:                     "Linux",
+		host.LocalNetworks:          "10.10.10.0/24",
+		host.Load:                   "0.01 0.01 0.01",
+		host.CPUUsage:               "25.00",
+		host.MemoryUsed:             "3072",
+		host.MemoryAvailable:        "1024",
+		host.DiskReadRate:           "1000.00",
+		host.DiskWriteRate:          "0.00",
+		host.RxRatePrefix + "eth0":  "100.00",
+		host.TxRatePrefix + "eth0":  "20.00",
+		host.RxRatePrefix + "weave": "1.00",
+		host.TxRatePrefix + "weave": "2.00",
+	})
+
+	want := OriginHost{
+		Hostname:        test.ServerHostName,
+		OS:              "Linux",
+		Networks:        []string{"10.10.10.0/24"},
+		Load:            "0.01 0.01 0.01",
+		CPUUsage:        "25.00",
+		MemoryUsed:      "3072",
+		MemoryAvailable: "1024",
+		DiskReadRate:    "1000.00",
+		DiskWriteRate:   "0.00",
+		Interfaces: []OriginHostNIC{
+			{Name: "eth0", RxRate: "100.00", TxRate: "20.00"},
+			{Name: "weave", RxRate: "1.00", TxRate: "2.00"},
+		},
+	}
+	have, ok := getOriginHost(topology, test.ServerHostNodeID)
+	if !ok || !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/gob"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -53,6 +55,9 @@ type collector interface {
 // accepting reports from probes.. It will always use the embedded HTML
 // resources for the UI.
 func Router(c collector) *mux.Router {
+	events := newEventStore(c)
+	c = events
+
 	router := mux.NewRouter()
 	router.HandleFunc("/api/report", makeReportPostHandler(c)).Methods("POST")
 	get := router.Methods("GET").Subrouter()
@@ -64,6 +69,7 @@ func Router(c collector) *mux.Router {
 	get.MatcherFunc(URLMatcher("/api/topology/{topology}/{local}/{remote}")).HandlerFunc(captureTopology(c, handleEdge))
 	get.MatcherFunc(URLMatcher("/api/origin/host/{id}")).HandlerFunc(makeOriginHostHandler(c))
 	get.HandleFunc("/api/report", makeRawReportHandler(c))
+	get.HandleFunc("/api/events", makeEventsHandler(events))
 	get.PathPrefix("/").Handler(http.FileServer(FS(false))) // everything else is static
 	return router
 }
@@ -87,7 +93,7 @@ func captureTopology(rep xfer.Reporter, f func(xfer.Reporter, topologyView, http
 			http.NotFound(w, r)
 			return
 		}
-		f(rep, topology, w, r)
+		f(rep, topology.withOptions(r.URL.Query()), w, r)
 	}
 }
 
@@ -104,32 +110,172 @@ var topologyRegistry = map[string]topologyView{
 	"applications": {
 		human:    "Applications",
 		parent:   "",
-		renderer: render.FilterUnconnected{Renderer: render.ProcessRenderer},
+		renderer: render.ProcessRenderer,
+		options:  unconnectedOptions,
 	},
 	"applications-by-name": {
 		human:    "by name",
 		parent:   "applications",
-		renderer: render.FilterUnconnected{Renderer: render.ProcessNameRenderer},
+		renderer: render.ProcessNameRenderer,
+		options:  unconnectedOptions,
+	},
+	"applications-by-service": {
+		human:    "by service",
+		parent:   "applications",
+		renderer: render.ProcessServiceRenderer,
+		options:  unconnectedOptions,
 	},
 	"containers": {
 		human:    "Containers",
 		parent:   "",
 		renderer: render.ContainerRenderer,
+		options:  stoppedOptions,
 	},
 	"containers-by-image": {
 		human:    "by image",
 		parent:   "containers",
 		renderer: render.ContainerImageRenderer,
+		options:  stoppedOptions.with(imageOptions),
+	},
+	"containers-by-service": {
+		human:    "by service",
+		parent:   "containers",
+		renderer: render.ContainerServiceRenderer,
+		options:  stoppedOptions,
+	},
+	"services": {
+		human:    "Services",
+		parent:   "",
+		renderer: render.ServiceRenderer,
+	},
+	"pods": {
+		human:    "Pods",
+		parent:   "",
+		renderer: render.PodRenderer,
+	},
+	"pods-by-service": {
+		human:    "by service",
+		parent:   "pods",
+		renderer: render.PodServiceRenderer,
 	},
 	"hosts": {
 		human:    "Hosts",
 		parent:   "",
 		renderer: render.HostRenderer,
 	},
+	"overlay": {
+		human:    "Overlay",
+		parent:   "",
+		renderer: render.OverlayRenderer,
+	},
 }
 
 type topologyView struct {
 	human    string
 	parent   string
 	renderer render.Renderer
+	options  optionParams
+}
+
+// optionParams are the options a topology view accepts, keyed by the URL
+// query parameter which selects between them. The first option for each
+// parameter is the default.
+type optionParams map[string][]option
+
+// with returns the params of both p and other.
+func (p optionParams) with(other optionParams) optionParams {
+	result := optionParams{}
+	for _, params := range []optionParams{p, other} {
+		for param, options := range params {
+			result[param] = options
+		}
+	}
+	return result
+}
+
+// option is one value of a topology view parameter, and how it decorates
+// the view's renderer.
+type option struct {
+	value    string
+	human    string
+	decorate func(render.Renderer) render.Renderer
+}
+
+var unconnectedOptions = optionParams{
+	"unconnected": {
+		{"hide", "Hide unconnected", func(r render.Renderer) render.Renderer {
+			return render.FilterUnconnected{Renderer: r}
+		}},
+		{"listening", "Show listening", func(r render.Renderer) render.Renderer {
+			return render.FilterUnconnected{Renderer: r, KeepListening: true}
+		}},
+		{"show", "Show all", func(r render.Renderer) render.Renderer {
+			return r
+		}},
+	},
+}
+
+var stoppedOptions = optionParams{
+	"stopped": {
+		{"hide", "Hide stopped", func(r render.Renderer) render.Renderer {
+			return render.FilterStopped{Renderer: r}
+		}},
+		{"show", "Show stopped", func(r render.Renderer) render.Renderer {
+			return r
+		}},
+	},
+}
+
+// outdatedImageAge is the age beyond which the images of running
+// containers are considered outdated.
+const outdatedImageAge = 90 * 24 * time.Hour
+
+var imageOptions = optionParams{
+	"images": {
+		{"all", "All images", func(r render.Renderer) render.Renderer {
+			return r
+		}},
+		{"in-use", "In use", func(r render.Renderer) render.Renderer {
+			return render.FilterOrigins{Renderer: r, Selector: report.SelectContainerImage, Keep: render.ImageInUse}
+		}},
+		{"outdated", "In use, over 90 days old", func(r render.Renderer) render.Renderer {
+			created := render.ImageCreatedBefore(time.Now().Add(-outdatedImageAge))
+			return render.FilterOrigins{Renderer: r, Selector: report.SelectContainerImage, Keep: func(nmd report.NodeMetadata) bool {
+				return render.ImageInUse(nmd) && created(nmd)
+			}}
+		}},
+	},
+}
+
+// withOptions returns the view with its renderer decorated according to
+// the query parameters. Missing or unknown values select the default. Every
+// view can also be filtered to the hosts with a label, with
+// ?host_label=<key>=<value>, which may be repeated, and grouped by the
+// values of a host label, with ?group_by_host_label=<key>.
+func (t topologyView) withOptions(values url.Values) topologyView {
+	params := []string{}
+	for param := range t.options {
+		params = append(params, param)
+	}
+	sort.Strings(params) // decorate in a stable order
+
+	for _, param := range params {
+		chosen := t.options[param][0]
+		for _, o := range t.options[param] {
+			if o.value == values.Get(param) {
+				chosen = o
+			}
+		}
+		t.renderer = chosen.decorate(t.renderer)
+	}
+
+	for _, label := range values["host_label"] {
+		if parts := strings.SplitN(label, "=", 2); len(parts) == 2 {
+			t.renderer = render.FilterHostLabel{Renderer: t.renderer, Key: parts[0], Value: parts[1]}
+		}
+	}
+	if key := values.Get("group_by_host_label"); key != "" {
+		t.renderer = render.GroupByHostLabel{Renderer: t.renderer, Key: key}
+	}
+	return t
 }
@@ -2,10 +2,13 @@ package main
 
 import (
 	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
 
 	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/scope/render"
 )
 
 type v map[string]string
@@ -28,3 +31,28 @@ func TestURLMatcher(t *testing.T) {
 	test("/a/b/{c}", "/a/b/b", true, v{"c": "b"})
 	test("/a/b/{c}", "/a/b/b%2Fb", true, v{"c": "b/b"})
 }
+
+func TestTopologyViewHostLabels(t *testing.T) {
+	view := topologyRegistry["hosts"].withOptions(url.Values{
+		"host_label":          {"env=prod", "team"},
+		"group_by_host_label": {"zone"},
+	})
+	want := render.GroupByHostLabel{
+		Renderer: render.FilterHostLabel{Renderer: render.HostRenderer, Key: "env", Value: "prod"},
+		Key:      "zone",
+	}
+	if !reflect.DeepEqual(want, view.renderer) {
+		t.Errorf("want %#v, have %#v", want, view.renderer)
+	}
+}
+
+func TestTopologyViewStoppedDefault(t *testing.T) {
+	for _, name := range []string{"containers", "containers-by-image", "containers-by-service"} {
+		if _, ok := topologyRegistry[name].withOptions(url.Values{}).renderer.(render.FilterStopped); !ok {
+			t.Errorf("%s: stopped containers aren't hidden by default", name)
+		}
+		if _, ok := topologyRegistry[name].withOptions(url.Values{"stopped": {"show"}}).renderer.(render.FilterStopped); ok {
+			t.Errorf("%s: stopped containers are hidden with stopped=show", name)
+		}
+	}
+}
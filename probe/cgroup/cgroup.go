@@ -0,0 +1,388 @@
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hooks exposed for mocking
+var (
+	ReadFile = ioutil.ReadFile
+	Now      = time.Now
+)
+
+// DefaultRoot is where the cgroup filesystems are usually mounted.
+const DefaultRoot = "/sys/fs/cgroup"
+
+const (
+	// clockTicks is USER_HZ, the unit of cpuacct.stat and /proc/stat.
+	clockTicks = 100
+
+	// Unified is the key of the cgroup v2 hierarchy in Paths.
+	Unified = ""
+)
+
+// Paths are the cgroups a process belongs to, as directories relative to
+// the cgroup root, keyed by v1 controller (e.g. "memory" ->
+// "memory/docker/abc"). Named v1 hierarchies are keyed by name (e.g.
+// "name=systemd"). The v2 cgroup, if any, has the key Unified.
+type Paths map[string]string
+
+// Systemd is the key of systemd's own v1 hierarchy in Paths.
+const Systemd = "name=systemd"
+
+// Stats are a sample of the resource usage of a cgroup. CPU times are in
+// nanoseconds, and memory and I/O in bytes. Counters which the kernel
+// doesn't provide are left at zero.
+type Stats struct {
+	Read time.Time
+
+	CPUUsage, CPUUser, CPUSystem uint64
+	PercpuUsage                  []uint64 // cgroup v1 only
+	SystemCPUUsage               uint64   // of the whole host, as per /proc/stat
+	CPUs                         int
+
+	MemoryUsage, MemoryMaxUsage uint64
+	MemoryLimit                 uint64 // zero if unlimited
+	MemoryFailcnt               uint64
+
+	BlkioRead, BlkioWrite uint64
+	Pids                  uint64
+
+	Network NetworkStats
+}
+
+// NetworkStats are the counters of all the non-loopback interfaces in the
+// network namespace of a process, added up.
+type NetworkStats struct {
+	RxBytes, RxPackets, RxErrors, RxDropped uint64
+	TxBytes, TxPackets, TxErrors, TxDropped uint64
+}
+
+// Reader reads Stats from the cgroup filesystems. It only relies on the
+// kernel, so it works for the containers of any runtime, given either a
+// process inside the container or the container's cgroup paths.
+type Reader struct {
+	root, procRoot string
+}
+
+// NewReader makes a new Reader for the cgroups mounted under root, finding
+// the cgroups of processes under procRoot.
+func NewReader(root, procRoot string) *Reader {
+	return &Reader{root: root, procRoot: procRoot}
+}
+
+// PathsOf returns the cgroups pid belongs to, from /proc/<pid>/cgroup.
+//
+//   4:cpu,cpuacct:/docker/abc   (v1)
+//   0::/system.slice/docker-abc.scope   (v2)
+func (r *Reader) PathsOf(pid int) (Paths, error) {
+	buf, err := ReadFile(path.Join(r.procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := Paths{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			result[Unified] = fields[2]
+			continue
+		}
+		if strings.HasPrefix(fields[1], "name=") {
+			result[fields[1]] = path.Join(fields[1], fields[2])
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			if controller == "" {
+				continue
+			}
+			result[controller] = path.Join(fields[1], fields[2])
+		}
+	}
+	return result, scanner.Err()
+}
+
+// Read samples the cgroups of pid, and the network interfaces in its
+// namespace.
+func (r *Reader) Read(pid int) (Stats, error) {
+	paths, err := r.PathsOf(pid)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats, err := r.ReadPaths(paths)
+	if err != nil {
+		return Stats{}, err
+	}
+	if buf, err := ReadFile(path.Join(r.procRoot, strconv.Itoa(pid), "net", "dev")); err == nil {
+		stats.Network = parseNetDev(buf)
+	}
+	return stats, nil
+}
+
+// ReadPaths samples the given cgroups. If any of the v1 controllers we know
+// about are there, we read those; otherwise we read the v2 cgroup. Missing
+// files are skipped, but it is an error for there to be nothing to read.
+func (r *Reader) ReadPaths(paths Paths) (Stats, error) {
+	stats := Stats{Read: Now()}
+
+	var found bool
+	if _, ok := paths[Unified]; ok && !hasV1Controllers(paths) {
+		found = r.readV2(path.Join(r.root, paths[Unified]), &stats)
+	} else {
+		found = r.readV1(paths, &stats)
+	}
+	if !found {
+		return Stats{}, fmt.Errorf("no cgroup stats in %v", paths)
+	}
+
+	if buf, err := ReadFile(path.Join(r.procRoot, "stat")); err == nil {
+		stats.SystemCPUUsage, stats.CPUs = parseSystemCPU(buf)
+	}
+	return stats, nil
+}
+
+func hasV1Controllers(paths Paths) bool {
+	for _, controller := range []string{"cpuacct", "memory", "blkio", "pids"} {
+		if _, ok := paths[controller]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reader) readV1(paths Paths, stats *Stats) bool {
+	found := false
+	dir := func(controller string) string {
+		return path.Join(r.root, paths[controller])
+	}
+	read := func(controller, filename string, dst *uint64) {
+		if _, ok := paths[controller]; !ok {
+			return
+		}
+		if v, err := readUint(path.Join(dir(controller), filename)); err == nil {
+			*dst = v
+			found = true
+		}
+	}
+
+	read("cpuacct", "cpuacct.usage", &stats.CPUUsage)
+	if _, ok := paths["cpuacct"]; ok {
+		if buf, err := ReadFile(path.Join(dir("cpuacct"), "cpuacct.usage_percpu")); err == nil {
+			for _, field := range strings.Fields(string(buf)) {
+				v, err := strconv.ParseUint(field, 10, 64)
+				if err != nil {
+					break
+				}
+				stats.PercpuUsage = append(stats.PercpuUsage, v)
+			}
+		}
+		if values, err := readKeyValues(path.Join(dir("cpuacct"), "cpuacct.stat")); err == nil {
+			stats.CPUUser = values["user"] * (uint64(time.Second) / clockTicks)
+			stats.CPUSystem = values["system"] * (uint64(time.Second) / clockTicks)
+		}
+	}
+
+	read("memory", "memory.usage_in_bytes", &stats.MemoryUsage)
+	read("memory", "memory.max_usage_in_bytes", &stats.MemoryMaxUsage)
+	read("memory", "memory.limit_in_bytes", &stats.MemoryLimit)
+	read("memory", "memory.failcnt", &stats.MemoryFailcnt)
+	if stats.MemoryLimit >= 1<<62 {
+		stats.MemoryLimit = 0 // "unlimited" is the largest page-aligned int64
+	}
+
+	// The throttle counters are there regardless of the I/O scheduler.
+	if _, ok := paths["blkio"]; ok {
+		for _, filename := range []string{"blkio.throttle.io_service_bytes", "blkio.io_service_bytes"} {
+			if buf, err := ReadFile(path.Join(dir("blkio"), filename)); err == nil {
+				stats.BlkioRead, stats.BlkioWrite = parseBlkio(buf)
+				found = true
+				break
+			}
+		}
+	}
+
+	read("pids", "pids.current", &stats.Pids)
+	return found
+}
+
+func (r *Reader) readV2(dir string, stats *Stats) bool {
+	found := false
+	read := func(filename string, dst *uint64) {
+		if v, err := readUint(path.Join(dir, filename)); err == nil {
+			*dst = v
+			found = true
+		}
+	}
+
+	if values, err := readKeyValues(path.Join(dir, "cpu.stat")); err == nil {
+		stats.CPUUsage = values["usage_usec"] * uint64(time.Microsecond)
+		stats.CPUUser = values["user_usec"] * uint64(time.Microsecond)
+		stats.CPUSystem = values["system_usec"] * uint64(time.Microsecond)
+		found = true
+	}
+
+	read("memory.current", &stats.MemoryUsage)
+	read("memory.peak", &stats.MemoryMaxUsage)
+	read("memory.max", &stats.MemoryLimit) // "max" doesn't parse, leaving zero
+	if values, err := readKeyValues(path.Join(dir, "memory.events")); err == nil {
+		stats.MemoryFailcnt = values["max"]
+	}
+
+	if buf, err := ReadFile(path.Join(dir, "io.stat")); err == nil {
+		stats.BlkioRead, stats.BlkioWrite = parseIOStat(buf)
+		found = true
+	}
+
+	read("pids.current", &stats.Pids)
+	return found
+}
+
+func readUint(filename string) (uint64, error) {
+	buf, err := ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(buf)), 10, 64)
+}
+
+// readKeyValues reads files of "key value" lines, like cpu.stat.
+func readKeyValues(filename string) (map[string]uint64, error) {
+	buf, err := ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			result[fields[0]] = v
+		}
+	}
+	return result, scanner.Err()
+}
+
+// parseBlkio adds up the bytes read and written from a v1 io_service_bytes
+// file.
+//
+//   8:0 Read 1234
+//   8:0 Write 5678
+//   Total 6912
+func parseBlkio(buf []byte) (read, write uint64) {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += v
+		case "Write":
+			write += v
+		}
+	}
+	return read, write
+}
+
+// parseIOStat adds up the bytes read and written from a v2 io.stat file.
+//
+//   8:0 rbytes=1234 wbytes=5678 rios=1 wios=2 dbytes=0 dios=0
+func parseIOStat(buf []byte) (read, write uint64) {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				read += v
+			case "wbytes":
+				write += v
+			}
+		}
+	}
+	return read, write
+}
+
+// parseSystemCPU returns the CPU time of the whole host in nanoseconds, as
+// the Docker daemon calculates it, and the number of CPUs, from /proc/stat.
+func parseSystemCPU(buf []byte) (uint64, int) {
+	var (
+		total uint64
+		cpus  int
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			cpus++
+			continue
+		}
+		for _, field := range fields[1:8] {
+			ticks, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0
+			}
+			total += ticks
+		}
+	}
+	return total * (uint64(time.Second) / clockTicks), cpus
+}
+
+// parseNetDev adds up the counters in /proc/<pid>/net/dev, skipping the
+// loopback interface.
+func parseNetDev(buf []byte) NetworkStats {
+	var result NetworkStats
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue // header
+		}
+		name, fields := strings.TrimSpace(parts[0]), strings.Fields(parts[1])
+		if name == "lo" || len(fields) < 12 {
+			continue
+		}
+		var values [12]uint64
+		for i := range values {
+			values[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		result.RxBytes += values[0]
+		result.RxPackets += values[1]
+		result.RxErrors += values[2]
+		result.RxDropped += values[3]
+		result.TxBytes += values[8]
+		result.TxPackets += values[9]
+		result.TxErrors += values[10]
+		result.TxDropped += values[11]
+	}
+	return result
+}
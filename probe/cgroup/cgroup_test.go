@@ -0,0 +1,171 @@
+package cgroup_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/cgroup"
+	"github.com/weaveworks/scope/test"
+)
+
+var now = time.Unix(1440000000, 0)
+
+const (
+	procStat = `cpu  100 0 100 700 100 0 0 0 0 0
+cpu0 50 0 50 350 50 0 0 0 0 0
+cpu1 50 0 50 350 50 0 0 0 0 0
+btime 1439999000
+`
+	netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:     100       1    0    0    0     0          0         0      100       1    0    0    0     0       0          0
+  eth0:    2000      20    1    2    0     0          0         0     3000      30    3    4    0     0       0          0
+`
+)
+
+// v1 is a cgroup v1 tree, with cpu and cpuacct mounted together, as
+// Docker sets it up.
+var v1 = map[string]string{
+	"/proc/stat":      procStat,
+	"/proc/1/net/dev": netDev,
+	"/proc/1/cgroup": `11:pids:/docker/abc
+9:blkio:/docker/abc
+4:memory:/docker/abc
+3:cpu,cpuacct:/docker/abc
+1:name=systemd:/docker/abc
+`,
+	"/sys/fs/cgroup/cpu,cpuacct/docker/abc/cpuacct.usage":        "3000000000\n",
+	"/sys/fs/cgroup/cpu,cpuacct/docker/abc/cpuacct.usage_percpu": "1000000000 2000000000 \n",
+	"/sys/fs/cgroup/cpu,cpuacct/docker/abc/cpuacct.stat":         "user 200\nsystem 100\n",
+	"/sys/fs/cgroup/memory/docker/abc/memory.usage_in_bytes":     "10485760\n",
+	"/sys/fs/cgroup/memory/docker/abc/memory.max_usage_in_bytes": "20971520\n",
+	"/sys/fs/cgroup/memory/docker/abc/memory.limit_in_bytes":     "9223372036854771712\n",
+	"/sys/fs/cgroup/memory/docker/abc/memory.failcnt":            "0\n",
+	"/sys/fs/cgroup/blkio/docker/abc/blkio.throttle.io_service_bytes": `8:0 Read 4096
+8:0 Write 8192
+8:16 Read 1024
+8:16 Write 0
+Total 13312
+`,
+	"/sys/fs/cgroup/pids/docker/abc/pids.current": "4\n",
+}
+
+// v2 is a unified cgroup tree, as a systemd-managed runtime (e.g. CRI-O)
+// sets it up.
+var v2 = map[string]string{
+	"/proc/stat":      procStat,
+	"/proc/1/net/dev": netDev,
+	"/proc/1/cgroup":  "0::/kubepods.slice/crio-abc.scope\n",
+	"/sys/fs/cgroup/kubepods.slice/crio-abc.scope/cpu.stat": `usage_usec 3000000
+user_usec 2000000
+system_usec 1000000
+nr_periods 0
+`,
+	"/sys/fs/cgroup/kubepods.slice/crio-abc.scope/memory.current": "10485760\n",
+	"/sys/fs/cgroup/kubepods.slice/crio-abc.scope/memory.peak":    "20971520\n",
+	"/sys/fs/cgroup/kubepods.slice/crio-abc.scope/memory.max":     "536870912\n",
+	"/sys/fs/cgroup/kubepods.slice/crio-abc.scope/memory.events":  "low 0\nhigh 0\nmax 2\noom 0\noom_kill 0\n",
+	"/sys/fs/cgroup/kubepods.slice/crio-abc.scope/io.stat": `8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0
+8:16 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0
+`,
+	"/sys/fs/cgroup/kubepods.slice/crio-abc.scope/pids.current": "4\n",
+}
+
+func stubFiles(files map[string]string) func() {
+	oldReadFile, oldNow := cgroup.ReadFile, cgroup.Now
+	cgroup.ReadFile = func(filename string) ([]byte, error) {
+		if contents, ok := files[filename]; ok {
+			return []byte(contents), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	cgroup.Now = func() time.Time { return now }
+	return func() { cgroup.ReadFile, cgroup.Now = oldReadFile, oldNow }
+}
+
+func TestPathsOf(t *testing.T) {
+	defer stubFiles(v1)()
+
+	have, err := cgroup.NewReader(cgroup.DefaultRoot, "/proc").PathsOf(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cgroup.Paths{
+		"pids":    "pids/docker/abc",
+		"blkio":   "blkio/docker/abc",
+		"memory":  "memory/docker/abc",
+		"cpu":     "cpu,cpuacct/docker/abc",
+		"cpuacct": "cpu,cpuacct/docker/abc",
+
+		cgroup.Systemd: "name=systemd/docker/abc",
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestRead(t *testing.T) {
+	network := cgroup.NetworkStats{
+		RxBytes: 2000, RxPackets: 20, RxErrors: 1, RxDropped: 2,
+		TxBytes: 3000, TxPackets: 30, TxErrors: 3, TxDropped: 4,
+	}
+
+	for name, tc := range map[string]struct {
+		files map[string]string
+		want  cgroup.Stats
+	}{
+		"v1": {v1, cgroup.Stats{
+			Read:           now,
+			CPUUsage:       3000000000,
+			CPUUser:        2000000000,
+			CPUSystem:      1000000000,
+			PercpuUsage:    []uint64{1000000000, 2000000000},
+			SystemCPUUsage: 10000000000,
+			CPUs:           2,
+			MemoryUsage:    10485760,
+			MemoryMaxUsage: 20971520,
+			BlkioRead:      5120,
+			BlkioWrite:     8192,
+			Pids:           4,
+			Network:        network,
+		}},
+		"v2": {v2, cgroup.Stats{
+			Read:           now,
+			CPUUsage:       3000000000,
+			CPUUser:        2000000000,
+			CPUSystem:      1000000000,
+			SystemCPUUsage: 10000000000,
+			CPUs:           2,
+			MemoryUsage:    10485760,
+			MemoryMaxUsage: 20971520,
+			MemoryLimit:    536870912,
+			MemoryFailcnt:  2,
+			BlkioRead:      5120,
+			BlkioWrite:     8192,
+			Pids:           4,
+			Network:        network,
+		}},
+	} {
+		func() {
+			defer stubFiles(tc.files)()
+			have, err := cgroup.NewReader(cgroup.DefaultRoot, "/proc").Read(1)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !reflect.DeepEqual(tc.want, have) {
+				t.Errorf("%s: %s", name, test.Diff(tc.want, have))
+			}
+		}()
+	}
+}
+
+func TestReadPathsMissing(t *testing.T) {
+	defer stubFiles(v2)()
+
+	reader := cgroup.NewReader(cgroup.DefaultRoot, "/proc")
+	if _, err := reader.ReadPaths(cgroup.Paths{cgroup.Unified: "/gone.scope"}); err == nil {
+		t.Errorf("expected an error for a cgroup which doesn't exist")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/weaveworks/scope/probe/filter"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/process"
+)
+
+var readFile = ioutil.ReadFile
+
+// config is everything about what the probe reports, and where to. It
+// starts off from the flags, and the config file, if any, overrides them.
+// All of it can be changed by reloading the config file; flags which aren't
+// here, such as -proc.root and -http.listen, need a restart.
+type config struct {
+	Targets         []string `json:"targets"`
+	Token           string   `json:"token"`
+	PublishInterval duration `json:"publish_interval"`
+	SpyInterval     duration `json:"spy_interval"`
+	ReporterTimeout duration `json:"reporter_timeout"`
+	Processes       bool     `json:"processes"`
+
+	// Services are the rules for naming the service each process is part
+	// of, tried in order.
+	Services []process.ServiceRule `json:"services"`
+
+	Host struct {
+		Labels         map[string]string `json:"labels"`
+		LabelsFile     string            `json:"labels_file"`
+		MetadataURL    string            `json:"metadata_url"`
+		LabelsInterval duration          `json:"labels_interval"`
+	} `json:"host"`
+
+	Docker struct {
+		Enabled   bool     `json:"enabled"`
+		Interval  duration `json:"interval"`
+		Bridge    string   `json:"bridge"`
+		Host      string   `json:"host"`
+		CertPath  string   `json:"cert_path"`
+		TLSVerify bool     `json:"tls_verify"`
+		Stats     string   `json:"stats"`
+	} `json:"docker"`
+
+	CRI struct {
+		Enabled  bool     `json:"enabled"`
+		Endpoint string   `json:"endpoint"`
+		Interval duration `json:"interval"`
+	} `json:"cri"`
+
+	Cgroup struct {
+		Root       string   `json:"root"`
+		Containers bool     `json:"containers"`
+		Patterns   []string `json:"patterns"`
+	} `json:"cgroup"`
+
+	Systemd struct {
+		Enabled bool `json:"enabled"`
+		DBus    bool `json:"dbus"`
+	} `json:"systemd"`
+
+	Kubernetes struct {
+		Enabled  bool     `json:"enabled"`
+		API      string   `json:"api"`
+		Interval duration `json:"interval"`
+	} `json:"kubernetes"`
+
+	Weave struct {
+		RouterAddr string `json:"router_addr"`
+	} `json:"weave"`
+
+	Plugins struct {
+		Root    string   `json:"root"`
+		Timeout duration `json:"timeout"`
+	} `json:"plugins"`
+
+	Capture struct {
+		Enabled    bool     `json:"enabled"`
+		Interfaces string   `json:"interfaces"`
+		On         duration `json:"on"`
+		Off        duration `json:"off"`
+	} `json:"capture"`
+
+	Filter filter.Config `json:"filter"`
+}
+
+// load returns the config with the config file at path, if any, on top.
+// Settings the file doesn't mention are left as they are. The file is YAML
+// if its name ends in .yaml or .yml, and JSON otherwise.
+func (c config) load(path string) (config, error) {
+	if path == "" {
+		return c, c.validate()
+	}
+	buf, err := readFile(path)
+	if err != nil {
+		return c, err
+	}
+	// Copy the slices and maps, so the file can't change them under the
+	// original.
+	c.Targets = append([]string(nil), c.Targets...)
+	c.Cgroup.Patterns = append([]string(nil), c.Cgroup.Patterns...)
+	c.Services = append([]process.ServiceRule(nil), c.Services...)
+	labels := map[string]string{}
+	for key, value := range c.Host.Labels {
+		labels[key] = value
+	}
+	c.Host.Labels = labels
+	c.Filter.Exclude = append([]filter.Exclude(nil), c.Filter.Exclude...)
+	c.Filter.Redact = append([]filter.Redact(nil), c.Filter.Redact...)
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, &c)
+	default:
+		err = json.Unmarshal(buf, &c)
+	}
+	if err != nil {
+		return c, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, c.validate()
+}
+
+func (c config) validate() error {
+	if len(c.Targets) == 0 {
+		return fmt.Errorf("no targets")
+	}
+	for name, interval := range map[string]duration{
+		"publish_interval":     c.PublishInterval,
+		"spy_interval":         c.SpyInterval,
+		"reporter_timeout":     c.ReporterTimeout,
+		"host.labels_interval": c.Host.LabelsInterval,
+		"docker.interval":      c.Docker.Interval,
+		"cri.interval":         c.CRI.Interval,
+		"kubernetes.interval":  c.Kubernetes.Interval,
+		"plugins.timeout":      c.Plugins.Timeout,
+	} {
+		if interval <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	for key := range c.Host.Labels {
+		if err := host.ValidateLabelKey(key); err != nil {
+			return fmt.Errorf("host labels: %v", err)
+		}
+	}
+	if _, err := process.NewServiceTagger("", c.Services); err != nil {
+		return fmt.Errorf("services: %v", err)
+	}
+	switch c.Docker.Stats {
+	case "api", "cgroup":
+	default:
+		return fmt.Errorf("unknown docker stats %q", c.Docker.Stats)
+	}
+	if _, err := filter.New(c.Filter); err != nil {
+		return fmt.Errorf("filter: %v", err)
+	}
+	return nil
+}
+
+// watchConfig sends on the returned channel whenever the file at path
+// changes, going by its modification time and size.
+func watchConfig(path string, interval time.Duration, quit <-chan struct{}) <-chan struct{} {
+	changed := make(chan struct{})
+	go func() {
+		last, _ := os.Stat(path)
+		t := tick(interval)
+		for {
+			select {
+			case <-t:
+			case <-quit:
+				return
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Printf("config: %v", err)
+				continue
+			}
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+			select {
+			case changed <- struct{}{}:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return changed
+}
+
+// duration is a time.Duration which is a string, such as "10s", in JSON.
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(buf []byte) error {
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+func testConfig() config {
+	c := config{
+		Targets:         []string{"192.168.0.1:4040"},
+		Token:           "secret",
+		PublishInterval: duration(3 * time.Second),
+		SpyInterval:     duration(time.Second),
+		ReporterTimeout: duration(time.Second),
+		Processes:       true,
+	}
+	c.Host.Labels = map[string]string{"env": "prod"}
+	c.Host.LabelsInterval = duration(time.Minute)
+	c.Docker.Stats = "api"
+	c.Docker.Interval = duration(10 * time.Second)
+	c.CRI.Interval = duration(10 * time.Second)
+	c.Kubernetes.Interval = duration(10 * time.Second)
+	c.Plugins.Timeout = duration(time.Second)
+	return c
+}
+
+func TestConfigLoad(t *testing.T) {
+	oldReadFile := readFile
+	defer func() { readFile = oldReadFile }()
+
+	file := `{"targets": ["192.168.0.2:4040"], "spy_interval": "2s", "host": {"labels": {"team": "storage"}}, "docker": {"enabled": true}, "weave": {"router_addr": "10.0.0.1"}}`
+	readFile = func(string) ([]byte, error) { return []byte(file), nil }
+
+	flags := testConfig()
+	have, err := flags.load("probe.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testConfig()
+	want.Targets = []string{"192.168.0.2:4040"}
+	want.SpyInterval = duration(2 * time.Second)
+	want.Host.Labels = map[string]string{"env": "prod", "team": "storage"}
+	want.Docker.Enabled = true
+	want.Weave.RouterAddr = "10.0.0.1"
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	if want := testConfig(); !reflect.DeepEqual(want, flags) {
+		t.Errorf("flags changed: %s", test.Diff(want, flags))
+	}
+
+	// The same config, in YAML.
+	file = "targets:\n  - 192.168.0.2:4040\nspy_interval: 2s\nhost:\n  labels:\n    team: storage\ndocker:\n  enabled: true\nweave:\n  router_addr: 10.0.0.1\n"
+	for _, path := range []string{"probe.yaml", "probe.yml"} {
+		have, err := flags.load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: %s", path, test.Diff(want, have))
+		}
+	}
+	if _, err := flags.load("probe.json"); err == nil {
+		t.Error("YAML in a .json file: expected an error")
+	}
+
+	for _, invalid := range []string{
+		`{"spy_interval": "soon"}`,
+		`{"publish_interval": "0s"}`,
+		`{"docker": {"interval": "0s"}}`,
+		`{"cri": {"interval": "-1s"}}`,
+		`{"kubernetes": {"interval": "0s"}}`,
+		`{"plugins": {"timeout": "0s"}}`,
+		`{"targets": []}`,
+		`{"docker": {"stats": "guess"}}`,
+		`{"host": {"labels": {"my env": "prod"}}}`,
+		`{"services": [{"cmdline": "("}]}`,
+		`{"filter": {"redact": [{"pattern": "("}]}}`,
+		`not json`,
+	} {
+		file = invalid
+		if _, err := flags.load("probe.json"); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+
+	// Flags are validated too, without a config file.
+	flags.Kubernetes.Interval = duration(-time.Second)
+	if _, err := flags.load(""); err == nil {
+		t.Error("negative kubernetes.interval flag: expected an error")
+	}
+}
+
+func TestProbeApply(t *testing.T) {
+	p := newProbe("host", "host", "/proc", nil)
+	defer p.stop()
+
+	c := testConfig()
+	if err := p.apply(c); err != nil {
+		t.Fatal(err)
+	}
+	endpoint, publishers := p.components["endpoint"], p.publishers
+
+	// Enabling weave starts it, and leaves everything else be.
+	c.Weave.RouterAddr = "10.0.0.1"
+	if err := p.apply(c); err != nil {
+		t.Fatal(err)
+	}
+	if have := p.components["endpoint"]; have != endpoint {
+		t.Errorf("endpoint reporter restarted")
+	}
+	if have := p.publishers; have != publishers {
+		t.Errorf("publishers restarted")
+	}
+	if have := len(p.components["weave"].reporters); have != 1 {
+		t.Errorf("want 1 weave reporter, have %d", have)
+	}
+
+	// Changing the targets restarts the publishers.
+	c.Targets = []string{"192.168.0.2:4040"}
+	if err := p.apply(c); err != nil {
+		t.Fatal(err)
+	}
+	if have := p.publishers; have == publishers {
+		t.Errorf("publishers not restarted")
+	}
+
+	// A component which fails to start means nothing changes.
+	weave := p.components["weave"]
+	bad := c
+	bad.Weave.RouterAddr = ""
+	bad.Cgroup.Containers = true
+	bad.Cgroup.Patterns = []string{"no equals sign"}
+	if err := p.apply(bad); err == nil {
+		t.Errorf("expected an error")
+	}
+	if have := p.components["weave"]; have != weave {
+		t.Errorf("weave restarted")
+	}
+	if !reflect.DeepEqual(c, p.config) {
+		t.Error(test.Diff(c, p.config))
+	}
+}
+
+func TestProbeServeHTTP(t *testing.T) {
+	p := newProbe("host", "host", "/proc", nil)
+	defer p.stop()
+	if err := p.apply(testConfig()); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/config", nil))
+	var have map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &have); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "<redacted>", have["token"]; want != have {
+		t.Errorf("want token %q, have %q", want, have)
+	}
+	if want, have := "1s", have["spy_interval"]; want != have {
+		t.Errorf("want spy_interval %q, have %q", want, have)
+	}
+}
+
+type containerTagger struct{}
+
+func (containerTagger) Tag(r report.Report) (report.Report, error) {
+	r.Container.NodeMetadatas[report.MakeContainerNodeID("host", "abc")] = report.MakeNodeMetadata()
+	return r, nil
+}
+
+func TestProbeSpyTagsHost(t *testing.T) {
+	p := newProbe("host", "host.example.com", "/proc", nil)
+	defer p.stop()
+	if err := p.apply(testConfig()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nodes added by taggers are tagged with the host too.
+	p.components["cgroup"] = &component{taggers: []Tagger{containerTagger{}}}
+	r := p.spy(report.MakeReport())
+	md := r.Container.NodeMetadatas[report.MakeContainerNodeID("host", "abc")]
+	for key, want := range map[string]string{
+		report.HostNodeID:        report.MakeHostNodeID("host"),
+		host.HostName:            "host.example.com",
+		host.LabelPrefix + "env": "prod",
+	} {
+		if have := md.Metadata[key]; want != have {
+			t.Errorf("%s: want %q, have %q", key, want, have)
+		}
+	}
+}
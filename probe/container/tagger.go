@@ -0,0 +1,159 @@
+package container
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/scope/probe/cgroup"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// Runtime is the node metadata key for the runtime a container was detected
+// as belonging to, going by its cgroup.
+const Runtime = "container_runtime"
+
+// Pattern recognises the cgroups of a runtime's containers. The first
+// submatch of Regexp is the container ID; if it has a submatch called name,
+// that's the container's name.
+type Pattern struct {
+	Runtime string
+	Regexp  *regexp.Regexp
+}
+
+// Patterns is a flag.Value, for a list of patterns given as runtime=regexp.
+type Patterns []Pattern
+
+// DefaultPatterns are the cgroup layouts of the runtimes we know about.
+var DefaultPatterns = Patterns{
+	{"docker", regexp.MustCompile(`/docker[-/]([0-9a-f]{64})`)},
+	{"podman", regexp.MustCompile(`/libpod-([0-9a-f]{64})\.scope`)},
+	{"cri-o", regexp.MustCompile(`/crio-([0-9a-f]{64})\.scope`)},
+	{"containerd", regexp.MustCompile(`/cri-containerd-([0-9a-f]{64})\.scope`)},
+	{"lxc", regexp.MustCompile(`/lxc(?:\.payload\.|\.payload/|/)([^/]+)`)},
+	{"systemd-nspawn", regexp.MustCompile(`/machine\.slice/machine-([^/]+)\.scope`)},
+}
+
+func (p *Patterns) String() string {
+	patterns := []string{}
+	for _, pattern := range *p {
+		patterns = append(patterns, pattern.Runtime+"="+pattern.Regexp.String())
+	}
+	return strings.Join(patterns, " ")
+}
+
+// Set implements flag.Value. Each call adds a pattern.
+func (p *Patterns) Set(value string) error {
+	fields := strings.SplitN(value, "=", 2)
+	if len(fields) != 2 || fields[0] == "" {
+		return fmt.Errorf("expected runtime=regexp, got %q", value)
+	}
+	re, err := regexp.Compile(fields[1])
+	if err != nil {
+		return err
+	}
+	if re.NumSubexp() < 1 {
+		return fmt.Errorf("%q has no submatch for the container ID", fields[1])
+	}
+	*p = append(*p, Pattern{Runtime: fields[0], Regexp: re})
+	return nil
+}
+
+// match returns the runtime, ID and name of the container with the given
+// cgroup paths, if any pattern matches them. The v2 cgroup is tried first,
+// then the v1 controllers in order.
+func (p Patterns) match(paths cgroup.Paths) (runtime, id, name string, ok bool) {
+	controllers := []string{}
+	for controller := range paths {
+		controllers = append(controllers, controller)
+	}
+	sort.Strings(controllers)
+
+	for _, pattern := range p {
+		for _, controller := range controllers {
+			submatches := pattern.Regexp.FindStringSubmatch(paths[controller])
+			if submatches == nil || submatches[1] == "" {
+				continue
+			}
+			id, name = submatches[1], shortID(submatches[1])
+			for i, subexp := range pattern.Regexp.SubexpNames() {
+				if subexp == "name" && submatches[i] != "" {
+					name = submatches[i]
+				}
+			}
+			return pattern.Runtime, id, name, true
+		}
+	}
+	return "", "", "", false
+}
+
+// shortID abbreviates long hex IDs, as the Docker client does.
+func shortID(id string) string {
+	if len(id) == 64 {
+		return id[:12]
+	}
+	return id
+}
+
+// Tagger works out which container each process belongs to from its cgroups,
+// so we can show the containers of runtimes we have no API for. It only
+// tags processes other taggers haven't, and only adds container nodes for
+// containers no reporter knows of: it's a fallback, so should go after the
+// runtime taggers.
+type Tagger struct {
+	cgroups  *cgroup.Reader
+	patterns Patterns
+	hostID   string
+}
+
+// NewTagger returns a usable Tagger.
+func NewTagger(cgroups *cgroup.Reader, patterns Patterns, hostID string) *Tagger {
+	return &Tagger{
+		cgroups:  cgroups,
+		patterns: patterns,
+		hostID:   hostID,
+	}
+}
+
+// Tag implements Tagger.
+func (t *Tagger) Tag(r report.Report) (report.Report, error) {
+	for nodeID, nmd := range r.Process.NodeMetadatas {
+		if _, ok := nmd.Metadata[docker.ContainerID]; ok {
+			continue
+		}
+		pid, err := strconv.Atoi(nmd.Metadata[process.PID])
+		if err != nil {
+			continue
+		}
+		paths, err := t.cgroups.PathsOf(pid)
+		if err != nil {
+			// The process has probably gone away.
+			continue
+		}
+		runtime, id, name, ok := t.patterns.match(paths)
+		if !ok {
+			continue
+		}
+
+		r.Process.NodeMetadatas[nodeID].Merge(report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID: id,
+		}))
+
+		containerNodeID := report.MakeContainerNodeID(t.hostID, id)
+		if _, ok := r.Container.NodeMetadatas[containerNodeID]; ok {
+			continue
+		}
+		r.Container.NodeMetadatas[containerNodeID] = report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:    id,
+			docker.ContainerName:  name,
+			docker.ContainerState: "running",
+			Runtime:               runtime,
+			report.HostNodeID:     report.MakeHostNodeID(t.hostID),
+		})
+	}
+	return r, nil
+}
@@ -0,0 +1,171 @@
+package container_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/cgroup"
+	"github.com/weaveworks/scope/probe/container"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+const (
+	dockerID = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	podmanID = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+)
+
+var cgroups = map[string]string{
+	"/proc/1/cgroup": "0::/init.scope\n",
+	"/proc/2/cgroup": "4:memory:/docker/" + dockerID + "\n3:cpu,cpuacct:/docker/" + dockerID + "\n",
+	"/proc/3/cgroup": "0::/machine.slice/libpod-" + podmanID + ".scope\n",
+	"/proc/4/cgroup": "0::/lxc.payload.web1\n",
+	"/proc/5/cgroup": "0::/lxc.monitor.web1\n",
+	"/proc/6/cgroup": "0::/machine.slice/machine-build.scope\n",
+	"/proc/7/cgroup": "0::/docker/" + dockerID + "\n",
+}
+
+func stubCgroups() func() {
+	oldReadFile := cgroup.ReadFile
+	cgroup.ReadFile = func(filename string) ([]byte, error) {
+		if contents, ok := cgroups[filename]; ok {
+			return []byte(contents), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	return func() { cgroup.ReadFile = oldReadFile }
+}
+
+func processReport(pids ...string) report.Report {
+	r := report.MakeReport()
+	for _, pid := range pids {
+		r.Process.NodeMetadatas[report.MakeProcessNodeID("host", pid)] = report.MakeNodeMetadataWith(map[string]string{process.PID: pid})
+	}
+	return r
+}
+
+func TestTagger(t *testing.T) {
+	defer stubCgroups()()
+
+	r := processReport("1", "2", "3", "4", "5", "6", "8")
+	tagger := container.NewTagger(cgroup.NewReader(cgroup.DefaultRoot, "/proc"), container.DefaultPatterns, "host")
+	r, err := tagger.Tag(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantContainers := map[string]string{
+		"1": "",
+		"2": dockerID,
+		"3": podmanID,
+		"4": "web1",
+		"5": "",
+		"6": "build",
+		"8": "",
+	}
+	for pid, want := range wantContainers {
+		if have := r.Process.NodeMetadatas[report.MakeProcessNodeID("host", pid)].Metadata[docker.ContainerID]; want != have {
+			t.Errorf("pid %s: want container %q, have %q", pid, want, have)
+		}
+	}
+
+	want := report.NodeMetadatas{
+		report.MakeContainerNodeID("host", dockerID): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:    dockerID,
+			docker.ContainerName:  "0123456789ab",
+			docker.ContainerState: "running",
+			container.Runtime:     "docker",
+			report.HostNodeID:     report.MakeHostNodeID("host"),
+		}),
+		report.MakeContainerNodeID("host", podmanID): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:    podmanID,
+			docker.ContainerName:  "fedcba987654",
+			docker.ContainerState: "running",
+			container.Runtime:     "podman",
+			report.HostNodeID:     report.MakeHostNodeID("host"),
+		}),
+		report.MakeContainerNodeID("host", "web1"): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:    "web1",
+			docker.ContainerName:  "web1",
+			docker.ContainerState: "running",
+			container.Runtime:     "lxc",
+			report.HostNodeID:     report.MakeHostNodeID("host"),
+		}),
+		report.MakeContainerNodeID("host", "build"): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:    "build",
+			docker.ContainerName:  "build",
+			docker.ContainerState: "running",
+			container.Runtime:     "systemd-nspawn",
+			report.HostNodeID:     report.MakeHostNodeID("host"),
+		}),
+	}
+	if have := r.Container.NodeMetadatas; !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestTaggerFallback(t *testing.T) {
+	defer stubCgroups()()
+
+	// Another tagger has already found pid 2's container, and a reporter
+	// knows about pid 7's.
+	r := processReport("2", "7")
+	r.Process.NodeMetadatas[report.MakeProcessNodeID("host", "2")].Metadata[docker.ContainerID] = "abc"
+	existing := report.MakeNodeMetadataWith(map[string]string{
+		docker.ContainerID:   dockerID,
+		docker.ContainerName: "web",
+	})
+	r.Container.NodeMetadatas[report.MakeContainerNodeID("host", dockerID)] = existing
+
+	r, err := container.NewTagger(cgroup.NewReader(cgroup.DefaultRoot, "/proc"), container.DefaultPatterns, "host").Tag(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := r.Process.NodeMetadatas[report.MakeProcessNodeID("host", "2")].Metadata[docker.ContainerID]; have != "abc" {
+		t.Errorf("want container abc, have %q", have)
+	}
+	if have := r.Process.NodeMetadatas[report.MakeProcessNodeID("host", "7")].Metadata[docker.ContainerID]; have != dockerID {
+		t.Errorf("want container %s, have %q", dockerID, have)
+	}
+	want := report.NodeMetadatas{report.MakeContainerNodeID("host", dockerID): existing}
+	if have := r.Container.NodeMetadatas; !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	defer stubCgroups()()
+
+	patterns := container.Patterns{}
+	for _, bad := range []string{"lxc", "=/lxc/(.*)", "lxc=/lxc/.*", "lxc=/lxc/(.*"} {
+		if err := patterns.Set(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	if err := patterns.Set(`mine=/machine\.slice/machine-(?P<name>[a-z]+)\.scope`); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `mine=/machine\.slice/machine-(?P<name>[a-z]+)\.scope`, patterns.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	r, err := container.NewTagger(cgroup.NewReader(cgroup.DefaultRoot, "/proc"), patterns, "host").Tag(processReport("2", "6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := report.NodeMetadatas{
+		report.MakeContainerNodeID("host", "build"): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:    "build",
+			docker.ContainerName:  "build",
+			docker.ContainerState: "running",
+			container.Runtime:     "mine",
+			report.HostNodeID:     report.MakeHostNodeID("host"),
+		}),
+	}
+	if have := r.Container.NodeMetadatas; !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
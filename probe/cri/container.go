@@ -0,0 +1,141 @@
+package cri
+
+import (
+	"strconv"
+	"time"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+// container is a docker.Container backed by a CRI container status. The
+// registry polls the stats of every container, so there's nothing to start
+// or stop gathering.
+type container struct {
+	status        *runtimeapi.ContainerStatus
+	pid           int
+	image         string
+	latestStats   *runtimeapi.ContainerStats
+	previousStats *runtimeapi.ContainerStats
+}
+
+func newContainer(status *runtimeapi.ContainerStatus, pid int) *container {
+	return &container{status: status, pid: pid, image: status.ImageRef}
+}
+
+func (c *container) ID() string {
+	return c.status.Id
+}
+
+func (c *container) Image() string {
+	return c.image
+}
+
+func (c *container) PID() int {
+	return c.pid
+}
+
+// State returns the state of the container, named as the Docker client would
+// name it: one of created, running, exited or unknown.
+func (c *container) State() string {
+	switch c.status.State {
+	case runtimeapi.ContainerState_CONTAINER_CREATED:
+		return "created"
+	case runtimeapi.ContainerState_CONTAINER_RUNNING:
+		return "running"
+	case runtimeapi.ContainerState_CONTAINER_EXITED:
+		return "exited"
+	}
+	return "unknown"
+}
+
+func (c *container) StartGatheringStats() error {
+	return nil
+}
+
+func (c *container) StopGatheringStats() {}
+
+func (c *container) addStats(stats *runtimeapi.ContainerStats) {
+	if c.latestStats != nil && stats.GetCpu().GetTimestamp() == c.latestStats.GetCpu().GetTimestamp() {
+		return
+	}
+	c.previousStats, c.latestStats = c.latestStats, stats
+}
+
+// eventsSince returns the lifecycle events the container has been through
+// since old, which may be nil. The CRI restarts a container by replacing it,
+// so each container starts and dies at most once.
+func (c *container) eventsSince(old *container) []docker.Event {
+	events := []docker.Event{}
+	if old == nil {
+		events = append(events, docker.Event{Time: timestamp(c.status.CreatedAt), Status: docker.CreateEvent})
+	}
+	if c.status.StartedAt != 0 && (old == nil || old.status.StartedAt == 0) {
+		events = append(events, docker.Event{Time: timestamp(c.status.StartedAt), Status: docker.StartEvent})
+	}
+	if c.status.FinishedAt != 0 && (old == nil || old.status.FinishedAt == 0) {
+		events = append(events, docker.Event{
+			Time:     timestamp(c.status.FinishedAt),
+			Status:   docker.DieEvent,
+			ExitCode: strconv.Itoa(int(c.status.ExitCode)),
+		})
+	}
+	return events
+}
+
+func (c *container) GetNodeMetadata() report.NodeMetadata {
+	result := report.MakeNodeMetadataWith(map[string]string{
+		docker.ContainerID:           c.ID(),
+		docker.ContainerName:         c.status.GetMetadata().GetName(),
+		docker.ContainerCreated:      timestamp(c.status.CreatedAt).Format(time.RFC822),
+		docker.ContainerState:        c.State(),
+		docker.ContainerRestartCount: strconv.Itoa(int(c.status.GetMetadata().GetAttempt())),
+		docker.ImageID:               c.image,
+	})
+	if c.State() == "exited" {
+		result.Metadata[docker.ContainerExitCode] = strconv.Itoa(int(c.status.ExitCode))
+	}
+	for key, value := range c.status.Labels {
+		result.Metadata[docker.LabelPrefix+key] = value
+	}
+
+	if c.latestStats == nil {
+		return result
+	}
+	if cpu := c.latestStats.GetCpu().GetUsageCoreNanoSeconds(); cpu != nil {
+		result.Metadata[docker.CPUTotalUsage] = strconv.FormatUint(cpu.Value, 10)
+	}
+	if memory := c.latestStats.GetMemory().GetWorkingSetBytes(); memory != nil {
+		result.Metadata[docker.MemoryUsage] = strconv.FormatUint(memory.Value, 10)
+	}
+	c.addCPUUsagePercent(result)
+	return result
+}
+
+// addCPUUsagePercent adds the CPU usage between the two most recent stats
+// samples, if we have them, scaled like the Docker client's: 100% is one
+// CPU fully used.
+func (c *container) addCPUUsagePercent(md report.NodeMetadata) {
+	if c.previousStats == nil {
+		return
+	}
+	previous, latest := c.previousStats.GetCpu(), c.latestStats.GetCpu()
+	if previous.GetUsageCoreNanoSeconds() == nil || latest.GetUsageCoreNanoSeconds() == nil {
+		return
+	}
+
+	var (
+		usageDelta = float64(latest.UsageCoreNanoSeconds.Value) - float64(previous.UsageCoreNanoSeconds.Value)
+		timeDelta  = float64(latest.Timestamp - previous.Timestamp)
+	)
+	if usageDelta >= 0 && timeDelta > 0 {
+		md.Metadata[docker.CPUUsagePercent] = strconv.FormatFloat(100*usageDelta/timeDelta, 'f', 2, 64)
+	}
+}
+
+// timestamp converts the CRI's nanoseconds since the epoch.
+func timestamp(ns int64) time.Time {
+	return time.Unix(0, ns).UTC()
+}
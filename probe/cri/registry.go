@@ -0,0 +1,293 @@
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	docker_client "github.com/fsouza/go-dockerclient"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/weaveworks/scope/probe/docker"
+)
+
+const (
+	// DefaultEndpoint is containerd's CRI socket.
+	DefaultEndpoint = "unix:///run/containerd/containerd.sock"
+
+	// maxEvents is the number of recent events we keep for each container.
+	maxEvents = 10
+)
+
+// registry keeps track of the containers of a CRI runtime, such as
+// containerd or CRI-O, and their images. It implements docker.Registry, so
+// the docker Tagger and Reporter work the same way on top of it.
+//
+// The CRI doesn't tell us about containers as they change, so we poll the
+// runtime every interval, and make up the lifecycle events from the
+// timestamps of each container.
+type registry struct {
+	sync.RWMutex
+	quit     chan chan struct{}
+	interval time.Duration
+	conn     *grpc.ClientConn
+	runtime  runtimeapi.RuntimeServiceClient
+	image    runtimeapi.ImageServiceClient
+
+	containers      map[string]*container
+	containersByPID map[int]docker.Container
+	images          map[string]*docker_client.APIImages
+	events          map[string][]docker.Event
+}
+
+// NewRegistry returns a usable docker.Registry, talking to the CRI runtime
+// at endpoint. Don't forget to Stop it.
+func NewRegistry(endpoint string, interval time.Duration) (docker.Registry, error) {
+	conn, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &registry{
+		containers:      map[string]*container{},
+		containersByPID: map[int]docker.Container{},
+		images:          map[string]*docker_client.APIImages{},
+		events:          map[string][]docker.Event{},
+
+		conn:     conn,
+		runtime:  runtimeapi.NewRuntimeServiceClient(conn),
+		image:    runtimeapi.NewImageServiceClient(conn),
+		interval: interval,
+		quit:     make(chan chan struct{}),
+	}
+
+	go r.loop()
+	return r, nil
+}
+
+// Stop stops polling the runtime.
+func (r *registry) Stop() {
+	ch := make(chan struct{})
+	r.quit <- ch
+	<-ch
+}
+
+func (r *registry) loop() {
+	tick := time.NewTicker(r.interval)
+	defer tick.Stop()
+
+	for {
+		if err := r.update(); err != nil {
+			log.Printf("cri registry: %s", err)
+		}
+
+		select {
+		case <-tick.C:
+		case ch := <-r.quit:
+			r.conn.Close()
+			close(ch)
+			return
+		}
+	}
+}
+
+// update fetches the runtime's containers, their stats, and its images.
+// Containers are only inspected when they're new or have changed state.
+func (r *registry) update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	list, err := r.runtime.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return err
+	}
+	stats, err := r.runtime.ListContainerStats(ctx, &runtimeapi.ListContainerStatsRequest{})
+	if err != nil {
+		return err
+	}
+	images, err := r.image.ListImages(ctx, &runtimeapi.ListImagesRequest{})
+	if err != nil {
+		return err
+	}
+
+	changed := map[string]*container{}
+	for _, c := range list.Containers {
+		r.RLock()
+		old, ok := r.containers[c.Id]
+		r.RUnlock()
+		if ok && old.status.State == c.State {
+			continue
+		}
+
+		resp, err := r.runtime.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: c.Id, Verbose: true})
+		if status.Code(err) == codes.NotFound {
+			// Don't spam the logs if the container was short lived
+			continue
+		} else if err != nil {
+			return err
+		}
+		changed[c.Id] = newContainer(resp.Status, pid(resp.Info))
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	listed := map[string]struct{}{}
+	for _, c := range list.Containers {
+		listed[c.Id] = struct{}{}
+	}
+	for id := range r.containers {
+		if _, ok := listed[id]; !ok {
+			r.removeContainer(id)
+		}
+	}
+	for id, c := range changed {
+		r.addContainer(id, c)
+	}
+
+	for _, s := range stats.Stats {
+		if c, ok := r.containers[s.GetAttributes().GetId()]; ok {
+			c.addStats(s)
+		}
+	}
+
+	r.updateImages(images.Images)
+	return nil
+}
+
+// addContainer starts tracking c, replacing whatever we knew about it
+// before, and records the events which got it to its current state. Only
+// running containers have processes to look up. Must be called with the lock
+// held.
+func (r *registry) addContainer(id string, c *container) {
+	old, ok := r.containers[id]
+	if ok {
+		r.forgetPID(old)
+		c.previousStats, c.latestStats = old.previousStats, old.latestStats
+	}
+
+	for _, e := range c.eventsSince(old) {
+		events := append(r.events[id], e)
+		if len(events) > maxEvents {
+			events = events[len(events)-maxEvents:]
+		}
+		r.events[id] = events
+	}
+
+	r.containers[id] = c
+	if c.State() == "running" && c.pid > 0 {
+		r.containersByPID[c.pid] = c
+	}
+}
+
+// removeContainer forgets the container. Must be called with the lock held.
+func (r *registry) removeContainer(id string) {
+	c, ok := r.containers[id]
+	if !ok {
+		return
+	}
+	delete(r.containers, id)
+	delete(r.events, id)
+	r.forgetPID(c)
+}
+
+// forgetPID stops pid lookups finding c. Must be called with the lock held.
+func (r *registry) forgetPID(c *container) {
+	if r.containersByPID[c.pid] == docker.Container(c) {
+		delete(r.containersByPID, c.pid)
+	}
+}
+
+// updateImages replaces the images we know of, and points each container at
+// the image it was created from. Runtimes may refer to an image by its ID
+// or by one of its digests. Must be called with the lock held.
+func (r *registry) updateImages(images []*runtimeapi.Image) {
+	r.images = map[string]*docker_client.APIImages{}
+	refs := map[string]string{}
+	for _, image := range images {
+		r.images[image.Id] = &docker_client.APIImages{
+			ID:          image.Id,
+			RepoTags:    image.RepoTags,
+			RepoDigests: image.RepoDigests,
+			Size:        int64(image.Size_),
+			VirtualSize: int64(image.Size_),
+		}
+		refs[image.Id] = image.Id
+		for _, digest := range image.RepoDigests {
+			refs[digest] = image.Id
+		}
+	}
+
+	for _, c := range r.containers {
+		c.image = c.status.ImageRef
+		if id, ok := refs[c.status.ImageRef]; ok {
+			c.image = id
+		}
+	}
+}
+
+// LockedPIDLookup runs f under a read lock, and gives f a function for
+// use doing pid->container lookups.
+func (r *registry) LockedPIDLookup(f func(func(int) docker.Container)) {
+	r.RLock()
+	defer r.RUnlock()
+
+	lookup := func(pid int) docker.Container {
+		return r.containersByPID[pid]
+	}
+
+	f(lookup)
+}
+
+// WalkContainers runs f on every container the registry knows of, whatever
+// its state.
+func (r *registry) WalkContainers(f func(docker.Container)) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, c := range r.containers {
+		f(c)
+	}
+}
+
+// WalkImages runs f on every image of containers the registry knows of. f
+// may be run on the same image more than once.
+func (r *registry) WalkImages(f func(*docker_client.APIImages)) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, c := range r.containers {
+		if image, ok := r.images[c.Image()]; ok {
+			f(image)
+		}
+	}
+}
+
+// WalkEvents runs f on the recent events of every container which has had
+// any.
+func (r *registry) WalkEvents(f func(containerID string, events []docker.Event)) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for containerID, events := range r.events {
+		f(containerID, events)
+	}
+}
+
+// pid finds the container's process in the runtime-specific information of a
+// verbose container status. Both containerd and CRI-O put it there.
+func pid(info map[string]string) int {
+	var v struct {
+		Pid int `json:"pid"`
+	}
+	if err := json.Unmarshal([]byte(info["info"]), &v); err != nil {
+		return 0
+	}
+	return v.Pid
+}
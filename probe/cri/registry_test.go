@@ -0,0 +1,270 @@
+package cri_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	docker_client "github.com/fsouza/go-dockerclient"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/weaveworks/scope/probe/cri"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/test"
+)
+
+const (
+	created  = int64(1440000000) * 1e9
+	started  = created + 1e9
+	finished = created + 2e9
+)
+
+// fakeRuntime is a CRI runtime and image service serving canned responses.
+type fakeRuntime struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	runtimeapi.UnimplementedImageServiceServer
+
+	sync.RWMutex
+	containers map[string]*runtimeapi.ContainerStatus
+	pids       map[string]string
+	stats      []*runtimeapi.ContainerStats
+	images     []*runtimeapi.Image
+}
+
+func (f *fakeRuntime) ListContainers(context.Context, *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	f.RLock()
+	defer f.RUnlock()
+	resp := &runtimeapi.ListContainersResponse{}
+	for _, s := range f.containers {
+		resp.Containers = append(resp.Containers, &runtimeapi.Container{Id: s.Id, State: s.State, ImageRef: s.ImageRef})
+	}
+	return resp, nil
+}
+
+func (f *fakeRuntime) ContainerStatus(_ context.Context, req *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+	f.RLock()
+	defer f.RUnlock()
+	s, ok := f.containers[req.ContainerId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no such container %s", req.ContainerId)
+	}
+	return &runtimeapi.ContainerStatusResponse{Status: s, Info: map[string]string{"info": f.pids[req.ContainerId]}}, nil
+}
+
+func (f *fakeRuntime) ListContainerStats(context.Context, *runtimeapi.ListContainerStatsRequest) (*runtimeapi.ListContainerStatsResponse, error) {
+	f.RLock()
+	defer f.RUnlock()
+	return &runtimeapi.ListContainerStatsResponse{Stats: f.stats}, nil
+}
+
+func (f *fakeRuntime) ListImages(context.Context, *runtimeapi.ListImagesRequest) (*runtimeapi.ListImagesResponse, error) {
+	f.RLock()
+	defer f.RUnlock()
+	return &runtimeapi.ListImagesResponse{Images: f.images}, nil
+}
+
+func (f *fakeRuntime) setContainer(s *runtimeapi.ContainerStatus, info string) {
+	f.Lock()
+	defer f.Unlock()
+	f.containers[s.Id] = s
+	f.pids[s.Id] = info
+}
+
+func (f *fakeRuntime) removeContainer(id string) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.containers, id)
+}
+
+func (f *fakeRuntime) setStats(stats ...*runtimeapi.ContainerStats) {
+	f.Lock()
+	defer f.Unlock()
+	f.stats = stats
+}
+
+func cpuStats(id string, timestamp int64, usage uint64) *runtimeapi.ContainerStats {
+	return &runtimeapi.ContainerStats{
+		Attributes: &runtimeapi.ContainerAttributes{Id: id},
+		Cpu: &runtimeapi.CpuUsage{
+			Timestamp:            timestamp,
+			UsageCoreNanoSeconds: &runtimeapi.UInt64Value{Value: usage},
+		},
+		Memory: &runtimeapi.MemoryUsage{
+			Timestamp:       timestamp,
+			WorkingSetBytes: &runtimeapi.UInt64Value{Value: 1048576},
+		},
+	}
+}
+
+var (
+	running = &runtimeapi.ContainerStatus{
+		Id:        "abc",
+		Metadata:  &runtimeapi.ContainerMetadata{Name: "nginx", Attempt: 1},
+		State:     runtimeapi.ContainerState_CONTAINER_RUNNING,
+		CreatedAt: created,
+		StartedAt: started,
+		ImageRef:  "docker.io/library/nginx@sha256:feed",
+		Labels:    map[string]string{"io.kubernetes.pod.name": "web"},
+	}
+	exited = &runtimeapi.ContainerStatus{
+		Id:         "abc",
+		Metadata:   &runtimeapi.ContainerMetadata{Name: "nginx", Attempt: 1},
+		State:      runtimeapi.ContainerState_CONTAINER_EXITED,
+		CreatedAt:  created,
+		StartedAt:  started,
+		FinishedAt: finished,
+		ExitCode:   137,
+		ImageRef:   "docker.io/library/nginx@sha256:feed",
+		Labels:     map[string]string{"io.kubernetes.pod.name": "web"},
+	}
+	image = &runtimeapi.Image{
+		Id:          "sha256:beef",
+		RepoTags:    []string{"docker.io/library/nginx:latest"},
+		RepoDigests: []string{"docker.io/library/nginx@sha256:feed"},
+		Size_:       1048576,
+	}
+)
+
+func serve(t *testing.T, f *fakeRuntime) (string, func()) {
+	dir, err := ioutil.TempDir("", "cri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "cri.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(server, f)
+	runtimeapi.RegisterImageServiceServer(server, f)
+	go server.Serve(listener)
+	return "unix://" + socket, func() {
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func containerIDs(registry docker.Registry) interface{} {
+	ids := []string{}
+	registry.WalkContainers(func(c docker.Container) {
+		ids = append(ids, c.ID()+" "+c.State())
+	})
+	sort.Strings(ids)
+	return ids
+}
+
+func TestRegistry(t *testing.T) {
+	f := &fakeRuntime{
+		containers: map[string]*runtimeapi.ContainerStatus{},
+		pids:       map[string]string{},
+		images:     []*runtimeapi.Image{image},
+	}
+	f.setContainer(running, `{"pid": 42}`)
+	f.setStats(cpuStats("abc", started, 0))
+
+	endpoint, stop := serve(t, f)
+	defer stop()
+
+	registry, err := cri.NewRegistry(endpoint, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.Stop()
+
+	test.Poll(t, 100*time.Millisecond, []string{"abc running"}, func() interface{} { return containerIDs(registry) })
+
+	// Containers are found by pid, as the docker tagger finds them.
+	var found docker.Container
+	registry.LockedPIDLookup(func(lookup func(int) docker.Container) {
+		found = lookup(42)
+	})
+	if found == nil || found.ID() != "abc" || found.Image() != "sha256:beef" {
+		t.Fatalf("pid lookup: %v", found)
+	}
+
+	// Images are referred to by ID, even when containers refer to them by
+	// digest.
+	images := []*docker_client.APIImages{}
+	registry.WalkImages(func(image *docker_client.APIImages) {
+		images = append(images, image)
+	})
+	wantImages := []*docker_client.APIImages{{
+		ID:          "sha256:beef",
+		RepoTags:    []string{"docker.io/library/nginx:latest"},
+		RepoDigests: []string{"docker.io/library/nginx@sha256:feed"},
+		Size:        1048576,
+		VirtualSize: 1048576,
+	}}
+	if !reflect.DeepEqual(wantImages, images) {
+		t.Errorf("%s", test.Diff(wantImages, images))
+	}
+
+	// A second stats sample gives us the CPU usage: half a CPU, for a
+	// second.
+	f.setStats(cpuStats("abc", started+1e9, 5e8))
+	want := map[string]string{
+		docker.ContainerID:                            "abc",
+		docker.ContainerName:                          "nginx",
+		docker.ContainerCreated:                       "19 Aug 15 16:00 UTC",
+		docker.ContainerState:                         "running",
+		docker.ContainerRestartCount:                  "1",
+		docker.ImageID:                                "sha256:beef",
+		docker.LabelPrefix + "io.kubernetes.pod.name": "web",
+		docker.CPUTotalUsage:                          "500000000",
+		docker.MemoryUsage:                            "1048576",
+		docker.CPUUsagePercent:                        "50.00",
+	}
+	metadata := func() interface{} {
+		var md map[string]string
+		registry.WalkContainers(func(c docker.Container) {
+			md = c.GetNodeMetadata().Metadata
+		})
+		return md
+	}
+	test.Poll(t, 100*time.Millisecond, want, metadata)
+	if have := metadata(); !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+
+	// When the container exits, we can no longer find it by pid, and we
+	// make up its lifecycle from its timestamps.
+	f.setContainer(exited, `{"pid": 0}`)
+	test.Poll(t, 100*time.Millisecond, []string{"abc exited"}, func() interface{} { return containerIDs(registry) })
+	registry.LockedPIDLookup(func(lookup func(int) docker.Container) {
+		found = lookup(42)
+	})
+	if found != nil {
+		t.Errorf("found exited container by pid: %v", found)
+	}
+
+	wantEvents := []docker.Event{
+		{Time: time.Unix(0, created).UTC(), Status: docker.CreateEvent},
+		{Time: time.Unix(0, started).UTC(), Status: docker.StartEvent},
+		{Time: time.Unix(0, finished).UTC(), Status: docker.DieEvent, ExitCode: "137"},
+	}
+	var events []docker.Event
+	registry.WalkEvents(func(containerID string, e []docker.Event) {
+		events = e
+	})
+	if !reflect.DeepEqual(wantEvents, events) {
+		t.Errorf("%s", test.Diff(wantEvents, events))
+	}
+
+	// Containers the runtime no longer lists are forgotten.
+	f.removeContainer("abc")
+	test.Poll(t, 100*time.Millisecond, []string{}, func() interface{} { return containerIDs(registry) })
+	if have := containerIDs(registry); !reflect.DeepEqual([]string{}, have) {
+		t.Errorf("want no containers, have %v", have)
+	}
+}
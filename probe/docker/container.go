@@ -9,7 +9,6 @@ import (
 	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,6 +16,7 @@ import (
 
 	docker "github.com/fsouza/go-dockerclient"
 
+	"github.com/weaveworks/scope/probe/cgroup"
 	"github.com/weaveworks/scope/report"
 )
 
@@ -27,6 +27,25 @@ const (
 	ContainerPorts   = "docker_container_ports"
 	ContainerCreated = "docker_container_created"
 
+	ContainerState        = "docker_container_state"
+	ContainerRestartCount = "docker_container_restart_count"
+	ContainerExitCode     = "docker_container_exit_code"
+	ContainerHealth       = "docker_container_health"
+	ContainerHostname     = "docker_container_hostname"
+	ContainerNetworkMode  = "docker_container_network_mode"
+
+	// Each label, and the container's IP on each network, has a key
+	// made up of one of these prefixes and the label or network name.
+	LabelPrefix     = "docker_label_"
+	NetworkIPPrefix = "docker_network_ip_"
+
+	// The labels Docker Compose and Swarm put on the containers of their
+	// services.
+	ComposeProjectLabel = "com.docker.compose.project"
+	ComposeServiceLabel = "com.docker.compose.service"
+	SwarmServiceLabel   = "com.docker.swarm.service.name"
+	SwarmStackLabel     = "com.docker.stack.namespace"
+
 	NetworkRxDropped = "network_rx_dropped"
 	NetworkRxBytes   = "network_rx_bytes"
 	NetworkRxErrors  = "network_rx_errors"
@@ -46,6 +65,15 @@ const (
 	CPUTotalUsage        = "cpu_total_usage"
 	CPUUsageInKernelmode = "cpu_usage_in_kernelmode"
 	CPUSystemCPUUsage    = "cpu_system_cpu_usage"
+
+	BlkioReadBytes  = "blkio_read_bytes"
+	BlkioWriteBytes = "blkio_write_bytes"
+	PidsCurrent     = "pids_current"
+
+	// Computed between successive stats samples
+	CPUUsagePercent = "cpu_usage_percent"
+	NetworkRxRate   = "network_rx_bytes_per_second"
+	NetworkTxRate   = "network_tx_bytes_per_second"
 )
 
 // Exported for testing
@@ -64,27 +92,42 @@ type ClientConn interface {
 	Close() error
 }
 
-// Container represents a Docker container
+// Container represents a container, of whichever runtime the Registry
+// tracks.
 type Container interface {
 	ID() string
 	Image() string
 	PID() int
+	State() string
 	GetNodeMetadata() report.NodeMetadata
 
 	StartGatheringStats() error
 	StopGatheringStats()
 }
 
+// DockerContainer is a Container the Docker daemon runs, which can be
+// updated as it changes.
+type DockerContainer interface {
+	Container
+	UpdateState(*docker.Container)
+}
+
 type container struct {
 	sync.RWMutex
-	container   *docker.Container
-	statsConn   ClientConn
-	latestStats *docker.Stats
+	container     *docker.Container
+	endpoint      Endpoint
+	cgroups       *cgroup.Reader
+	statsConn     ClientConn
+	latestStats   *docker.Stats
+	previousStats *docker.Stats
+	latestPids    uint64
 }
 
-// NewContainer creates a new Container
-func NewContainer(c *docker.Container) Container {
-	return &container{container: c}
+// NewContainer creates a new Container. If cgroups is nil, stats are
+// streamed from the Docker daemon at endpoint; otherwise they're read from
+// the container's cgroups whenever we report on it.
+func NewContainer(c *docker.Container, endpoint Endpoint, cgroups *cgroup.Reader) DockerContainer {
+	return &container{container: c, endpoint: endpoint, cgroups: cgroups}
 }
 
 func (c *container) ID() string {
@@ -99,30 +142,40 @@ func (c *container) PID() int {
 	return c.container.State.Pid
 }
 
+func (c *container) State() string {
+	return State(c.container)
+}
+
+// UpdateState replaces what we know of the container, keeping its stats.
+func (c *container) UpdateState(container *docker.Container) {
+	c.Lock()
+	defer c.Unlock()
+	c.container = container
+}
+
 func (c *container) StartGatheringStats() error {
 	c.Lock()
 	defer c.Unlock()
 
+	if c.cgroups != nil {
+		return nil
+	}
 	if c.statsConn != nil {
 		return fmt.Errorf("already gather stats for container %s", c.container.ID)
 	}
 
+	// UpdateState replaces c.container, so take the ID while we hold the lock.
+	id := c.container.ID
 	go func() {
-		log.Printf("docker container: collecting stats for %s", c.container.ID)
-		req, err := http.NewRequest("GET", fmt.Sprintf("/containers/%s/stats", c.container.ID), nil)
+		log.Printf("docker container: collecting stats for %s", id)
+		req, err := http.NewRequest("GET", fmt.Sprintf("/containers/%s/stats", id), nil)
 		if err != nil {
 			log.Printf("docker container: %v", err)
 			return
 		}
 		req.Header.Set("User-Agent", "weavescope")
 
-		url, err := url.Parse(endpoint)
-		if err != nil {
-			log.Printf("docker container: %v", err)
-			return
-		}
-
-		dial, err := DialStub(url.Scheme, url.Path)
+		dial, err := c.endpoint.dial()
 		if err != nil {
 			log.Printf("docker container: %v", err)
 			return
@@ -143,9 +196,10 @@ func (c *container) StartGatheringStats() error {
 			c.Lock()
 			defer c.Unlock()
 
-			log.Printf("docker container: stopped collecting stats for %s", c.container.ID)
+			log.Printf("docker container: stopped collecting stats for %s", id)
 			c.statsConn = nil
 			c.latestStats = nil
+			c.previousStats = nil
 		}()
 
 		stats := &docker.Stats{}
@@ -158,7 +212,7 @@ func (c *container) StartGatheringStats() error {
 			}
 
 			c.Lock()
-			c.latestStats = stats
+			c.previousStats, c.latestStats = c.latestStats, stats
 			c.Unlock()
 
 			stats = &docker.Stats{}
@@ -179,9 +233,54 @@ func (c *container) StopGatheringStats() {
 	c.statsConn.Close()
 	c.statsConn = nil
 	c.latestStats = nil
+	c.previousStats = nil
 	return
 }
 
+// readCgroupStats samples the container's cgroups, converting them to the
+// form the Docker daemon would have streamed to us.
+func (c *container) readCgroupStats() {
+	sample, err := c.cgroups.Read(c.PID())
+	if err != nil {
+		log.Printf("docker container: %v", err)
+		return
+	}
+
+	stats := &docker.Stats{Read: sample.Read}
+	stats.CPUStats.CPUUsage.TotalUsage = sample.CPUUsage
+	stats.CPUStats.CPUUsage.UsageInUsermode = sample.CPUUser
+	stats.CPUStats.CPUUsage.UsageInKernelmode = sample.CPUSystem
+	stats.CPUStats.SystemCPUUsage = sample.SystemCPUUsage
+	stats.CPUStats.CPUUsage.PercpuUsage = sample.PercpuUsage
+	if len(stats.CPUStats.CPUUsage.PercpuUsage) == 0 {
+		// cgroup v2 has no per-CPU usage; we only need the number of CPUs.
+		stats.CPUStats.CPUUsage.PercpuUsage = make([]uint64, sample.CPUs)
+	}
+	stats.MemoryStats.Usage = sample.MemoryUsage
+	stats.MemoryStats.MaxUsage = sample.MemoryMaxUsage
+	stats.MemoryStats.Limit = sample.MemoryLimit
+	stats.MemoryStats.Failcnt = sample.MemoryFailcnt
+	stats.BlkioStats.IOServiceBytesRecursive = []docker.BlkioStatsEntry{
+		{Op: "Read", Value: sample.BlkioRead},
+		{Op: "Write", Value: sample.BlkioWrite},
+	}
+	stats.Network = docker.NetworkStats{
+		RxBytes:   sample.Network.RxBytes,
+		RxPackets: sample.Network.RxPackets,
+		RxErrors:  sample.Network.RxErrors,
+		RxDropped: sample.Network.RxDropped,
+		TxBytes:   sample.Network.TxBytes,
+		TxPackets: sample.Network.TxPackets,
+		TxErrors:  sample.Network.TxErrors,
+		TxDropped: sample.Network.TxDropped,
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.previousStats, c.latestStats = c.latestStats, stats
+	c.latestPids = sample.Pids
+}
+
 func (c *container) ports() string {
 	if c.container.NetworkSettings == nil {
 		return ""
@@ -202,6 +301,10 @@ func (c *container) ports() string {
 }
 
 func (c *container) GetNodeMetadata() report.NodeMetadata {
+	if c.cgroups != nil {
+		c.readCgroupStats()
+	}
+
 	c.RLock()
 	defer c.RUnlock()
 
@@ -213,6 +316,8 @@ func (c *container) GetNodeMetadata() report.NodeMetadata {
 		ContainerCommand: c.container.Path + " " + strings.Join(c.container.Args, " "),
 		ImageID:          c.container.Image,
 	})
+	c.addState(result)
+	c.addConfig(result)
 
 	if c.latestStats == nil {
 		return result
@@ -239,5 +344,148 @@ func (c *container) GetNodeMetadata() report.NodeMetadata {
 		CPUUsageInKernelmode: strconv.FormatUint(c.latestStats.CPUStats.CPUUsage.UsageInKernelmode, 10),
 		CPUSystemCPUUsage:    strconv.FormatUint(c.latestStats.CPUStats.SystemCPUUsage, 10),
 	}))
+	c.addBlkio(result)
+	if c.latestPids > 0 {
+		result.Metadata[PidsCurrent] = strconv.FormatUint(c.latestPids, 10)
+	}
+	c.addRates(result)
+	return result
+}
+
+// addRates adds the CPU usage and network rates between the two most recent
+// stats samples, if we have them. Must be called with the lock held.
+func (c *container) addRates(md report.NodeMetadata) {
+	previous, latest := c.previousStats, c.latestStats
+	if previous == nil {
+		return
+	}
+
+	// As calculated by the docker client: the container's share of the
+	// system's CPU time, scaled by the number of CPUs.
+	var (
+		containerDelta = float64(latest.CPUStats.CPUUsage.TotalUsage) - float64(previous.CPUStats.CPUUsage.TotalUsage)
+		systemDelta    = float64(latest.CPUStats.SystemCPUUsage) - float64(previous.CPUStats.SystemCPUUsage)
+		cpus           = len(latest.CPUStats.CPUUsage.PercpuUsage)
+	)
+	if cpus == 0 {
+		cpus = 1
+	}
+	if containerDelta >= 0 && systemDelta > 0 {
+		md.Metadata[CPUUsagePercent] = strconv.FormatFloat(100*float64(cpus)*containerDelta/systemDelta, 'f', 2, 64)
+	}
+
+	seconds := latest.Read.Sub(previous.Read).Seconds()
+	if seconds <= 0 {
+		return
+	}
+	for key, bytes := range map[string][2]uint64{
+		NetworkRxRate: {previous.Network.RxBytes, latest.Network.RxBytes},
+		NetworkTxRate: {previous.Network.TxBytes, latest.Network.TxBytes},
+	} {
+		if bytes[1] >= bytes[0] {
+			md.Metadata[key] = strconv.FormatFloat(float64(bytes[1]-bytes[0])/seconds, 'f', 2, 64)
+		}
+	}
+}
+
+// State returns the state of a container, as the Docker client shows it:
+// one of created, running, paused, restarting, exited or dead.
+func State(c *docker.Container) string {
+	switch {
+	case c.State.Running && c.State.Paused:
+		return "paused"
+	case c.State.Running && c.State.Restarting:
+		return "restarting"
+	case c.State.Running:
+		return "running"
+	case c.State.Dead:
+		return "dead"
+	case c.State.StartedAt.IsZero():
+		return "created"
+	}
+	return "exited"
+}
+
+// addState adds the container's lifecycle state. The exit code is only
+// meaningful once the container has stopped. Must be called with the lock
+// held.
+func (c *container) addState(md report.NodeMetadata) {
+	state := State(c.container)
+	md.Metadata[ContainerState] = state
+	md.Metadata[ContainerRestartCount] = strconv.Itoa(c.container.RestartCount)
+	if state == "exited" || state == "dead" {
+		md.Metadata[ContainerExitCode] = strconv.Itoa(c.container.State.ExitCode)
+	}
+	if health := c.container.State.Health.Status; health != "" {
+		md.Metadata[ContainerHealth] = health
+	}
+}
+
+// addConfig adds the container's labels, hostname and networking. Must be
+// called with the lock held.
+func (c *container) addConfig(md report.NodeMetadata) {
+	if config := c.container.Config; config != nil {
+		if config.Hostname != "" {
+			md.Metadata[ContainerHostname] = config.Hostname
+		}
+		for key, value := range config.Labels {
+			md.Metadata[LabelPrefix+key] = value
+		}
+	}
+	if hostConfig := c.container.HostConfig; hostConfig != nil && hostConfig.NetworkMode != "" {
+		md.Metadata[ContainerNetworkMode] = hostConfig.NetworkMode
+	}
+	if settings := c.container.NetworkSettings; settings != nil {
+		for name, network := range settings.Networks {
+			if network.IPAddress != "" {
+				md.Metadata[NetworkIPPrefix+name] = network.IPAddress
+			}
+		}
+	}
+}
+
+// Labels returns the Docker labels in a container's metadata.
+func Labels(md report.NodeMetadata) map[string]string {
+	return withPrefix(md, LabelPrefix)
+}
+
+// ImageLabels returns the Docker labels in an image's metadata.
+func ImageLabels(md report.NodeMetadata) map[string]string {
+	return withPrefix(md, ImageLabelPrefix)
+}
+
+// NetworkIPs returns the container's IP on each network it's attached to,
+// from its metadata.
+func NetworkIPs(md report.NodeMetadata) map[string]string {
+	return withPrefix(md, NetworkIPPrefix)
+}
+
+func withPrefix(md report.NodeMetadata, prefix string) map[string]string {
+	result := map[string]string{}
+	for key, value := range md.Metadata {
+		if strings.HasPrefix(key, prefix) {
+			result[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
 	return result
 }
+
+// addBlkio adds up the bytes the container has read from and written to
+// block devices. Must be called with the lock held.
+func (c *container) addBlkio(md report.NodeMetadata) {
+	entries := c.latestStats.BlkioStats.IOServiceBytesRecursive
+	if len(entries) == 0 {
+		return
+	}
+	var read, write uint64
+	for _, entry := range entries {
+		switch entry.Op {
+		case "Read":
+			read += entry.Value
+		case "Write":
+			write += entry.Value
+		}
+	}
+	md.Metadata[BlkioReadBytes] = strconv.FormatUint(read, 10)
+	md.Metadata[BlkioWriteBytes] = strconv.FormatUint(write, 10)
+}
@@ -8,12 +8,15 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"reflect"
 	"runtime"
 	"testing"
 	"time"
 
 	client "github.com/fsouza/go-dockerclient"
 
+	"github.com/weaveworks/scope/probe/cgroup"
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/test"
 )
@@ -49,13 +52,14 @@ func TestContainer(t *testing.T) {
 		return connection
 	}
 
-	c := docker.NewContainer(container1)
+	c := docker.NewContainer(container1, docker.Endpoint{Host: docker.DefaultHost}, nil)
 	err := c.StartGatheringStats()
 	if err != nil {
 		t.Errorf("%v", err)
 	}
 	defer c.StopGatheringStats()
-	runtime.Gosched() // wait for StartGatheringStats goroutine to call connection.Do
+	c.UpdateState(container1) // mustn't race with the stats goroutine
+	runtime.Gosched()         // wait for StartGatheringStats goroutine to call connection.Do
 
 	// Send some stats to the docker container
 	stats := &client.Stats{}
@@ -69,3 +73,156 @@ func TestContainer(t *testing.T) {
 		return c.GetNodeMetadata().Metadata[docker.MemoryUsage]
 	})
 }
+
+func TestContainerRates(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	oldDialStub, oldNewClientConnStub := docker.DialStub, docker.NewClientConnStub
+	defer func() { docker.DialStub, docker.NewClientConnStub = oldDialStub, oldNewClientConnStub }()
+
+	docker.DialStub = func(network, address string) (net.Conn, error) {
+		return nil, nil
+	}
+
+	reader, writer := io.Pipe()
+	connection := &mockConnection{reader}
+
+	docker.NewClientConnStub = func(c net.Conn, r *bufio.Reader) docker.ClientConn {
+		return connection
+	}
+
+	c := docker.NewContainer(container1, docker.Endpoint{Host: docker.DefaultHost}, nil)
+	if err := c.StartGatheringStats(); err != nil {
+		t.Errorf("%v", err)
+	}
+	defer c.StopGatheringStats()
+	runtime.Gosched()
+
+	// Two samples two seconds apart, during which the container used a
+	// quarter of the system's time on two CPUs.
+	now := time.Unix(1440000000, 0)
+	for _, sample := range []struct {
+		read                  time.Time
+		total, system, rx, tx uint64
+	}{
+		{now, 1000, 10000, 1000, 2000},
+		{now.Add(2 * time.Second), 3000, 18000, 5000, 2000},
+	} {
+		stats := &client.Stats{Read: sample.read}
+		stats.CPUStats.CPUUsage.TotalUsage = sample.total
+		stats.CPUStats.CPUUsage.PercpuUsage = []uint64{0, 0}
+		stats.CPUStats.SystemCPUUsage = sample.system
+		stats.Network.RxBytes = sample.rx
+		stats.Network.TxBytes = sample.tx
+		if err := json.NewEncoder(writer).Encode(&stats); err != nil {
+			t.Error(err)
+		}
+	}
+
+	want := map[string]string{
+		docker.CPUUsagePercent: "50.00",
+		docker.NetworkRxRate:   "2000.00",
+		docker.NetworkTxRate:   "0.00",
+	}
+	test.Poll(t, 10*time.Millisecond, want, func() interface{} {
+		md := c.GetNodeMetadata()
+		have := map[string]string{}
+		for key := range want {
+			if value, ok := md.Metadata[key]; ok {
+				have[key] = value
+			}
+		}
+		return have
+	})
+}
+
+func TestContainerCgroupStats(t *testing.T) {
+	oldReadFile, oldNow := cgroup.ReadFile, cgroup.Now
+	defer func() { cgroup.ReadFile, cgroup.Now = oldReadFile, oldNow }()
+
+	// Two samples two seconds apart, during which the container used a
+	// quarter of the system's time on two CPUs.
+	var (
+		now   = time.Unix(1440000000, 0)
+		files = map[string]string{
+			"/proc/1/cgroup": "0::/system.slice/docker-ping.scope\n",
+			"/sys/fs/cgroup/system.slice/docker-ping.scope/cpu.stat":       "usage_usec 1\n",
+			"/sys/fs/cgroup/system.slice/docker-ping.scope/memory.current": "12345\n",
+			"/sys/fs/cgroup/system.slice/docker-ping.scope/pids.current":   "3\n",
+			"/proc/stat": "cpu  1000 0 0 0 0 0 0 0 0 0\ncpu0 500 0 0 0 0 0 0 0 0 0\ncpu1 500 0 0 0 0 0 0 0 0 0\n",
+		}
+	)
+	cgroup.ReadFile = func(filename string) ([]byte, error) {
+		if contents, ok := files[filename]; ok {
+			return []byte(contents), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	cgroup.Now = func() time.Time { return now }
+
+	c := docker.NewContainer(container1, docker.Endpoint{}, cgroup.NewReader(cgroup.DefaultRoot, "/proc"))
+	if err := c.StartGatheringStats(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.StopGatheringStats()
+	c.GetNodeMetadata()
+
+	now = now.Add(2 * time.Second)
+	files["/sys/fs/cgroup/system.slice/docker-ping.scope/cpu.stat"] = "usage_usec 1000001\n"
+	files["/proc/stat"] = "cpu  1400 0 0 0 0 0 0 0 0 0\ncpu0 700 0 0 0 0 0 0 0 0 0\ncpu1 700 0 0 0 0 0 0 0 0 0\n"
+
+	want := map[string]string{
+		docker.MemoryUsage:     "12345",
+		docker.PidsCurrent:     "3",
+		docker.CPUUsagePercent: "50.00",
+	}
+	md := c.GetNodeMetadata()
+	have := map[string]string{}
+	for key := range want {
+		have[key] = md.Metadata[key]
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestContainerMetadata(t *testing.T) {
+	c := docker.NewContainer(&client.Container{
+		ID:    "ping",
+		Name:  "/pong",
+		Image: "baz",
+		State: client.State{
+			StartedAt:  time.Unix(1440000000, 0),
+			FinishedAt: time.Unix(1440000060, 0),
+			ExitCode:   137,
+			Health:     client.Health{Status: "unhealthy"},
+		},
+		RestartCount:    2,
+		Config:          &client.Config{Hostname: "pong", Labels: map[string]string{"tier": "frontend"}},
+		HostConfig:      &client.HostConfig{NetworkMode: "bridge"},
+		NetworkSettings: &client.NetworkSettings{Networks: map[string]client.ContainerNetwork{"bridge": {IPAddress: "172.17.0.2"}}},
+	}, docker.Endpoint{}, nil)
+
+	md := c.GetNodeMetadata()
+	want := map[string]string{
+		docker.ContainerState:             "exited",
+		docker.ContainerExitCode:          "137",
+		docker.ContainerRestartCount:      "2",
+		docker.ContainerHealth:            "unhealthy",
+		docker.ContainerHostname:          "pong",
+		docker.ContainerNetworkMode:       "bridge",
+		docker.LabelPrefix + "tier":       "frontend",
+		docker.NetworkIPPrefix + "bridge": "172.17.0.2",
+	}
+	have := map[string]string{}
+	for key := range want {
+		have[key] = md.Metadata[key]
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+
+	if want, have := map[string]string{"tier": "frontend"}, docker.Labels(md); !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
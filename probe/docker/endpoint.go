@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path"
+)
+
+// DefaultHost is the local Docker daemon's socket.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// Vars exported for testing.
+var (
+	Getenv       = os.Getenv
+	ReadCertFile = ioutil.ReadFile
+)
+
+// Endpoint is how we talk to the Docker daemon, be it over a unix socket
+// or TCP. If CertPath is set, TCP connections use TLS, with the client
+// certificate and key in cert.pem and key.pem; if TLSVerify is also set, the
+// daemon's certificate is verified against ca.pem.
+type Endpoint struct {
+	Host      string
+	CertPath  string
+	TLSVerify bool
+}
+
+// EndpointFromEnv returns the Endpoint configured the way the docker client
+// is: with DOCKER_HOST, DOCKER_CERT_PATH and DOCKER_TLS_VERIFY.
+func EndpointFromEnv() Endpoint {
+	e := Endpoint{
+		Host:      Getenv("DOCKER_HOST"),
+		CertPath:  Getenv("DOCKER_CERT_PATH"),
+		TLSVerify: Getenv("DOCKER_TLS_VERIFY") != "",
+	}
+	if e.Host == "" {
+		e.Host = DefaultHost
+	}
+	if e.TLSVerify && e.CertPath == "" {
+		if home := Getenv("HOME"); home != "" {
+			e.CertPath = path.Join(home, ".docker")
+		}
+	}
+	return e
+}
+
+func (e Endpoint) useTLS() bool {
+	return e.CertPath != "" || e.TLSVerify
+}
+
+func (e Endpoint) certFile(name string) string {
+	return path.Join(e.CertPath, name)
+}
+
+// network returns the network and address to dial to reach the daemon.
+func (e Endpoint) network() (string, string, error) {
+	u, err := url.Parse(e.Host)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "unix":
+		return "unix", u.Path, nil
+	case "tcp", "http", "https":
+		return "tcp", u.Host, nil
+	}
+	return "", "", fmt.Errorf("unsupported docker endpoint %q", e.Host)
+}
+
+// tlsConfig loads the client certificate and, if we verify the daemon, the
+// CA certificate.
+func (e Endpoint) tlsConfig() (*tls.Config, error) {
+	certPEM, err := ReadCertFile(e.certFile("cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ReadCertFile(e.certFile("key.pem"))
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: !e.TLSVerify,
+	}
+	if e.TLSVerify {
+		caPEM, err := ReadCertFile(e.certFile("ca.pem"))
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates in %s", e.certFile("ca.pem"))
+		}
+	}
+	return config, nil
+}
+
+// dial connects to the daemon, for requests the client library doesn't
+// make for us.
+func (e Endpoint) dial() (net.Conn, error) {
+	network, address, err := e.network()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := DialStub(network, address)
+	if err != nil || network != "tcp" || !e.useTLS() {
+		return conn, err
+	}
+
+	config, err := e.tlsConfig()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		config.ServerName = host
+	}
+	return tls.Client(conn, config), nil
+}
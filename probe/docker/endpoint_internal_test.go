@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEndpointFromEnv(t *testing.T) {
+	oldGetenv := Getenv
+	defer func() { Getenv = oldGetenv }()
+
+	for _, tc := range []struct {
+		env  map[string]string
+		want Endpoint
+	}{
+		{map[string]string{}, Endpoint{Host: DefaultHost}},
+		{
+			map[string]string{"DOCKER_HOST": "tcp://10.0.0.1:2376", "DOCKER_CERT_PATH": "/certs", "DOCKER_TLS_VERIFY": "1"},
+			Endpoint{Host: "tcp://10.0.0.1:2376", CertPath: "/certs", TLSVerify: true},
+		},
+		{
+			map[string]string{"DOCKER_HOST": "tcp://10.0.0.1:2376", "DOCKER_TLS_VERIFY": "1", "HOME": "/root"},
+			Endpoint{Host: "tcp://10.0.0.1:2376", CertPath: "/root/.docker", TLSVerify: true},
+		},
+	} {
+		Getenv = func(key string) string { return tc.env[key] }
+		if have := EndpointFromEnv(); tc.want != have {
+			t.Errorf("%v: want %+v, have %+v", tc.env, tc.want, have)
+		}
+	}
+}
+
+func TestEndpointNetwork(t *testing.T) {
+	for host, want := range map[string][2]string{
+		"unix:///var/run/docker.sock": {"unix", "/var/run/docker.sock"},
+		"tcp://10.0.0.1:2376":         {"tcp", "10.0.0.1:2376"},
+		"https://docker.local:2376":   {"tcp", "docker.local:2376"},
+	} {
+		network, address, err := Endpoint{Host: host}.network()
+		if err != nil || network != want[0] || address != want[1] {
+			t.Errorf("%s: want %v, have %s %s %v", host, want, network, address, err)
+		}
+	}
+	if _, _, err := (Endpoint{Host: "npipe:////./pipe/docker"}).network(); err == nil {
+		t.Errorf("expected an error for an unsupported scheme")
+	}
+}
+
+func TestEndpointTLSConfig(t *testing.T) {
+	certPEM, keyPEM := selfSignedCert(t)
+	files := map[string][]byte{
+		"/certs/cert.pem": certPEM,
+		"/certs/key.pem":  keyPEM,
+		"/certs/ca.pem":   certPEM,
+	}
+	oldReadCertFile := ReadCertFile
+	defer func() { ReadCertFile = oldReadCertFile }()
+	ReadCertFile = func(filename string) ([]byte, error) {
+		if contents, ok := files[filename]; ok {
+			return contents, nil
+		}
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+
+	config, err := Endpoint{Host: "tcp://10.0.0.1:2376", CertPath: "/certs"}.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Certificates) != 1 || !config.InsecureSkipVerify || config.RootCAs != nil {
+		t.Errorf("unverified config: %+v", config)
+	}
+
+	config, err = Endpoint{Host: "tcp://10.0.0.1:2376", CertPath: "/certs", TLSVerify: true}.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Certificates) != 1 || config.InsecureSkipVerify || config.RootCAs == nil {
+		t.Errorf("verified config: %+v", config)
+	}
+
+	delete(files, "/certs/ca.pem")
+	if _, err := (Endpoint{Host: "tcp://10.0.0.1:2376", CertPath: "/certs", TLSVerify: true}).tlsConfig(); err == nil {
+		t.Errorf("expected an error without a CA certificate")
+	}
+}
+
+func selfSignedCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "scope"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
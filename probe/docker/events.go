@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"strings"
+	"time"
+)
+
+// ContainerEvents is the key for a container's recent lifecycle events in
+// its node metadata, encoded with EncodeEvents.
+const ContainerEvents = "docker_container_events"
+
+// Event is something which happened to a container, as reported by the
+// Docker daemon. ExitCode is only set for die events.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Status   string    `json:"status"`
+	ExitCode string    `json:"exit_code,omitempty"`
+}
+
+// EncodeEvents encodes events for node metadata, as a comma-separated list
+// of "time status [exit code]" entries.
+//
+//   2015-08-19T12:00:00Z start,2015-08-19T12:05:00Z die 137
+func EncodeEvents(events []Event) string {
+	entries := make([]string, 0, len(events))
+	for _, e := range events {
+		entry := e.Time.UTC().Format(time.RFC3339) + " " + e.Status
+		if e.ExitCode != "" {
+			entry += " " + e.ExitCode
+		}
+		entries = append(entries, entry)
+	}
+	return strings.Join(entries, ",")
+}
+
+// DecodeEvents is the inverse of EncodeEvents. Malformed entries are
+// skipped.
+func DecodeEvents(s string) []Event {
+	result := []Event{}
+	for _, entry := range strings.Split(s, ",") {
+		fields := strings.Fields(entry)
+		if len(fields) < 2 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			continue
+		}
+		e := Event{Time: t, Status: fields[1]}
+		if len(fields) > 2 {
+			e.ExitCode = fields[2]
+		}
+		result = append(result, e)
+	}
+	return result
+}
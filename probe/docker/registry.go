@@ -2,17 +2,28 @@ package docker
 
 import (
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
 	docker_client "github.com/fsouza/go-dockerclient"
+
+	"github.com/weaveworks/scope/probe/cgroup"
 )
 
 // Consts exported for testing.
 const (
-	StartEvent = "start"
-	DieEvent   = "die"
-	endpoint   = "unix:///var/run/docker.sock"
+	CreateEvent  = "create"
+	StartEvent   = "start"
+	PauseEvent   = "pause"
+	UnpauseEvent = "unpause"
+	DieEvent     = "die"
+	DestroyEvent = "destroy"
+	OOMEvent     = "oom"
+	KillEvent    = "kill"
+
+	// maxEvents is the number of recent events we keep for each container.
+	maxEvents = 10
 )
 
 // Vars exported for testing.
@@ -21,12 +32,15 @@ var (
 	NewContainerStub    = NewContainer
 )
 
-// Registry keeps track of running docker containers and their images
+// Registry keeps track of containers, running or not, and their images. It
+// is the probe's view of a container runtime: this package implements it for
+// the Docker daemon, and probe/cri for CRI runtimes such as containerd.
 type Registry interface {
 	Stop()
 	LockedPIDLookup(f func(func(int) Container))
 	WalkContainers(f func(Container))
 	WalkImages(f func(*docker_client.APIImages))
+	WalkEvents(f func(containerID string, events []Event))
 }
 
 type registry struct {
@@ -34,10 +48,13 @@ type registry struct {
 	quit     chan chan struct{}
 	interval time.Duration
 	client   Client
+	endpoint Endpoint
+	cgroups  *cgroup.Reader
 
-	containers      map[string]Container
+	containers      map[string]DockerContainer
 	containersByPID map[int]Container
 	images          map[string]*docker_client.APIImages
+	events          map[string][]Event
 }
 
 // Client interface for mocking.
@@ -49,23 +66,35 @@ type Client interface {
 	RemoveEventListener(chan *docker_client.APIEvents) error
 }
 
-func newDockerClient(endpoint string) (Client, error) {
-	return docker_client.NewClient(endpoint)
+func newDockerClient(endpoint Endpoint) (Client, error) {
+	if !endpoint.useTLS() {
+		return docker_client.NewClient(endpoint.Host)
+	}
+	var ca string
+	if endpoint.TLSVerify {
+		ca = endpoint.certFile("ca.pem")
+	}
+	return docker_client.NewTLSClient(endpoint.Host, endpoint.certFile("cert.pem"), endpoint.certFile("key.pem"), ca)
 }
 
-// NewRegistry returns a usable Registry. Don't forget to Stop it.
-func NewRegistry(interval time.Duration) (Registry, error) {
+// NewRegistry returns a usable Registry, talking to the Docker daemon at
+// endpoint. Don't forget to Stop it. If cgroups is not nil, container stats
+// are read from it rather than the Docker daemon.
+func NewRegistry(endpoint Endpoint, interval time.Duration, cgroups *cgroup.Reader) (Registry, error) {
 	client, err := NewDockerClientStub(endpoint)
 	if err != nil {
 		return nil, err
 	}
 
 	r := &registry{
-		containers:      map[string]Container{},
+		containers:      map[string]DockerContainer{},
 		containersByPID: map[int]Container{},
 		images:          map[string]*docker_client.APIImages{},
+		events:          map[string][]Event{},
 
 		client:   client,
+		endpoint: endpoint,
+		cgroups:  cgroups,
 		interval: interval,
 		quit:     make(chan chan struct{}),
 	}
@@ -158,9 +187,10 @@ func (r *registry) reset() {
 		c.StopGatheringStats()
 	}
 
-	r.containers = map[string]Container{}
+	r.containers = map[string]DockerContainer{}
 	r.containersByPID = map[int]Container{}
 	r.images = map[string]*docker_client.APIImages{}
+	r.events = map[string][]Event{}
 }
 
 func (r *registry) updateContainers() error {
@@ -170,7 +200,7 @@ func (r *registry) updateContainers() error {
 	}
 
 	for _, apiContainer := range apiContainers {
-		if err := r.addContainer(apiContainer.ID); err != nil {
+		if _, err := r.updateContainer(apiContainer.ID); err != nil {
 			return err
 		}
 	}
@@ -196,41 +226,92 @@ func (r *registry) updateImages() error {
 }
 
 func (r *registry) handleEvent(event *docker_client.APIEvents) {
-	switch event.Status {
-	case DieEvent:
-		containerID := event.ID
-		r.removeContainer(containerID)
+	e := Event{Time: time.Unix(event.Time, 0).UTC(), Status: event.Status}
+	if event.Time == 0 {
+		e.Time = time.Now().UTC()
+	}
 
-	case StartEvent:
-		containerID := event.ID
-		if err := r.addContainer(containerID); err != nil {
+	switch event.Status {
+	case CreateEvent, StartEvent, PauseEvent, UnpauseEvent, DieEvent:
+		dockerContainer, err := r.updateContainer(event.ID)
+		if err != nil {
 			log.Printf("docker registry: %s", err)
 		}
+		if dockerContainer == nil {
+			return
+		}
+		if event.Status == DieEvent {
+			e.ExitCode = strconv.Itoa(dockerContainer.State.ExitCode)
+		}
+
+	case OOMEvent, KillEvent:
+
+	case DestroyEvent:
+		r.removeContainer(event.ID)
+		return
+
+	default:
+		return
 	}
+
+	r.recordEvent(event.ID, e)
 }
 
-func (r *registry) addContainer(containerID string) error {
+// recordEvent remembers the event, forgetting the oldest of the container's
+// events if it has too many.
+func (r *registry) recordEvent(containerID string, e Event) {
+	r.Lock()
+	defer r.Unlock()
+
+	events := append(r.events[containerID], e)
+	if len(events) > maxEvents {
+		events = events[len(events)-maxEvents:]
+	}
+	r.events[containerID] = events
+}
+
+// updateContainer inspects the container and starts tracking it, or updates
+// what we knew about it before. Only running containers have stats, and
+// processes to look up. If the container has gone away, we forget it, and
+// return nil.
+func (r *registry) updateContainer(containerID string) (*docker_client.Container, error) {
 	dockerContainer, err := r.client.InspectContainer(containerID)
 	if err != nil {
 		// Don't spam the logs if the container was short lived
 		if _, ok := err.(*docker_client.NoSuchContainer); ok {
-			return nil
+			r.removeContainer(containerID)
+			return nil, nil
 		}
-		return err
+		return nil, err
 	}
+	return dockerContainer, r.addContainer(dockerContainer)
+}
 
-	if !dockerContainer.State.Running {
-		// We get events late, and the containers sometimes have already
-		// stopped.  Not an error, so don't return it.
-		return nil
-	}
+func (r *registry) addContainer(dockerContainer *docker_client.Container) error {
+	containerID := dockerContainer.ID
 
 	r.Lock()
 	defer r.Unlock()
 
-	c := NewContainerStub(dockerContainer)
-	r.containers[containerID] = c
+	// Containers we already know are updated in place, so pausing or
+	// restarting them doesn't lose their stats, and the rates from them.
+	c, ok := r.containers[containerID]
+	wasRunning := ok && c.PID() != 0
+	if ok {
+		r.forgetPID(c)
+		c.UpdateState(dockerContainer)
+	} else {
+		c = NewContainerStub(dockerContainer, r.endpoint, r.cgroups)
+		r.containers[containerID] = c
+	}
+	if !dockerContainer.State.Running {
+		c.StopGatheringStats()
+		return nil
+	}
 	r.containersByPID[dockerContainer.State.Pid] = c
+	if wasRunning {
+		return nil
+	}
 
 	return c.StartGatheringStats()
 }
@@ -245,10 +326,18 @@ func (r *registry) removeContainer(containerID string) {
 	}
 
 	delete(r.containers, containerID)
-	delete(r.containersByPID, container.PID())
+	delete(r.events, containerID)
+	r.forgetPID(container)
 	container.StopGatheringStats()
 }
 
+// forgetPID stops pid lookups finding c. Must be called with the lock held.
+func (r *registry) forgetPID(c Container) {
+	if r.containersByPID[c.PID()] == c {
+		delete(r.containersByPID, c.PID())
+	}
+}
+
 // LockedPIDLookup runs f under a read lock, and gives f a function for
 // use doing pid->container lookups.
 func (r *registry) LockedPIDLookup(f func(func(int) Container)) {
@@ -262,7 +351,8 @@ func (r *registry) LockedPIDLookup(f func(func(int) Container)) {
 	f(lookup)
 }
 
-// WalkContainers runs f on every running containers the registry knows of.
+// WalkContainers runs f on every container the registry knows of, whatever
+// its state.
 func (r *registry) WalkContainers(f func(Container)) {
 	r.RLock()
 	defer r.RUnlock()
@@ -272,13 +362,16 @@ func (r *registry) WalkContainers(f func(Container)) {
 	}
 }
 
-// WalkImages runs f on every image of running containers the registry
-// knows of.  f may be run on the same image more than once.
+// WalkImages runs f on every image of containers the registry knows of,
+// whether they're running or not.  f may be run on the same image more than
+// once.
 func (r *registry) WalkImages(f func(*docker_client.APIImages)) {
 	r.RLock()
 	defer r.RUnlock()
 
-	// Loop over containers so we only emit images for running containers.
+	// Loop over containers so we only emit images which have containers,
+	// including stopped ones; the reporter counts the running ones, for
+	// the "in use" filter.
 	for _, container := range r.containers {
 		image, ok := r.images[container.Image()]
 		if ok {
@@ -286,3 +379,14 @@ func (r *registry) WalkImages(f func(*docker_client.APIImages)) {
 		}
 	}
 }
+
+// WalkEvents runs f on the recent events of every container which has had
+// any.
+func (r *registry) WalkEvents(f func(containerID string, events []Event)) {
+	r.RLock()
+	defer r.RUnlock()
+
+	for containerID, events := range r.events {
+		f(containerID, events)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"reflect"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	client "github.com/fsouza/go-dockerclient"
 
+	"github.com/weaveworks/scope/probe/cgroup"
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test"
@@ -27,10 +29,18 @@ func (c *mockContainer) PID() int {
 	return c.c.State.Pid
 }
 
+func (c *mockContainer) State() string {
+	return docker.State(c.c)
+}
+
 func (c *mockContainer) Image() string {
 	return c.c.Image
 }
 
+func (c *mockContainer) UpdateState(container *client.Container) {
+	c.c = container
+}
+
 func (c *mockContainer) StartGatheringStats() error {
 	return nil
 }
@@ -62,7 +72,11 @@ func (m *mockDockerClient) ListContainers(client.ListContainersOptions) ([]clien
 func (m *mockDockerClient) InspectContainer(id string) (*client.Container, error) {
 	m.RLock()
 	defer m.RUnlock()
-	return m.containers[id], nil
+	c, ok := m.containers[id]
+	if !ok {
+		return nil, &client.NoSuchContainer{ID: id}
+	}
+	return c, nil
 }
 
 func (m *mockDockerClient) ListImages(client.ListImagesOptions) ([]client.APIImages, error) {
@@ -110,8 +124,14 @@ var (
 		Image: "baz",
 		State: client.State{Pid: 1, Running: true},
 	}
+	container2Exited = &client.Container{
+		ID:    "wiff",
+		Name:  "waff",
+		Image: "baz",
+		State: client.State{ExitCode: 1},
+	}
 	apiContainer1 = client.APIContainers{ID: "ping"}
-	apiImage1     = client.APIImages{ID: "baz", RepoTags: []string{"bang", "not-chosen"}}
+	apiImage1     = client.APIImages{ID: "baz", RepoTags: []string{"bang", "not-chosen"}, Created: 1440000000, VirtualSize: 1048576, Labels: map[string]string{"maintainer": "me"}}
 	mockClient    = mockDockerClient{
 		apiContainers: []client.APIContainers{apiContainer1},
 		containers:    map[string]*client.Container{"ping": container1},
@@ -123,11 +143,11 @@ func setupStubs(mdc *mockDockerClient, f func()) {
 	oldDockerClient, oldNewContainer := docker.NewDockerClientStub, docker.NewContainerStub
 	defer func() { docker.NewDockerClientStub, docker.NewContainerStub = oldDockerClient, oldNewContainer }()
 
-	docker.NewDockerClientStub = func(endpoint string) (docker.Client, error) {
+	docker.NewDockerClientStub = func(docker.Endpoint) (docker.Client, error) {
 		return mdc, nil
 	}
 
-	docker.NewContainerStub = func(c *client.Container) docker.Container {
+	docker.NewContainerStub = func(c *client.Container, _ docker.Endpoint, _ *cgroup.Reader) docker.DockerContainer {
 		return &mockContainer{c}
 	}
 
@@ -160,7 +180,7 @@ func allImages(r docker.Registry) []*client.APIImages {
 func TestRegistry(t *testing.T) {
 	mdc := mockClient // take a copy
 	setupStubs(&mdc, func() {
-		registry, _ := docker.NewRegistry(10 * time.Second)
+		registry, _ := docker.NewRegistry(docker.Endpoint{Host: docker.DefaultHost}, 10*time.Second, nil)
 		defer registry.Stop()
 		runtime.Gosched()
 
@@ -184,7 +204,7 @@ func TestRegistry(t *testing.T) {
 func TestRegistryEvents(t *testing.T) {
 	mdc := mockClient // take a copy
 	setupStubs(&mdc, func() {
-		registry, _ := docker.NewRegistry(10 * time.Second)
+		registry, _ := docker.NewRegistry(docker.Endpoint{Host: docker.DefaultHost}, 10*time.Second, nil)
 		defer registry.Stop()
 		runtime.Gosched()
 
@@ -205,13 +225,38 @@ func TestRegistryEvents(t *testing.T) {
 			check(want)
 		}
 
+		started := allContainers(registry)[1]
+
 		{
 			mdc.Lock()
-			delete(mdc.containers, "wiff")
+			mdc.containers["wiff"] = container2Exited
 			mdc.Unlock()
 			mdc.send(&client.APIEvents{Status: docker.DieEvent, ID: "wiff"})
 			runtime.Gosched()
 
+			want := []docker.Container{&mockContainer{container1}, &mockContainer{container2Exited}}
+			check(want)
+
+			// The container is updated in place, keeping its stats.
+			if have := allContainers(registry)[1]; have != started {
+				t.Errorf("container was replaced on die: %p != %p", have, started)
+			}
+		}
+
+		{
+			want := []string{"start", "die 1"}
+			test.Poll(t, 10*time.Millisecond, want, func() interface{} {
+				return eventStatuses(registry, "wiff")
+			})
+		}
+
+		{
+			mdc.Lock()
+			delete(mdc.containers, "wiff")
+			mdc.Unlock()
+			mdc.send(&client.APIEvents{Status: docker.DestroyEvent, ID: "wiff"})
+			runtime.Gosched()
+
 			want := []docker.Container{&mockContainer{container1}}
 			check(want)
 		}
@@ -220,7 +265,7 @@ func TestRegistryEvents(t *testing.T) {
 			mdc.Lock()
 			delete(mdc.containers, "ping")
 			mdc.Unlock()
-			mdc.send(&client.APIEvents{Status: docker.DieEvent, ID: "ping"})
+			mdc.send(&client.APIEvents{Status: docker.DestroyEvent, ID: "ping"})
 			runtime.Gosched()
 
 			want := []docker.Container{}
@@ -228,7 +273,7 @@ func TestRegistryEvents(t *testing.T) {
 		}
 
 		{
-			mdc.send(&client.APIEvents{Status: docker.DieEvent, ID: "doesntexist"})
+			mdc.send(&client.APIEvents{Status: docker.DestroyEvent, ID: "doesntexist"})
 			runtime.Gosched()
 
 			want := []docker.Container{}
@@ -236,3 +281,16 @@ func TestRegistryEvents(t *testing.T) {
 		}
 	})
 }
+
+func eventStatuses(r docker.Registry, containerID string) []string {
+	result := []string{}
+	r.WalkEvents(func(id string, events []docker.Event) {
+		if id != containerID {
+			return
+		}
+		for _, e := range events {
+			result = append(result, strings.TrimSpace(e.Status+" "+e.ExitCode))
+		}
+	})
+	return result
+}
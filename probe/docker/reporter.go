@@ -1,6 +1,10 @@
 package docker
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	docker_client "github.com/fsouza/go-dockerclient"
 
 	"github.com/weaveworks/scope/report"
@@ -8,8 +12,15 @@ import (
 
 // Keys for use in NodeMetadata
 const (
-	ImageID   = "docker_image_id"
-	ImageName = "docker_image_name"
+	ImageID                = "docker_image_id"
+	ImageName              = "docker_image_name"
+	ImageTags              = "docker_image_tags"
+	ImageVirtualSize       = "docker_image_virtual_size"
+	ImageCreated           = "docker_image_created"
+	ImageRunningContainers = "docker_image_running_containers"
+
+	// Each image label has a key made up of this prefix and the label.
+	ImageLabelPrefix = "docker_image_label_"
 )
 
 // Reporter generate Reports containing Container and ContainerImage topologies
@@ -42,19 +53,44 @@ func (r *Reporter) containerTopology() report.Topology {
 		result.NodeMetadatas[nodeID] = c.GetNodeMetadata()
 	})
 
+	r.registry.WalkEvents(func(containerID string, events []Event) {
+		nodeID := report.MakeContainerNodeID(r.scope, containerID)
+		if nmd, ok := result.NodeMetadatas[nodeID]; ok {
+			nmd.Metadata[ContainerEvents] = EncodeEvents(events)
+		}
+	})
+
 	return result
 }
 
 func (r *Reporter) containerImageTopology() report.Topology {
 	result := report.NewTopology()
 
+	running := map[string]int{}
+	r.registry.WalkContainers(func(c Container) {
+		if c.State() == "running" {
+			running[c.Image()]++
+		}
+	})
+
 	r.registry.WalkImages(func(image *docker_client.APIImages) {
 		nmd := report.MakeNodeMetadataWith(map[string]string{
-			ImageID: image.ID,
+			ImageID:                image.ID,
+			ImageVirtualSize:       strconv.FormatInt(image.VirtualSize, 10),
+			ImageRunningContainers: strconv.Itoa(running[image.ID]),
 		})
 
+		// Not every runtime knows when its images were created.
+		if image.Created != 0 {
+			nmd.Metadata[ImageCreated] = time.Unix(image.Created, 0).UTC().Format(time.RFC822)
+		}
+
 		if len(image.RepoTags) > 0 {
 			nmd.Metadata[ImageName] = image.RepoTags[0]
+			nmd.Metadata[ImageTags] = strings.Join(image.RepoTags, ", ")
+		}
+		for key, value := range image.Labels {
+			nmd.Metadata[ImageLabelPrefix+key] = value
 		}
 
 		nodeID := report.MakeContainerNodeID(r.scope, image.ID)
@@ -3,6 +3,7 @@ package docker_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	client "github.com/fsouza/go-dockerclient"
 
@@ -14,6 +15,7 @@ import (
 type mockRegistry struct {
 	containersByPID map[int]docker.Container
 	images          map[string]*client.APIImages
+	events          map[string][]docker.Event
 }
 
 func (r *mockRegistry) Stop() {}
@@ -36,6 +38,12 @@ func (r *mockRegistry) WalkImages(f func(*client.APIImages)) {
 	}
 }
 
+func (r *mockRegistry) WalkEvents(f func(string, []docker.Event)) {
+	for id, events := range r.events {
+		f(id, events)
+	}
+}
+
 var (
 	mockRegistryInstance = &mockRegistry{
 		containersByPID: map[int]docker.Container{
@@ -44,6 +52,15 @@ var (
 		images: map[string]*client.APIImages{
 			"baz": &apiImage1,
 		},
+		events: map[string][]docker.Event{
+			"ping": {
+				{Time: time.Unix(1440000000, 0), Status: docker.StartEvent},
+				{Time: time.Unix(1440000060, 0), Status: docker.DieEvent, ExitCode: "137"},
+			},
+			"gone": {
+				{Time: time.Unix(1440000000, 0), Status: docker.StartEvent},
+			},
+		},
 	}
 )
 
@@ -54,9 +71,10 @@ func TestReporter(t *testing.T) {
 		EdgeMetadatas: report.EdgeMetadatas{},
 		NodeMetadatas: report.NodeMetadatas{
 			report.MakeContainerNodeID("", "ping"): report.MakeNodeMetadataWith(map[string]string{
-				docker.ContainerID:   "ping",
-				docker.ContainerName: "pong",
-				docker.ImageID:       "baz",
+				docker.ContainerID:     "ping",
+				docker.ContainerName:   "pong",
+				docker.ImageID:         "baz",
+				docker.ContainerEvents: "2015-08-19T16:00:00Z start,2015-08-19T16:01:00Z die 137",
 			}),
 		},
 	}
@@ -65,8 +83,13 @@ func TestReporter(t *testing.T) {
 		EdgeMetadatas: report.EdgeMetadatas{},
 		NodeMetadatas: report.NodeMetadatas{
 			report.MakeContainerNodeID("", "baz"): report.MakeNodeMetadataWith(map[string]string{
-				docker.ImageID:   "baz",
-				docker.ImageName: "bang",
+				docker.ImageID:                         "baz",
+				docker.ImageName:                       "bang",
+				docker.ImageTags:                       "bang, not-chosen",
+				docker.ImageVirtualSize:                "1048576",
+				docker.ImageCreated:                    "19 Aug 15 16:00 UTC",
+				docker.ImageRunningContainers:          "1",
+				docker.ImageLabelPrefix + "maintainer": "me",
 			}),
 		},
 	}
@@ -0,0 +1,179 @@
+package endpoint
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/scope/report"
+)
+
+const sysClassNet = "/sys/class/net"
+
+// namespace is a network namespace, along with a process running inside it,
+// through which we can see its socket tables. The zero namespace is the
+// probe's own.
+type namespace struct {
+	id      string   // inode of the namespace, e.g. "4026532496"
+	pid     int      // a process in the namespace
+	pids    []int    // every process in the namespace
+	veths   []string // host-side peers of the namespace's veth interfaces
+	sockets []socket // the namespace's socket tables
+}
+
+// annotate records the namespace on the metadata of a node inside it.
+func (ns namespace) annotate(md report.NodeMetadata) {
+	if ns.id == "" {
+		return
+	}
+	md.Metadata[NetNamespace] = ns.id
+	if len(ns.veths) > 0 {
+		md.Metadata[Veth] = strings.Join(ns.veths, ",")
+	}
+}
+
+// readNamespaces enumerates the network namespaces of the processes under
+// procRoot: the probe's own, and the others. If the kernel doesn't expose
+// namespaces, we return an error.
+func readNamespaces(procRoot string) (namespace, []namespace, error) {
+	self, err := Readlink(path.Join(procRoot, "self", "ns", "net"))
+	if err != nil {
+		return namespace{}, nil, err
+	}
+
+	dirEntries, err := ReadDir(procRoot)
+	if err != nil {
+		return namespace{}, nil, err
+	}
+
+	var (
+		own    = namespace{pids: []int{}}
+		result = []namespace{}
+		seen   = map[string]int{} // link -> index in result
+	)
+	for _, dirEntry := range dirEntries {
+		pid, err := strconv.Atoi(dirEntry.Name())
+		if err != nil {
+			continue
+		}
+		link, err := Readlink(path.Join(procRoot, dirEntry.Name(), "ns", "net"))
+		if err != nil {
+			continue
+		}
+		if link == self {
+			own.pids = append(own.pids, pid)
+			continue
+		}
+		if i, ok := seen[link]; ok {
+			result[i].pids = append(result[i].pids, pid)
+			continue
+		}
+		seen[link] = len(result)
+		result = append(result, namespace{
+			id:   strings.Trim(strings.TrimPrefix(link, "net:"), "[]"),
+			pid:  pid,
+			pids: []int{pid},
+		})
+	}
+	return own, result, nil
+}
+
+// link is a network interface, and the index of the interface it's linked
+// to, if any. Both ends of a veth are linked to each other, though their
+// indexes are only unique within their own namespaces.
+type link struct {
+	name            string
+	ifindex, iflink int
+}
+
+// readVethPeers returns the host-side interface of each pair of linked
+// interfaces (e.g. veths), keyed by its interface index, which is unique
+// in the host's namespace.
+func readVethPeers() (map[int]link, error) {
+	dirEntries, err := ReadDir(sysClassNet)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, dirEntry := range dirEntries {
+		names = append(names, dirEntry.Name())
+	}
+	result := map[int]link{}
+	for _, l := range readLinks(sysClassNet, names) {
+		result[l.ifindex] = l
+	}
+	return result, nil
+}
+
+// namespaceVeths returns the host-side peers of the linked interfaces in
+// the network namespace of pid. A namespace's interface is the peer of a
+// host interface if each is linked to the other's index. Its interfaces
+// are listed in /proc/<pid>/net/dev, and their indexes read from the sysfs
+// the process sees, which shows its own namespace's interfaces.
+func namespaceVeths(procRoot string, pid int, peers map[int]link) []string {
+	names, err := readInterfaceNames(procRoot, pid)
+	if err != nil {
+		return nil
+	}
+	result := []string{}
+	for _, l := range readLinks(path.Join(procRoot, strconv.Itoa(pid), "root", sysClassNet), names) {
+		if peer, ok := peers[l.iflink]; ok && peer.iflink == l.ifindex {
+			result = append(result, peer.name)
+		}
+	}
+	return result
+}
+
+// readLinks returns the interfaces with the names, under dir, which are
+// linked to another interface.
+func readLinks(dir string, names []string) []link {
+	result := []link{}
+	for _, name := range names {
+		ifindex, err := readInt(path.Join(dir, name, "ifindex"))
+		if err != nil {
+			continue
+		}
+		iflink, err := readInt(path.Join(dir, name, "iflink"))
+		if err != nil || iflink == ifindex {
+			continue
+		}
+		result = append(result, link{name, ifindex, iflink})
+	}
+	return result
+}
+
+func readInt(filename string) (int, error) {
+	buf, err := ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(buf)))
+}
+
+// readInterfaceNames returns the names of the interfaces in the network
+// namespace of pid, from its /proc/<pid>/net/dev.
+//
+//   Inter-|   Receive                                                |  Transmit
+//    face |bytes    packets errs drop fifo frame compressed multicast|bytes ...
+//       lo:       0       0    0    0    0     0          0         0        0 ...
+//     eth0:    1296      16    0    0    0     0          0         0      648 ...
+func readInterfaceNames(procRoot string, pid int) ([]string, error) {
+	buf, err := ReadFile(path.Join(procRoot, strconv.Itoa(pid), "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 2)
+		if len(fields) != 2 || strings.Contains(fields[0], "|") {
+			continue // header
+		}
+		result = append(result, strings.TrimSpace(fields[0]))
+	}
+	return result, scanner.Err()
+}
@@ -0,0 +1,302 @@
+package endpoint
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Hooks exposed for mocking
+var (
+	ReadDir  = ioutil.ReadDir
+	ReadFile = ioutil.ReadFile
+	Readlink = os.Readlink
+)
+
+// TCP socket states, as they appear in /proc/net/tcp. See
+// include/net/tcp_states.h in the kernel source.
+const (
+	tcpEstablished = 0x01
+	tcpListen      = 0x0A
+)
+
+var tcpStates = map[uint64]string{
+	0x01: "ESTABLISHED",
+	0x02: "SYN_SENT",
+	0x03: "SYN_RECV",
+	0x04: "FIN_WAIT1",
+	0x05: "FIN_WAIT2",
+	0x06: "TIME_WAIT",
+	0x07: "CLOSE",
+	0x08: "CLOSE_WAIT",
+	0x09: "LAST_ACK",
+	0x0A: "LISTEN",
+	0x0B: "CLOSING",
+}
+
+// socket is a single entry from one of the /proc/net socket tables.
+type socket struct {
+	transport     string
+	localAddress  net.IP
+	localPort     uint16
+	remoteAddress net.IP
+	remotePort    uint16
+	state         uint64
+	inode         uint64
+}
+
+// listening is true for TCP sockets accepting connections, and for UDP
+// sockets which aren't connected to a peer.
+func (s socket) listening() bool {
+	switch s.transport {
+	case "tcp":
+		return s.state == tcpListen
+	case "udp":
+		return s.remotePort == 0 && s.remoteAddress.IsUnspecified()
+	}
+	return false
+}
+
+func (s socket) stateName() string {
+	if name, ok := tcpStates[s.state]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+// readSockets reads the TCP and UDP socket tables under procRoot. Tables which don't
+// exist (no IPv6, or we're not on Linux) are skipped without error.
+func readSockets(procRoot string) ([]socket, error) {
+	var result []socket
+	for _, table := range []struct{ transport, filename string }{
+		{"tcp", "tcp"},
+		{"tcp", "tcp6"},
+		{"udp", "udp"},
+		{"udp", "udp6"},
+	} {
+		buf, err := ReadFile(path.Join(procRoot, "net", table.filename))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		sockets, err := parseProcNet(table.transport, buf)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", table.filename, err)
+		}
+		result = append(result, sockets...)
+	}
+	return result, nil
+}
+
+// parseProcNet parses the contents of a /proc/net/{tcp,tcp6,udp,udp6} file.
+//
+//   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//    0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   998        0 15869 ...
+func parseProcNet(transport string, buf []byte) ([]socket, error) {
+	var (
+		result = []socket{}
+		lines  = bytes.Split(buf, []byte{'\n'})
+	)
+	for i, line := range lines {
+		fields := strings.Fields(string(line))
+		if i == 0 || len(fields) < 10 {
+			continue // header or blank
+		}
+
+		localAddress, localPort, err := parseHexAddr(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		remoteAddress, remotePort, err := parseHexAddr(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, socket{
+			transport:     transport,
+			localAddress:  localAddress,
+			localPort:     localPort,
+			remoteAddress: remoteAddress,
+			remotePort:    remotePort,
+			state:         state,
+			inode:         inode,
+		})
+	}
+	return result, nil
+}
+
+// parseHexAddr parses an "address:port" pair as found in /proc/net/tcp. The
+// address is a sequence of 32 bit words in host (little endian) byte order;
+// the port is big endian.
+func parseHexAddr(s string) (net.IP, uint16, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	addr, err := hex.DecodeString(parts[0])
+	if err != nil || (len(addr) != net.IPv4len && len(addr) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	for i := 0; i < len(addr); i += 4 {
+		addr[i], addr[i+1], addr[i+2], addr[i+3] = addr[i+3], addr[i+2], addr[i+1], addr[i]
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port %q", s)
+	}
+	return net.IP(addr), uint16(port), nil
+}
+
+// listeners is the set of local addresses accepting TCP connections, keyed
+// by port.
+type listeners map[uint16][]net.IP
+
+func makeListeners(sockets []socket) listeners {
+	result := listeners{}
+	for _, s := range sockets {
+		if s.transport == "tcp" && s.listening() {
+			result[s.localPort] = append(result[s.localPort], s.localAddress)
+		}
+	}
+	return result
+}
+
+// contains is true if there's a listener on the port, bound to either the
+// given address or the unspecified address.
+func (l listeners) contains(addr net.IP, port uint16) bool {
+	for _, ip := range l[port] {
+		if ip.IsUnspecified() || ip.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// socketOwners finds the processes holding sockets open, for the sockets
+// procspy doesn't tell us about: listening sockets, connections which
+// aren't established, and the sockets of other network namespaces. procspy
+// walks the file descriptors of every process to find the owners of the
+// host's established connections, but keeps what it finds to itself. So
+// rather than walk them all again on every report, we remember the owners
+// we've found, and only walk the processes which could hold the sockets we
+// haven't seen before.
+type socketOwners struct {
+	procRoot string
+	owners   map[uint64]int // by socket inode; 0 if we couldn't find one
+}
+
+// ownerQuery is a set of sockets whose owners we want, and the processes
+// which could own them: those in the sockets' network namespace. If pids
+// is nil, any process could.
+type ownerQuery struct {
+	inodes []uint64
+	pids   []int
+}
+
+func newSocketOwners(procRoot string) *socketOwners {
+	return &socketOwners{procRoot: procRoot, owners: map[uint64]int{}}
+}
+
+// lookup returns the owners of the sockets in the queries, by inode.
+// Owners of sockets which weren't asked about are forgotten.
+func (o *socketOwners) lookup(queries []ownerQuery) map[uint64]int {
+	owners := map[uint64]int{}
+	for _, q := range queries {
+		unknown := map[uint64]struct{}{}
+		for _, inode := range q.inodes {
+			if pid, ok := o.owners[inode]; ok {
+				owners[inode] = pid
+			} else {
+				unknown[inode] = struct{}{}
+			}
+		}
+		if len(unknown) == 0 {
+			continue
+		}
+		found := walkSocketOwners(o.procRoot, q.pids, unknown)
+		for inode := range unknown {
+			owners[inode] = found[inode]
+		}
+	}
+	o.owners = owners
+	return owners
+}
+
+// walkSocketOwners maps the wanted socket inodes to the PID of a process
+// holding them open, by walking the file descriptors of pids, or of every
+// process under procRoot if pids is nil. Processes we can't inspect (they've
+// gone away, or we lack permission) are skipped.
+func walkSocketOwners(procRoot string, pids []int, wanted map[uint64]struct{}) map[uint64]int {
+	if pids == nil {
+		dirEntries, err := ReadDir(procRoot)
+		if err != nil {
+			return nil
+		}
+		for _, dirEntry := range dirEntries {
+			if pid, err := strconv.Atoi(dirEntry.Name()); err == nil {
+				pids = append(pids, pid)
+			}
+		}
+	}
+
+	owners := map[uint64]int{}
+	for _, pid := range pids {
+		fdDir := path.Join(procRoot, strconv.Itoa(pid), "fd")
+		fds, err := ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := Readlink(path.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.Trim(link[len("socket:"):], "[]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := wanted[inode]; !ok {
+				continue
+			}
+			if _, ok := owners[inode]; !ok {
+				owners[inode] = pid
+			}
+		}
+		if len(owners) == len(wanted) {
+			break
+		}
+	}
+	return owners
+}
+
+// ownedInodes returns the inodes of the sockets we want the owners of. In
+// the probe's own namespace, procspy tells us the owners of established
+// connections, so we only want the rest.
+func ownedInodes(sockets []socket, established bool) []uint64 {
+	inodes := []uint64{}
+	for _, s := range sockets {
+		if s.inode == 0 || !(s.listening() || s.transport == "tcp") {
+			continue
+		}
+		if !established && s.transport == "tcp" && s.state == tcpEstablished {
+			continue
+		}
+		inodes = append(inodes, s.inode)
+	}
+	return inodes
+}
@@ -2,7 +2,13 @@ package endpoint
 
 import (
 	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -14,16 +20,21 @@ import (
 
 // Node metadata keys.
 const (
-	Addr = "addr" // typically IPv4
-	Port = "port"
+	Addr         = "addr" // typically IPv4
+	Port         = "port"
+	Listening    = "listening"     // transport(s) on which the endpoint accepts connections, e.g. "tcp,udp"
+	NetNamespace = "net_namespace" // set if the endpoint isn't in the host's network namespace
+	Veth         = "veth"          // host-side peer(s) of the endpoint's namespace's interfaces
 )
 
 // Reporter generates Reports containing the Endpoint topology.
 type Reporter struct {
 	hostID           string
 	hostName         string
+	procRoot         string
 	includeProcesses bool
 	includeNAT       bool
+	owners           *socketOwners
 }
 
 // SpyDuration is an exported prometheus metric
@@ -42,13 +53,17 @@ var SpyDuration = prometheus.NewSummaryVec(
 // generate a report.Report that contains every discovered (spied) connection
 // on the host machine, at the granularity of host and port. That information
 // is stored in the Endpoint topology. It optionally enriches that topology
-// with process (PID) information.
-func NewReporter(hostID, hostName string, includeProcesses bool) *Reporter {
+// with process (PID) information, and with the listening sockets on the
+// host. The socket tables under procRoot are used to annotate connections
+// with their TCP state and direction.
+func NewReporter(hostID, hostName, procRoot string, includeProcesses bool) *Reporter {
 	return &Reporter{
 		hostID:           hostID,
 		hostName:         hostName,
+		procRoot:         procRoot,
 		includeProcesses: includeProcesses,
 		includeNAT:       conntrackModulePresent(),
+		owners:           newSocketOwners(procRoot),
 	}
 }
 
@@ -64,8 +79,31 @@ func (r *Reporter) Report() (report.Report, error) {
 		return rpt, err
 	}
 
-	for conn := conns.Next(); conn != nil; conn = conns.Next() {
-		r.addConnection(&rpt, conn)
+	// The socket tables give us the state of every connection (procspy only
+	// yields established ones), and tell us which ports are listening.
+	sockets, err := readSockets(r.procRoot)
+	if err != nil {
+		log.Printf("endpoint reporter: %v", err)
+	}
+
+	// Processes in other network namespaces (e.g. containers not sharing
+	// the host's network) have their own socket tables, which procspy
+	// can't see. Their owners are only worth looking up if we're reporting
+	// processes.
+	var (
+		own, namespaces = r.readNamespaces()
+		owners          map[uint64]int
+	)
+	if r.includeProcesses {
+		queries := []ownerQuery{{inodes: ownedInodes(sockets, false), pids: own.pids}}
+		for _, ns := range namespaces {
+			queries = append(queries, ownerQuery{inodes: ownedInodes(ns.sockets, true), pids: ns.pids})
+		}
+		owners = r.owners.lookup(queries)
+	}
+	r.addSockets(&rpt, namespace{}, sockets, conns, owners)
+	for _, ns := range namespaces {
+		r.addSockets(&rpt, ns, ns.sockets, nil, owners)
 	}
 
 	if r.includeNAT {
@@ -75,29 +113,151 @@ func (r *Reporter) Report() (report.Report, error) {
 	return rpt, err
 }
 
-func (r *Reporter) addConnection(rpt *report.Report, c *procspy.Connection) {
+// readNamespaces returns the probe's own network namespace, and the others,
+// with their veths and sockets. If the kernel doesn't expose namespaces, the
+// probe's own namespace has nil pids, as any process could be in it.
+func (r *Reporter) readNamespaces() (namespace, []namespace) {
+	own, namespaces, err := readNamespaces(r.procRoot)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("endpoint reporter: %v", err)
+	}
+	if len(namespaces) == 0 {
+		return own, nil
+	}
+	peers, err := readVethPeers()
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("endpoint reporter: %v", err)
+	}
+
+	result := []namespace{}
+	for _, ns := range namespaces {
+		ns.veths = namespaceVeths(r.procRoot, ns.pid, peers)
+		sockets, err := readSockets(path.Join(r.procRoot, strconv.Itoa(ns.pid)))
+		if err != nil {
+			log.Printf("endpoint reporter: namespace %s: %v", ns.id, err)
+			continue
+		}
+		ns.sockets = sockets
+		result = append(result, ns)
+	}
+	return own, result
+}
+
+// addSockets adds the connections and listening sockets of a network
+// namespace to the report. In the probe's own namespace, conns (from
+// procspy) tells us the established connections and their processes;
+// otherwise we rely on the socket tables and owners.
+func (r *Reporter) addSockets(rpt *report.Report, ns namespace, sockets []socket, conns procspy.ConnIter, owners map[uint64]int) {
+	var (
+		listening = makeListeners(sockets)
+		states    = map[connectionKey]socket{}
+	)
+	for _, s := range sockets {
+		if s.transport == "tcp" && !s.listening() {
+			states[makeConnectionKey(s.localAddress, s.localPort, s.remoteAddress, s.remotePort)] = s
+		}
+	}
+
+	if conns != nil {
+		for conn := conns.Next(); conn != nil; conn = conns.Next() {
+			key := makeConnectionKey(conn.LocalAddress, conn.LocalPort, conn.RemoteAddress, conn.RemotePort)
+			state := tcpStates[tcpEstablished]
+			if s, ok := states[key]; ok {
+				state = s.stateName()
+				delete(states, key)
+			}
+			r.addConnection(rpt, ns, conn, state, listening, len(sockets) > 0)
+		}
+	}
+
+	// Whatever is left are connections procspy didn't tell us about, e.g.
+	// those in TIME_WAIT. Unless we know their owner, they only make it to
+	// the address topology.
+	for _, s := range states {
+		r.addConnection(rpt, ns, &procspy.Connection{
+			Transport:     s.transport,
+			LocalAddress:  s.localAddress,
+			LocalPort:     s.localPort,
+			RemoteAddress: s.remoteAddress,
+			RemotePort:    s.remotePort,
+			Proc:          procspy.Proc{PID: uint(owners[s.inode])},
+		}, s.stateName(), listening, true)
+	}
+
+	// Listening sockets become endpoint nodes in their own right, so that
+	// services without any current peers still show up.
+	if r.includeProcesses {
+		for _, s := range sockets {
+			if s.listening() {
+				r.addListener(rpt, ns, s, owners[s.inode])
+			}
+		}
+	}
+}
+
+// scope returns the ID to scope addr by. Loopback and wildcard addresses in
+// other namespaces aren't the host's, so are scoped by the namespace too.
+func (r *Reporter) scope(ns namespace, addr net.IP) string {
+	if ns.id != "" && (addr.IsLoopback() || addr.IsUnspecified()) {
+		return report.MakeNamespacedScope(r.hostID, ns.id)
+	}
+	return r.hostID
+}
+
+// connectionKey identifies a connection by its 4-tuple.
+type connectionKey struct {
+	localAddress, remoteAddress string
+	localPort, remotePort       uint16
+}
+
+func makeConnectionKey(localAddress net.IP, localPort uint16, remoteAddress net.IP, remotePort uint16) connectionKey {
+	return connectionKey{
+		localAddress:  localAddress.String(),
+		remoteAddress: remoteAddress.String(),
+		localPort:     localPort,
+		remotePort:    remotePort,
+	}
+}
+
+// addConnection adds c to the report. If haveListeners is false, we don't
+// know which ports are listening, so we can't tell the connection's
+// direction.
+func (r *Reporter) addConnection(rpt *report.Report, ns namespace, c *procspy.Connection, state string, listening listeners, haveListeners bool) {
 	var (
-		localAddressNodeID  = report.MakeAddressNodeID(r.hostID, c.LocalAddress.String())
-		remoteAddressNodeID = report.MakeAddressNodeID(r.hostID, c.RemoteAddress.String())
+		localScope          = r.scope(ns, c.LocalAddress)
+		remoteScope         = r.scope(ns, c.RemoteAddress)
+		localAddressNodeID  = report.MakeAddressNodeID(localScope, c.LocalAddress.String())
+		remoteAddressNodeID = report.MakeAddressNodeID(remoteScope, c.RemoteAddress.String())
 		adjecencyID         = report.MakeAdjacencyID(localAddressNodeID)
 		edgeID              = report.MakeEdgeID(localAddressNodeID, remoteAddressNodeID)
+		isServer            = listening.contains(c.LocalAddress, c.LocalPort)
+		direction           report.Direction
 	)
 
+	if haveListeners {
+		direction = report.Outbound
+		if isServer {
+			direction = report.Inbound
+		}
+	}
+
 	rpt.Address.Adjacency[adjecencyID] = rpt.Address.Adjacency[adjecencyID].Add(remoteAddressNodeID)
 
 	if _, ok := rpt.Address.NodeMetadatas[localAddressNodeID]; !ok {
-		rpt.Address.NodeMetadatas[localAddressNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		md := report.MakeNodeMetadataWith(map[string]string{
 			"name": r.hostName,
 			Addr:   c.LocalAddress.String(),
 		})
+		ns.annotate(md)
+		rpt.Address.NodeMetadatas[localAddressNodeID] = md
 	}
 
-	countTCPConnection(rpt.Address.EdgeMetadatas, edgeID)
+	countTCPConnection(rpt.Address.EdgeMetadatas, edgeID, state, direction)
 
 	if c.Proc.PID > 0 {
 		var (
-			localEndpointNodeID  = report.MakeEndpointNodeID(r.hostID, c.LocalAddress.String(), strconv.Itoa(int(c.LocalPort)))
-			remoteEndpointNodeID = report.MakeEndpointNodeID(r.hostID, c.RemoteAddress.String(), strconv.Itoa(int(c.RemotePort)))
+			localEndpointNodeID  = report.MakeEndpointNodeID(localScope, c.LocalAddress.String(), strconv.Itoa(int(c.LocalPort)))
+			remoteEndpointNodeID = report.MakeEndpointNodeID(remoteScope, c.RemoteAddress.String(), strconv.Itoa(int(c.RemotePort)))
 			adjecencyID          = report.MakeAdjacencyID(localEndpointNodeID)
 			edgeID               = report.MakeEdgeID(localEndpointNodeID, remoteEndpointNodeID)
 		)
@@ -111,19 +271,60 @@ func (r *Reporter) addConnection(rpt *report.Report, c *procspy.Connection) {
 				Port:        strconv.Itoa(int(c.LocalPort)),
 				process.PID: fmt.Sprint(c.Proc.PID),
 			})
+			if isServer {
+				md.Metadata[Listening] = c.Transport
+			}
+			ns.annotate(md)
 
 			rpt.Endpoint.NodeMetadatas[localEndpointNodeID] = md
 		}
 
-		countTCPConnection(rpt.Endpoint.EdgeMetadatas, edgeID)
+		countTCPConnection(rpt.Endpoint.EdgeMetadatas, edgeID, state, direction)
+	}
+}
+
+// addListener adds a listening socket to the endpoint topology, attributed
+// to pid if it's known.
+func (r *Reporter) addListener(rpt *report.Report, ns namespace, s socket, pid int) {
+	nodeID := report.MakeEndpointNodeID(r.scope(ns, s.localAddress), s.localAddress.String(), strconv.Itoa(int(s.localPort)))
+	md, ok := rpt.Endpoint.NodeMetadatas[nodeID]
+	if !ok {
+		md = report.MakeNodeMetadataWith(map[string]string{
+			Addr: s.localAddress.String(),
+			Port: strconv.Itoa(int(s.localPort)),
+		})
+		ns.annotate(md)
+	}
+	md.Metadata[Listening] = addTransport(md.Metadata[Listening], s.transport)
+	if _, ok := md.Metadata[process.PID]; !ok && pid > 0 {
+		md.Metadata[process.PID] = strconv.Itoa(pid)
+	}
+	rpt.Endpoint.NodeMetadatas[nodeID] = md
+}
+
+// addTransport adds transport to a comma-separated list of transports,
+// keeping it sorted and free of duplicates.
+func addTransport(transports, transport string) string {
+	result := []string{transport}
+	for _, t := range strings.Split(transports, ",") {
+		if t != "" && t != transport {
+			result = append(result, t)
+		}
 	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
 }
 
-func countTCPConnection(mds report.EdgeMetadatas, key string) {
+func countTCPConnection(mds report.EdgeMetadatas, key, state string, direction report.Direction) {
 	md := mds[key]
 	if md.MaxConnCountTCP == nil {
 		md.MaxConnCountTCP = new(uint64)
 	}
 	*md.MaxConnCountTCP++
+	if md.ConnCountByState == nil {
+		md.ConnCountByState = map[string]uint64{}
+	}
+	md.ConnCountByState[state]++
+	md.Direction |= direction
 	mds[key] = md
 }
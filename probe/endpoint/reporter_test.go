@@ -1,14 +1,20 @@
 package endpoint_test
 
 import (
+	"fmt"
 	"net"
+	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/weaveworks/procspy"
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/probe/endpoint"
 	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
 )
 
 var (
@@ -64,15 +70,63 @@ var (
 	}
 )
 
+type mockFile string
+
+func (f mockFile) Name() string       { return string(f) }
+func (f mockFile) Size() int64        { return 0 }
+func (f mockFile) Mode() os.FileMode  { return 0 }
+func (f mockFile) ModTime() time.Time { return time.Now() }
+func (f mockFile) IsDir() bool        { return false }
+func (f mockFile) Sys() interface{}   { return nil }
+
+// stubProc makes the reporter see a filesystem containing the given files
+// (keyed by filename), and symlinks such as file descriptors (keyed by
+// filename, valued by link target).
+func stubProc(files, links map[string]string) func() {
+	oldReadDir, oldReadFile, oldReadlink := endpoint.ReadDir, endpoint.ReadFile, endpoint.Readlink
+	endpoint.ReadDir = func(dirname string) ([]os.FileInfo, error) {
+		seen, result := map[string]struct{}{}, []os.FileInfo{}
+		for _, m := range []map[string]string{files, links} {
+			for filename := range m {
+				if !strings.HasPrefix(filename, dirname+"/") {
+					continue
+				}
+				name := strings.SplitN(filename[len(dirname)+1:], "/", 2)[0]
+				if _, ok := seen[name]; !ok {
+					seen[name] = struct{}{}
+					result = append(result, mockFile(name))
+				}
+			}
+		}
+		return result, nil
+	}
+	endpoint.ReadFile = func(filename string) ([]byte, error) {
+		if content, ok := files[filename]; ok {
+			return []byte(content), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	endpoint.Readlink = func(name string) (string, error) {
+		if target, ok := links[name]; ok {
+			return target, nil
+		}
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+	return func() {
+		endpoint.ReadDir, endpoint.ReadFile, endpoint.Readlink = oldReadDir, oldReadFile, oldReadlink
+	}
+}
+
 func TestSpyNoProcesses(t *testing.T) {
 	procspy.SetFixtures(fixConnections)
+	defer stubProc(nil, nil)()
 
 	const (
 		nodeID   = "heinz-tomato-ketchup" // TODO rename to hostID
 		nodeName = "frenchs-since-1904"   // TODO rename to hostNmae
 	)
 
-	reporter := endpoint.NewReporter(nodeID, nodeName, false)
+	reporter := endpoint.NewReporter(nodeID, nodeName, "/proc", false)
 	r, _ := reporter.Report()
 	//buf, _ := json.MarshalIndent(r, "", "    ")
 	//t.Logf("\n%s\n", buf)
@@ -103,13 +157,14 @@ func TestSpyNoProcesses(t *testing.T) {
 
 func TestSpyWithProcesses(t *testing.T) {
 	procspy.SetFixtures(fixConnectionsWithProcesses)
+	defer stubProc(nil, nil)()
 
 	const (
 		nodeID   = "nikon"             // TODO rename to hostID
 		nodeName = "fishermans-friend" // TODO rename to hostNmae
 	)
 
-	reporter := endpoint.NewReporter(nodeID, nodeName, false)
+	reporter := endpoint.NewReporter(nodeID, nodeName, "/proc", false)
 	r, _ := reporter.Report()
 	// buf, _ := json.MarshalIndent(r, "", "    ") ; t.Logf("\n%s\n", buf)
 
@@ -135,3 +190,270 @@ func TestSpyWithProcesses(t *testing.T) {
 		}
 	}
 }
+
+const procNetHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+// procNetLine formats a /proc/net/{tcp,udp} entry for an IPv4 socket.
+func procNetLine(localIP net.IP, localPort uint16, remoteIP net.IP, remotePort uint16, state, inode int) string {
+	hexAddr := func(ip net.IP, port uint16) string {
+		ip = ip.To4()
+		return fmt.Sprintf("%02X%02X%02X%02X:%04X", ip[3], ip[2], ip[1], ip[0], port)
+	}
+	return fmt.Sprintf("   0: %s %s %02X 00000000:00000000 00:00000000 00000000     0        0 %d 1 0000000000000000 100 0 0 10 0\n",
+		hexAddr(localIP, localPort), hexAddr(remoteIP, remotePort), state, inode)
+}
+
+func TestSpyConnectionState(t *testing.T) {
+	procspy.SetFixtures(fixConnectionsWithProcesses)
+
+	var (
+		anyIP           = net.ParseIP("0.0.0.0")
+		timeWaitAddress = net.ParseIP("192.168.1.3")
+	)
+	defer stubProc(map[string]string{
+		"/proc/net/tcp": procNetHeader +
+			procNetLine(anyIP, fixLocalPort, anyIP, 0, 0x0A, 1000) +
+			procNetLine(fixLocalAddress, fixLocalPort, fixRemoteAddress, fixRemotePort, 0x01, 1001) +
+			procNetLine(fixLocalAddress, 54321, timeWaitAddress, 8080, 0x06, 0),
+	}, nil)()
+
+	const (
+		hostID   = "jonathan"
+		hostName = "mcdonalds"
+	)
+
+	r, err := endpoint.NewReporter(hostID, hostName, "/proc", true).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		scopedLocal  = report.MakeEndpointNodeID(hostID, fixLocalAddress.String(), strconv.Itoa(int(fixLocalPort)))
+		scopedRemote = report.MakeEndpointNodeID(hostID, fixRemoteAddress.String(), strconv.Itoa(int(fixRemotePort)))
+	)
+
+	if want, have := "tcp", r.Endpoint.NodeMetadatas[scopedLocal].Metadata[endpoint.Listening]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Both fixture connections are from different processes on the same
+	// 4-tuple, so they collapse into a single edge.
+	if want, have := (report.EdgeMetadata{
+		MaxConnCountTCP:  newu64(2),
+		Direction:        report.Inbound,
+		ConnCountByState: map[string]uint64{"ESTABLISHED": 2},
+	}), r.Endpoint.EdgeMetadatas[report.MakeEdgeID(scopedLocal, scopedRemote)]; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// The TIME_WAIT socket has no process, so only shows up between
+	// addresses, as a client.
+	var (
+		localAddressID    = report.MakeAddressNodeID(hostID, fixLocalAddress.String())
+		timeWaitAddressID = report.MakeAddressNodeID(hostID, timeWaitAddress.String())
+	)
+	if want, have := (report.EdgeMetadata{
+		MaxConnCountTCP:  newu64(1),
+		Direction:        report.Outbound,
+		ConnCountByState: map[string]uint64{"TIME_WAIT": 1},
+	}), r.Address.EdgeMetadatas[report.MakeEdgeID(localAddressID, timeWaitAddressID)]; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestSpyListening(t *testing.T) {
+	procspy.SetFixtures(fixConnectionsWithProcesses)
+
+	anyIP := net.ParseIP("0.0.0.0")
+	defer stubProc(map[string]string{
+		"/proc/net/tcp": procNetHeader +
+			procNetLine(anyIP, 80, anyIP, 0, 0x0A, 1000) +
+			procNetLine(anyIP, 53, anyIP, 0, 0x0A, 1001),
+		"/proc/net/udp": procNetHeader +
+			procNetLine(anyIP, 53, anyIP, 0, 0x07, 1002) +
+			procNetLine(fixLocalAddress, 40000, fixRemoteAddress, 53, 0x01, 1003),
+	}, map[string]string{
+		"/proc/4242/fd/0": "/dev/null",
+		"/proc/4242/fd/3": "socket:[1000]",
+		"/proc/4243/fd/5": "socket:[1002]",
+	})()
+
+	const hostID = "jonathan"
+	r, err := endpoint.NewReporter(hostID, "mcdonalds", "/proc", true).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for nodeID, want := range map[string]map[string]string{
+		report.MakeEndpointNodeID(hostID, "0.0.0.0", "80"): {
+			endpoint.Addr:      "0.0.0.0",
+			endpoint.Port:      "80",
+			endpoint.Listening: "tcp",
+			"pid":              "4242",
+		},
+		report.MakeEndpointNodeID(hostID, "0.0.0.0", "53"): {
+			endpoint.Addr:      "0.0.0.0",
+			endpoint.Port:      "53",
+			endpoint.Listening: "tcp,udp",
+			"pid":              "4243",
+		},
+	} {
+		if have := r.Endpoint.NodeMetadatas[nodeID].Metadata; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: %s", nodeID, test.Diff(want, have))
+		}
+	}
+
+	// The connected UDP socket isn't listening.
+	if _, ok := r.Endpoint.NodeMetadatas[report.MakeEndpointNodeID(hostID, fixLocalAddress.String(), "40000")]; ok {
+		t.Errorf("didn't expect a node for a connected UDP socket")
+	}
+
+	// Once we know who owns the sockets, we don't look again, even for
+	// those without an owner we could find.
+	reporter := endpoint.NewReporter(hostID, "mcdonalds", "/proc", true)
+	if _, err := reporter.Report(); err != nil {
+		t.Fatal(err)
+	}
+	readlink, fdReads := endpoint.Readlink, 0
+	defer func() { endpoint.Readlink = readlink }()
+	endpoint.Readlink = func(name string) (string, error) {
+		if strings.Contains(name, "/fd/") {
+			fdReads++
+		}
+		return readlink(name)
+	}
+	if _, err := reporter.Report(); err != nil {
+		t.Fatal(err)
+	}
+	if fdReads != 0 {
+		t.Errorf("want no file descriptors read, have %d", fdReads)
+	}
+}
+
+func TestSpyNamespaces(t *testing.T) {
+	procspy.SetFixtures([]procspy.Connection{})
+
+	var (
+		anyIP       = net.ParseIP("0.0.0.0")
+		loopback    = net.ParseIP("127.0.0.1")
+		containerIP = net.ParseIP("172.17.0.2")
+		bridgeIP    = net.ParseIP("172.17.0.1")
+	)
+	defer stubProc(map[string]string{
+		"/proc/100/net/tcp": procNetHeader +
+			procNetLine(loopback, 8080, anyIP, 0, 0x0A, 2000) +
+			procNetLine(anyIP, 80, anyIP, 0, 0x0A, 2001) +
+			procNetLine(containerIP, 80, bridgeIP, 50000, 0x01, 2002),
+		"/proc/100/net/dev":                         procNetDev("lo", "eth0"),
+		"/proc/100/root/sys/class/net/lo/ifindex":   "1\n",
+		"/proc/100/root/sys/class/net/lo/iflink":    "1\n",
+		"/proc/100/root/sys/class/net/eth0/ifindex": "7\n",
+		"/proc/100/root/sys/class/net/eth0/iflink":  "8\n",
+
+		// Another container, whose eth0 has the same index, in its own
+		// namespace, as the first's.
+		"/proc/200/net/tcp": procNetHeader +
+			procNetLine(anyIP, 443, anyIP, 0, 0x0A, 3000),
+		"/proc/200/net/dev":                         procNetDev("lo", "eth0"),
+		"/proc/200/root/sys/class/net/eth0/ifindex": "7\n",
+		"/proc/200/root/sys/class/net/eth0/iflink":  "10\n",
+
+		"/sys/class/net/eth0/ifindex":     "2\n",
+		"/sys/class/net/eth0/iflink":      "2\n",
+		"/sys/class/net/veth1234/ifindex": "8\n",
+		"/sys/class/net/veth1234/iflink":  "7\n",
+		"/sys/class/net/veth5678/ifindex": "10\n",
+		"/sys/class/net/veth5678/iflink":  "7\n",
+	}, map[string]string{
+		"/proc/self/ns/net": "net:[4026531956]",
+		"/proc/1/ns/net":    "net:[4026531956]",
+		"/proc/100/ns/net":  "net:[4026532000]",
+		"/proc/101/ns/net":  "net:[4026532000]",
+		"/proc/200/ns/net":  "net:[4026532100]",
+		"/proc/100/fd/3":    "socket:[2000]",
+		"/proc/100/fd/4":    "socket:[2001]",
+		"/proc/101/fd/3":    "socket:[2002]",
+		"/proc/200/fd/3":    "socket:[3000]",
+	})()
+
+	const hostID = "jonathan"
+	r, err := endpoint.NewReporter(hostID, "mcdonalds", "/proc", true).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Loopback and wildcard addresses are private to the namespace.
+	for nodeID, want := range map[string]map[string]string{
+		report.MakeEndpointNodeID(report.MakeNamespacedScope(hostID, "4026532000"), "127.0.0.1", "8080"): {
+			endpoint.Addr:         "127.0.0.1",
+			endpoint.Port:         "8080",
+			endpoint.Listening:    "tcp",
+			endpoint.NetNamespace: "4026532000",
+			endpoint.Veth:         "veth1234",
+			"pid":                 "100",
+		},
+		report.MakeEndpointNodeID(report.MakeNamespacedScope(hostID, "4026532000"), "0.0.0.0", "80"): {
+			endpoint.Addr:         "0.0.0.0",
+			endpoint.Port:         "80",
+			endpoint.Listening:    "tcp",
+			endpoint.NetNamespace: "4026532000",
+			endpoint.Veth:         "veth1234",
+			"pid":                 "100",
+		},
+		report.MakeEndpointNodeID(hostID, containerIP.String(), "80"): {
+			endpoint.Addr:         containerIP.String(),
+			endpoint.Port:         "80",
+			endpoint.Listening:    "tcp",
+			endpoint.NetNamespace: "4026532000",
+			endpoint.Veth:         "veth1234",
+			"pid":                 "101",
+		},
+		report.MakeEndpointNodeID(report.MakeNamespacedScope(hostID, "4026532100"), "0.0.0.0", "443"): {
+			endpoint.Addr:         "0.0.0.0",
+			endpoint.Port:         "443",
+			endpoint.Listening:    "tcp",
+			endpoint.NetNamespace: "4026532100",
+			endpoint.Veth:         "veth5678",
+			"pid":                 "200",
+		},
+	} {
+		if have := r.Endpoint.NodeMetadatas[nodeID].Metadata; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: %s", nodeID, test.Diff(want, have))
+		}
+	}
+
+	if want, have := (report.EdgeMetadata{
+		MaxConnCountTCP:  newu64(1),
+		Direction:        report.Inbound,
+		ConnCountByState: map[string]uint64{"ESTABLISHED": 1},
+	}), r.Endpoint.EdgeMetadatas[report.MakeEdgeID(
+		report.MakeEndpointNodeID(hostID, containerIP.String(), "80"),
+		report.MakeEndpointNodeID(hostID, bridgeIP.String(), "50000"),
+	)]; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Without processes, we still see the namespaces' connections.
+	r, err = endpoint.NewReporter(hostID, "mcdonalds", "/proc", false).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeID := report.MakeAddressNodeID(hostID, containerIP.String())
+	if want, have := "4026532000", r.Address.NodeMetadatas[nodeID].Metadata[endpoint.NetNamespace]; want != have {
+		t.Errorf("%s: want namespace %q, have %q", nodeID, want, have)
+	}
+	if len(r.Endpoint.NodeMetadatas) != 0 {
+		t.Errorf("want no endpoints, have %v", r.Endpoint.NodeMetadatas)
+	}
+}
+
+func procNetDev(interfaces ...string) string {
+	dev := "Inter-|   Receive                                                |  Transmit\n" +
+		" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n"
+	for _, name := range interfaces {
+		dev += fmt.Sprintf("%6s:       0       0    0    0    0     0          0         0        0       0    0    0    0     0       0          0\n", name)
+	}
+	return dev
+}
+
+func newu64(value uint64) *uint64 { return &value }
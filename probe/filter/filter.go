@@ -0,0 +1,234 @@
+// Package filter removes what shouldn't leave the host from reports:
+// processes, containers and endpoints nobody wants to see, such as kernel
+// threads and monitoring agents, and secrets, such as passwords in command
+// lines.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// Redacted replaces the parts of metadata values which are redacted.
+const Redacted = "<redacted>"
+
+// Config is the rules of a Filter.
+type Config struct {
+	Exclude []Exclude `json:"exclude"`
+	Redact  []Redact  `json:"redact"`
+}
+
+// Exclude is a rule for which nodes to leave out of reports: those in the
+// topology (process, container or endpoint) with a value for the key that
+// matches the pattern. Leaving out a container leaves out its processes,
+// and leaving out a process leaves out its endpoints.
+type Exclude struct {
+	Topology string `json:"topology"`
+	Key      string `json:"key"`
+	Pattern  string `json:"pattern"`
+}
+
+// Redact is a rule for what to redact from the values of metadata with the
+// key, or of all metadata, if there's no key. If the pattern has submatches,
+// they're redacted; otherwise the whole match is.
+type Redact struct {
+	Key     string `json:"key"`
+	Pattern string `json:"pattern"`
+}
+
+// DefaultConfig redacts things that look like secrets from command lines.
+var DefaultConfig = Config{
+	Redact: []Redact{
+		{process.Cmdline, defaultSecretPattern},
+		{docker.ContainerCommand, defaultSecretPattern},
+	},
+}
+
+const defaultSecretPattern = `(?i)(?:password|passwd|secret|token|api[-_]?key)[=: ]+(\S+)`
+
+type exclude struct {
+	key string
+	re  *regexp.Regexp
+}
+
+type redact struct {
+	key string
+	re  *regexp.Regexp
+}
+
+// Filter is a Tagger which applies the rules of a Config. It should be the
+// last tagger, so it sees everything the others add.
+type Filter struct {
+	exclude map[string][]exclude
+	redact  []redact
+}
+
+// New returns a Filter for the Config, or an error if any of its rules are
+// invalid.
+func New(c Config) (*Filter, error) {
+	f := &Filter{exclude: map[string][]exclude{}}
+	for _, rule := range c.Exclude {
+		switch rule.Topology {
+		case "process", "container", "endpoint":
+		default:
+			return nil, fmt.Errorf("can't exclude from topology %q", rule.Topology)
+		}
+		if rule.Key == "" {
+			return nil, fmt.Errorf("exclude rule for %s has no key", rule.Topology)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude[rule.Topology] = append(f.exclude[rule.Topology], exclude{rule.Key, re})
+	}
+	for _, rule := range c.Redact {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.redact = append(f.redact, redact{rule.Key, re})
+	}
+	return f, nil
+}
+
+// Tag implements Tagger.
+func (f *Filter) Tag(r report.Report) (report.Report, error) {
+	var (
+		containers   = excluded(r.Container, f.exclude["container"], nil)
+		containerIDs = values(r.Container, containers, docker.ContainerID)
+		processes    = excluded(r.Process, f.exclude["process"], func(md report.NodeMetadata) bool {
+			_, ok := containerIDs[md.Metadata[docker.ContainerID]]
+			return ok
+		})
+		pids      = values(r.Process, processes, process.PID)
+		endpoints = excluded(r.Endpoint, f.exclude["endpoint"], func(md report.NodeMetadata) bool {
+			_, ok := pids[md.Metadata[process.PID]]
+			return ok
+		})
+	)
+
+	remove(r.Container, containers)
+	remove(r.Process, processes)
+	remove(r.Endpoint, endpoints)
+
+	if len(f.redact) > 0 {
+		for _, topology := range r.Topologies() {
+			for _, nmd := range topology.NodeMetadatas {
+				f.redactMetadata(nmd)
+			}
+		}
+	}
+	return r, nil
+}
+
+// excluded returns the IDs of the nodes in the topology which match any of
+// the rules, or the extra condition, if any.
+func excluded(t report.Topology, rules []exclude, also func(report.NodeMetadata) bool) map[string]struct{} {
+	ids := map[string]struct{}{}
+	for nodeID, nmd := range t.NodeMetadatas {
+		if also != nil && also(nmd) {
+			ids[nodeID] = struct{}{}
+			continue
+		}
+		for _, rule := range rules {
+			if val, ok := nmd.Metadata[rule.key]; ok && rule.re.MatchString(val) {
+				ids[nodeID] = struct{}{}
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// values returns the values for the key of the nodes in the topology with
+// the IDs. The probe only reports on its own host, so values such as PIDs
+// are unique.
+func values(t report.Topology, ids map[string]struct{}, key string) map[string]struct{} {
+	vals := map[string]struct{}{}
+	for nodeID := range ids {
+		if val, ok := t.NodeMetadatas[nodeID].Metadata[key]; ok && val != "" {
+			vals[val] = struct{}{}
+		}
+	}
+	return vals
+}
+
+// remove removes the nodes from the topology, along with their edges.
+func remove(t report.Topology, ids map[string]struct{}) {
+	if len(ids) == 0 {
+		return
+	}
+	for nodeID := range ids {
+		delete(t.NodeMetadatas, nodeID)
+		delete(t.Adjacency, report.MakeAdjacencyID(nodeID))
+	}
+	for adjacencyID, dstNodeIDs := range t.Adjacency {
+		kept := report.MakeIDList()
+		for _, dstNodeID := range dstNodeIDs {
+			if _, ok := ids[dstNodeID]; !ok {
+				kept = kept.Add(dstNodeID)
+			}
+		}
+		if len(kept) == 0 {
+			delete(t.Adjacency, adjacencyID)
+			continue
+		}
+		t.Adjacency[adjacencyID] = kept
+	}
+	for edgeID := range t.EdgeMetadatas {
+		srcNodeID, dstNodeID, ok := report.ParseEdgeID(edgeID)
+		if !ok {
+			continue
+		}
+		_, src := ids[srcNodeID]
+		_, dst := ids[dstNodeID]
+		if src || dst {
+			delete(t.EdgeMetadatas, edgeID)
+		}
+	}
+}
+
+func (f *Filter) redactMetadata(nmd report.NodeMetadata) {
+	for key, val := range nmd.Metadata {
+		for _, rule := range f.redact {
+			if rule.key == "" || rule.key == key {
+				val = redactString(rule.re, val)
+			}
+		}
+		nmd.Metadata[key] = val
+	}
+}
+
+// redactString replaces the submatches of re in s, or the whole matches if
+// there are no submatches, with Redacted.
+func redactString(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var (
+		result = []byte{}
+		last   = 0
+	)
+	for _, match := range matches {
+		spans := match[2:]
+		if len(spans) == 0 {
+			spans = match[:2]
+		}
+		for i := 0; i+1 < len(spans); i += 2 {
+			start, end := spans[i], spans[i+1]
+			if start < last || start < 0 {
+				continue // unmatched or overlapping submatch
+			}
+			result = append(result, s[last:start]...)
+			result = append(result, Redacted...)
+			last = end
+		}
+	}
+	return string(append(result, s[last:]...))
+}
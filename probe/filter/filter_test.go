@@ -0,0 +1,127 @@
+package filter_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/filter"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+func TestFilterExclude(t *testing.T) {
+	var (
+		agentContainerNodeID = report.MakeContainerNodeID("host", "agent")
+		appContainerNodeID   = report.MakeContainerNodeID("host", "app")
+		agentNodeID          = report.MakeProcessNodeID("host", "1")
+		appNodeID            = report.MakeProcessNodeID("host", "2")
+		kthreadNodeID        = report.MakeProcessNodeID("host", "3")
+		agentEndpointNodeID  = report.MakeEndpointNodeID("host", "10.0.0.1", "8125")
+		appEndpointNodeID    = report.MakeEndpointNodeID("host", "10.0.0.1", "80")
+		remoteNodeID         = report.MakeEndpointNodeID("", "10.0.0.2", "12345")
+	)
+
+	input := report.MakeReport()
+	input.Container.NodeMetadatas[agentContainerNodeID] = report.MakeNodeMetadataWith(map[string]string{docker.ContainerID: "agent", docker.ContainerName: "monitoring-agent"})
+	input.Container.NodeMetadatas[appContainerNodeID] = report.MakeNodeMetadataWith(map[string]string{docker.ContainerID: "app", docker.ContainerName: "app"})
+	input.Process.NodeMetadatas[agentNodeID] = report.MakeNodeMetadataWith(map[string]string{process.PID: "1", process.Comm: "agent", docker.ContainerID: "agent"})
+	input.Process.NodeMetadatas[appNodeID] = report.MakeNodeMetadataWith(map[string]string{process.PID: "2", process.Comm: "app", docker.ContainerID: "app"})
+	input.Process.NodeMetadatas[kthreadNodeID] = report.MakeNodeMetadataWith(map[string]string{process.PID: "3", process.Comm: "kworker/0:1"})
+	input.Endpoint.NodeMetadatas[agentEndpointNodeID] = report.MakeNodeMetadataWith(map[string]string{process.PID: "1"})
+	input.Endpoint.NodeMetadatas[appEndpointNodeID] = report.MakeNodeMetadataWith(map[string]string{process.PID: "2"})
+	input.Endpoint.NodeMetadatas[remoteNodeID] = report.MakeNodeMetadata()
+	input.Endpoint.Adjacency[report.MakeAdjacencyID(agentEndpointNodeID)] = report.MakeIDList(remoteNodeID)
+	input.Endpoint.Adjacency[report.MakeAdjacencyID(appEndpointNodeID)] = report.MakeIDList(remoteNodeID)
+	input.Endpoint.Adjacency[report.MakeAdjacencyID(remoteNodeID)] = report.MakeIDList(agentEndpointNodeID, appEndpointNodeID)
+	input.Endpoint.EdgeMetadatas[report.MakeEdgeID(agentEndpointNodeID, remoteNodeID)] = report.EdgeMetadata{}
+	input.Endpoint.EdgeMetadatas[report.MakeEdgeID(remoteNodeID, agentEndpointNodeID)] = report.EdgeMetadata{}
+	input.Endpoint.EdgeMetadatas[report.MakeEdgeID(appEndpointNodeID, remoteNodeID)] = report.EdgeMetadata{}
+
+	want := report.MakeReport()
+	want.Container.NodeMetadatas[appContainerNodeID] = input.Container.NodeMetadatas[appContainerNodeID].Copy()
+	want.Process.NodeMetadatas[appNodeID] = input.Process.NodeMetadatas[appNodeID].Copy()
+	want.Endpoint.NodeMetadatas[appEndpointNodeID] = input.Endpoint.NodeMetadatas[appEndpointNodeID].Copy()
+	want.Endpoint.NodeMetadatas[remoteNodeID] = report.MakeNodeMetadata()
+	want.Endpoint.Adjacency[report.MakeAdjacencyID(appEndpointNodeID)] = report.MakeIDList(remoteNodeID)
+	want.Endpoint.Adjacency[report.MakeAdjacencyID(remoteNodeID)] = report.MakeIDList(appEndpointNodeID)
+	want.Endpoint.EdgeMetadatas[report.MakeEdgeID(appEndpointNodeID, remoteNodeID)] = report.EdgeMetadata{}
+
+	f, err := filter.New(filter.Config{
+		Exclude: []filter.Exclude{
+			{Topology: "container", Key: docker.ContainerName, Pattern: "^monitoring-"},
+			{Topology: "process", Key: process.Comm, Pattern: `^kworker/`},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := f.Tag(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestFilterRedact(t *testing.T) {
+	f, err := filter.New(filter.DefaultConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeID := report.MakeProcessNodeID("host", "1")
+	input := report.MakeReport()
+	input.Process.NodeMetadatas[nodeID] = report.MakeNodeMetadataWith(map[string]string{
+		process.Comm:    "mysql password=hunter2",
+		process.Cmdline: "mysql -u root --password=hunter2 -h db --api-key abc123",
+	})
+	have, err := f.Tag(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		process.Comm:    "mysql password=hunter2", // only the command line
+		process.Cmdline: "mysql -u root --password=<redacted> -h db --api-key <redacted>",
+	} {
+		if have := have.Process.NodeMetadatas[nodeID].Metadata[key]; want != have {
+			t.Errorf("%s: want %q, have %q", key, want, have)
+		}
+	}
+
+	// Without submatches, the whole match goes, from every key.
+	f, err = filter.New(filter.Config{Redact: []filter.Redact{{Pattern: `\d+\.\d+\.\d+\.\d+`}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input = report.MakeReport()
+	input.Process.NodeMetadatas[nodeID] = report.MakeNodeMetadataWith(map[string]string{
+		process.Cmdline: "ping 10.0.0.1",
+		process.Comm:    "ping",
+	})
+	have, err = f.Tag(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := report.MakeNodeMetadataWith(map[string]string{
+		process.Cmdline: "ping <redacted>",
+		process.Comm:    "ping",
+	})
+	if have := have.Process.NodeMetadatas[nodeID]; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	for _, c := range []filter.Config{
+		{Exclude: []filter.Exclude{{Topology: "host", Key: "name", Pattern: "."}}},
+		{Exclude: []filter.Exclude{{Topology: "process", Pattern: "."}}},
+		{Exclude: []filter.Exclude{{Topology: "process", Key: process.Comm, Pattern: "("}}},
+		{Redact: []filter.Redact{{Pattern: "["}}},
+	} {
+		if _, err := filter.New(c); err == nil {
+			t.Errorf("%+v: expected an error", c)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package host
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// LabelPrefix is the prefix of the keys of host labels in NodeMetadata,
+// such as environment, zone, role and team.
+const LabelPrefix = "host_label_"
+
+var metadataClient = &http.Client{Timeout: 2 * time.Second}
+
+// Labels returns the host labels in a node's metadata.
+func Labels(md report.NodeMetadata) map[string]string {
+	result := map[string]string{}
+	for key, value := range md.Metadata {
+		if strings.HasPrefix(key, LabelPrefix) {
+			result[strings.TrimPrefix(key, LabelPrefix)] = value
+		}
+	}
+	return result
+}
+
+// ParseLabels parses labels given as key=value pairs, separated by commas
+// or newlines. Blank lines and lines starting with # are ignored.
+func ParseLabels(s string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("label %q isn't key=value", line)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if err := ValidateLabelKey(key); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// ValidateLabelKey returns an error if the key can't be used for a label.
+func ValidateLabelKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("empty label key")
+	}
+	if strings.ContainsAny(key, "=, \t\n") {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
+
+// LabelLoader keeps the labels of this host up to date. They come from a
+// metadata endpoint, which serves a JSON object of them, a file of them in
+// key=value lines, and static labels, each overriding the one before. The
+// endpoint and the file are read again every interval; if either can't be
+// read, the labels last read from it are kept.
+type LabelLoader struct {
+	static map[string]string
+	file   string
+	url    string
+	quit   chan struct{}
+
+	mtx      sync.RWMutex
+	fromFile map[string]string
+	fromURL  map[string]string
+}
+
+// NewLabelLoader returns a LabelLoader which has read the file and the
+// metadata endpoint, if any, once already.
+func NewLabelLoader(static map[string]string, file, url string, interval time.Duration) *LabelLoader {
+	l := &LabelLoader{
+		static: static,
+		file:   file,
+		url:    url,
+		quit:   make(chan struct{}),
+	}
+	l.load()
+	if file != "" || url != "" {
+		go l.loop(interval)
+	}
+	return l
+}
+
+// Labels returns the labels of this host.
+func (l *LabelLoader) Labels() map[string]string {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	labels := map[string]string{}
+	for _, source := range []map[string]string{l.fromURL, l.fromFile, l.static} {
+		for key, value := range source {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// Stop stops reading the file and the metadata endpoint.
+func (l *LabelLoader) Stop() {
+	close(l.quit)
+}
+
+func (l *LabelLoader) loop(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			l.load()
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+func (l *LabelLoader) load() {
+	if l.file != "" {
+		if labels, err := readLabelsFile(l.file); err != nil {
+			log.Printf("host labels: %v", err)
+		} else {
+			l.mtx.Lock()
+			l.fromFile = labels
+			l.mtx.Unlock()
+		}
+	}
+	if l.url != "" {
+		if labels, err := fetchLabels(l.url); err != nil {
+			log.Printf("host labels: %v", err)
+		} else {
+			l.mtx.Lock()
+			l.fromURL = labels
+			l.mtx.Unlock()
+		}
+	}
+}
+
+func readLabelsFile(path string) (map[string]string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := ParseLabels(string(buf))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return labels, nil
+}
+
+func fetchLabels(url string) (map[string]string, error) {
+	resp, err := metadataClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	var labels map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&labels); err != nil {
+		return nil, fmt.Errorf("%s: %v", url, err)
+	}
+	for key := range labels {
+		if err := ValidateLabelKey(key); err != nil {
+			return nil, fmt.Errorf("%s: %v", url, err)
+		}
+	}
+	return labels, nil
+}
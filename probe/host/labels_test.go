@@ -0,0 +1,79 @@
+package host_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/test"
+)
+
+func TestParseLabels(t *testing.T) {
+	have, err := host.ParseLabels("env=prod, team = storage\n# a comment\n\nrole=db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"env": "prod", "team": "storage", "role": "db"}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	for _, invalid := range []string{"env", "=prod", "my env=prod"} {
+		if _, err := host.ParseLabels(invalid); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestLabelLoader(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		metadata = `{"zone": "eu-west-1a", "env": "staging"}`
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		w.Write([]byte(metadata))
+	}))
+	defer server.Close()
+
+	file, err := ioutil.TempFile("", "host-labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if err := ioutil.WriteFile(file.Name(), []byte("env=prod\nrole=web\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := host.NewLabelLoader(map[string]string{"team": "storage", "role": "db"}, file.Name(), server.URL, 10*time.Millisecond)
+	defer loader.Stop()
+
+	// The file overrides the endpoint, and the static labels override both.
+	want := map[string]string{"zone": "eu-west-1a", "env": "prod", "role": "db", "team": "storage"}
+	if have := loader.Labels(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Changes to the endpoint are picked up, and if it breaks, the labels
+	// last read from it are kept.
+	mtx.Lock()
+	metadata = `{"zone": "eu-west-1b"}`
+	mtx.Unlock()
+	want = map[string]string{"zone": "eu-west-1b", "env": "prod", "role": "db", "team": "storage"}
+	test.Poll(t, 100*time.Millisecond, want, func() interface{} { return loader.Labels() })
+
+	mtx.Lock()
+	metadata = `not json`
+	mtx.Unlock()
+	time.Sleep(30 * time.Millisecond)
+	if have := loader.Labels(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
@@ -0,0 +1,194 @@
+package host
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CPUTimes are the cumulative times (in clock ticks) all CPUs have spent
+// busy, and in total, since boot.
+type CPUTimes struct {
+	Busy, Total uint64
+}
+
+// MemoryInfo describes the memory of the host, in bytes.
+type MemoryInfo struct {
+	Total, Available uint64
+}
+
+// DiskCounters are the cumulative bytes read from and written to the host's
+// disks since boot.
+type DiskCounters struct {
+	ReadBytes, WriteBytes uint64
+}
+
+// InterfaceCounters are the cumulative bytes received and transmitted by
+// each network interface, keyed by name.
+type InterfaceCounters map[string]struct {
+	RxBytes, TxBytes uint64
+}
+
+// sectorSize is the unit of /proc/diskstats, regardless of the device.
+const sectorSize = 512
+
+// parseCPUTimes parses the aggregate "cpu" line of /proc/stat.
+//
+//   cpu  user nice system idle iowait irq softirq steal guest guest_nice
+func parseCPUTimes(buf []byte) (CPUTimes, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+
+		// Guest time is already accounted for in user time.
+		var times CPUTimes
+		for i, field := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			ticks, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return CPUTimes{}, err
+			}
+			times.Total += ticks
+			if i != 3 && i != 4 { // idle, iowait
+				times.Busy += ticks
+			}
+		}
+		return times, nil
+	}
+	return CPUTimes{}, fmt.Errorf("no cpu line in stat")
+}
+
+// parseMemoryInfo parses /proc/meminfo. Kernels before 3.14 don't have
+// MemAvailable, so we estimate it from the free memory and caches.
+func parseMemoryInfo(buf []byte) (MemoryInfo, error) {
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return MemoryInfo{}, err
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = value * 1024 // kB
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return MemoryInfo{}, fmt.Errorf("no MemTotal in meminfo")
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return MemoryInfo{Total: total, Available: available}, nil
+}
+
+// parseDiskCounters parses /proc/diskstats, adding up the whole disks.
+// Partitions and device-mapper devices are skipped, as they'd be counted
+// twice, as are loop and RAM devices.
+//
+//   8       0 sda 1234 0 5678 ...
+func parseDiskCounters(buf []byte) (DiskCounters, error) {
+	type device struct {
+		name                        string
+		sectorsRead, sectorsWritten uint64
+	}
+	devices := []device{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if strings.HasPrefix(fields[2], "loop") ||
+			strings.HasPrefix(fields[2], "ram") ||
+			strings.HasPrefix(fields[2], "dm-") {
+			continue
+		}
+		sectorsRead, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return DiskCounters{}, err
+		}
+		sectorsWritten, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return DiskCounters{}, err
+		}
+		devices = append(devices, device{fields[2], sectorsRead, sectorsWritten})
+	}
+
+	var result DiskCounters
+	for _, d := range devices {
+		partition := false
+		for _, other := range devices {
+			if isPartition(d.name, other.name) {
+				partition = true
+				break
+			}
+		}
+		if !partition {
+			result.ReadBytes += d.sectorsRead * sectorSize
+			result.WriteBytes += d.sectorsWritten * sectorSize
+		}
+	}
+	return result, nil
+}
+
+// isPartition is true if name is a partition of disk, e.g. sda1 of sda, or
+// nvme0n1p1 of nvme0n1.
+func isPartition(name, disk string) bool {
+	if len(name) <= len(disk) || !strings.HasPrefix(name, disk) {
+		return false
+	}
+	suffix := name[len(disk):]
+	if last := disk[len(disk)-1]; last >= '0' && last <= '9' {
+		if suffix[0] != 'p' {
+			return false
+		}
+		suffix = suffix[1:]
+	}
+	_, err := strconv.Atoi(suffix)
+	return err == nil
+}
+
+// parseInterfaceCounters parses /proc/net/dev. The loopback interface is
+// skipped.
+//
+//   Inter-|   Receive                            |  Transmit
+//    face |bytes    packets errs drop fifo frame compressed multicast|bytes ...
+//     eth0: 1234 ...
+func parseInterfaceCounters(buf []byte) (InterfaceCounters, error) {
+	result := InterfaceCounters{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue // header
+		}
+		name, fields := strings.TrimSpace(parts[0]), strings.Fields(parts[1])
+		if name == "lo" || len(fields) < 9 {
+			continue
+		}
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		counters := result[name]
+		counters.RxBytes, counters.TxBytes = rx, tx
+		result[name] = counters
+	}
+	return result, nil
+}
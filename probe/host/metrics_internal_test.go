@@ -0,0 +1,64 @@
+package host
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUTimes(t *testing.T) {
+	have, err := parseCPUTimes([]byte("cpu  100 10 50 800 40 0 5 0 20 0\ncpu0 50 5 25 400 20 0 2 0 10 0\nintr 1234\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CPUTimes{Busy: 165, Total: 1005}); want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestParseMemoryInfo(t *testing.T) {
+	for input, want := range map[string]MemoryInfo{
+		"MemTotal:        2048 kB\nMemFree:          512 kB\nMemAvailable:    1024 kB\n": {Total: 2048 * 1024, Available: 1024 * 1024},
+		"MemTotal:        2048 kB\nMemFree:          512 kB\nBuffers:          128 kB\nCached:           256 kB\n": {Total: 2048 * 1024, Available: 896 * 1024},
+	} {
+		have, err := parseMemoryInfo([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != have {
+			t.Errorf("want %+v, have %+v", want, have)
+		}
+	}
+}
+
+func TestParseDiskCounters(t *testing.T) {
+	const diskstats = `   8       0 sda 100 0 1000 0 50 0 2000 0 0 0 0
+   8       1 sda1 90 0 900 0 40 0 1800 0 0 0 0
+ 259       0 nvme0n1 10 0 100 0 5 0 200 0 0 0 0
+ 259       1 nvme0n1p1 10 0 100 0 5 0 200 0 0 0 0
+   7       0 loop0 10 0 100 0 0 0 0 0 0 0 0
+ 253       0 dm-0 90 0 900 0 40 0 1800 0 0 0 0
+`
+	have, err := parseDiskCounters([]byte(diskstats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (DiskCounters{ReadBytes: 1100 * sectorSize, WriteBytes: 2200 * sectorSize}); want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestParseInterfaceCounters(t *testing.T) {
+	const netdev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
+  eth0: 2000 20 0 0 0 0 0 0 3000 30 0 0 0 0 0 0
+`
+	have, err := parseInterfaceCounters([]byte(netdev))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InterfaceCounters{"eth0": {RxBytes: 2000, TxBytes: 3000}}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
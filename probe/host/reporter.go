@@ -2,6 +2,8 @@ package host
 
 import (
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +19,15 @@ const (
 	Load          = "load"
 	KernelVersion = "kernel_version"
 	Uptime        = "uptime"
+
+	CPUUsage        = "cpu_usage_percent"
+	MemoryTotal     = "memory_total_bytes"
+	MemoryUsed      = "memory_used_bytes"
+	MemoryAvailable = "memory_available_bytes"
+	DiskReadRate    = "disk_read_bytes_per_second"
+	DiskWriteRate   = "disk_write_bytes_per_second"
+	RxRatePrefix    = "rx_bytes_per_second_" // followed by the interface name
+	TxRatePrefix    = "tx_bytes_per_second_" // followed by the interface name
 )
 
 // Exposed for testing.
@@ -27,7 +38,8 @@ const (
 
 // Exposed for testing.
 var (
-	Now = func() string { return time.Now().UTC().Format(time.RFC3339Nano) }
+	Now   = func() string { return time.Now().UTC().Format(time.RFC3339Nano) }
+	Clock = time.Now
 )
 
 // Reporter generates Reports containing the host topology.
@@ -35,6 +47,16 @@ type Reporter struct {
 	hostID    string
 	hostName  string
 	localNets report.Networks
+	previous  sample // to turn counters into rates
+}
+
+// sample is a reading of the host's counters. Counters which couldn't be
+// read are nil.
+type sample struct {
+	time       time.Time
+	cpu        *CPUTimes
+	disk       *DiskCounters
+	interfaces InterfaceCounters
 }
 
 // NewReporter returns a Reporter which produces a report containing host
@@ -68,7 +90,7 @@ func (r *Reporter) Report() (report.Report, error) {
 		return rep, err
 	}
 
-	rep.Host.NodeMetadatas[report.MakeHostNodeID(r.hostID)] = report.MakeNodeMetadataWith(map[string]string{
+	md := report.MakeNodeMetadataWith(map[string]string{
 		Timestamp:     Now(),
 		HostName:      r.hostName,
 		LocalNetworks: strings.Join(localCIDRs, " "),
@@ -77,6 +99,74 @@ func (r *Reporter) Report() (report.Report, error) {
 		KernelVersion: kernel,
 		Uptime:        uptime.String(),
 	})
+	r.addMetrics(md)
+	rep.Host.NodeMetadatas[report.MakeHostNodeID(r.hostID)] = md
 
 	return rep, nil
 }
+
+// addMetrics adds the host's resource usage. Rates are over the time since
+// the previous report, so are missing from the first.
+func (r *Reporter) addMetrics(md report.NodeMetadata) {
+	current := sample{time: Clock()}
+	if memory, err := GetMemoryInfo(); err == nil {
+		md.Metadata[MemoryTotal] = strconv.FormatUint(memory.Total, 10)
+		md.Metadata[MemoryAvailable] = strconv.FormatUint(memory.Available, 10)
+		if memory.Available <= memory.Total {
+			md.Metadata[MemoryUsed] = strconv.FormatUint(memory.Total-memory.Available, 10)
+		}
+	}
+
+	if cpu, err := GetCPUTimes(); err == nil {
+		current.cpu = &cpu
+	}
+	if disk, err := GetDiskCounters(); err == nil {
+		current.disk = &disk
+	}
+	if interfaces, err := GetInterfaceCounters(); err == nil {
+		current.interfaces = interfaces
+	}
+	previous := r.previous
+	r.previous = current
+
+	seconds := current.time.Sub(previous.time).Seconds()
+	if previous.time.IsZero() || seconds <= 0 {
+		return
+	}
+	if current.cpu != nil && previous.cpu != nil &&
+		current.cpu.Total > previous.cpu.Total && current.cpu.Busy >= previous.cpu.Busy {
+		busy := float64(current.cpu.Busy - previous.cpu.Busy)
+		total := float64(current.cpu.Total - previous.cpu.Total)
+		md.Metadata[CPUUsage] = strconv.FormatFloat(100*busy/total, 'f', 2, 64)
+	}
+	if current.disk != nil && previous.disk != nil {
+		addRate(md, DiskReadRate, previous.disk.ReadBytes, current.disk.ReadBytes, seconds)
+		addRate(md, DiskWriteRate, previous.disk.WriteBytes, current.disk.WriteBytes, seconds)
+	}
+	for name, counters := range current.interfaces {
+		if prev, ok := previous.interfaces[name]; ok {
+			addRate(md, RxRatePrefix+name, prev.RxBytes, counters.RxBytes, seconds)
+			addRate(md, TxRatePrefix+name, prev.TxBytes, counters.TxBytes, seconds)
+		}
+	}
+}
+
+// Interfaces returns the names of the interfaces the host's metadata has
+// rates for, sorted.
+func Interfaces(md report.NodeMetadata) []string {
+	result := []string{}
+	for key := range md.Metadata {
+		if strings.HasPrefix(key, RxRatePrefix) {
+			result = append(result, strings.TrimPrefix(key, RxRatePrefix))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// addRate adds the rate of change of a counter, unless it has been reset.
+func addRate(md report.NodeMetadata, key string, previous, current uint64, seconds float64) {
+	if current >= previous {
+		md.Metadata[key] = strconv.FormatFloat(float64(current-previous)/seconds, 'f', 2, 64)
+	}
+}
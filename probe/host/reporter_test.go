@@ -1,6 +1,7 @@
 package host_test
 
 import (
+	"fmt"
 	"net"
 	"reflect"
 	"runtime"
@@ -43,6 +44,7 @@ func TestReporter(t *testing.T) {
 	host.GetLoad = func() string { return load }
 	host.GetUptime = func() (time.Duration, error) { return time.ParseDuration(uptime) }
 	host.Now = func() string { return now }
+	defer stubMetrics(false)()
 
 	want := report.MakeReport()
 	want.Host.NodeMetadatas[report.MakeHostNodeID(hostID)] = report.MakeNodeMetadataWith(map[string]string{
@@ -59,3 +61,118 @@ func TestReporter(t *testing.T) {
 		t.Errorf("%s", test.Diff(want, have))
 	}
 }
+
+// stubMetrics makes the metrics hooks fail, or return the given samples in
+// turn, ten seconds apart.
+func stubMetrics(ok bool, samples ...metricsSample) func() {
+	var (
+		oldGetCPUTimes          = host.GetCPUTimes
+		oldGetMemoryInfo        = host.GetMemoryInfo
+		oldGetDiskCounters      = host.GetDiskCounters
+		oldGetInterfaceCounters = host.GetInterfaceCounters
+		oldClock                = host.Clock
+		current                 = -1
+		errUnavailable          = fmt.Errorf("unavailable")
+	)
+	host.Clock = func() time.Time {
+		current++
+		return time.Unix(1440000000, 0).Add(time.Duration(current) * 10 * time.Second)
+	}
+	host.GetCPUTimes = func() (host.CPUTimes, error) {
+		if !ok {
+			return host.CPUTimes{}, errUnavailable
+		}
+		return samples[current].cpu, nil
+	}
+	host.GetMemoryInfo = func() (host.MemoryInfo, error) {
+		if !ok {
+			return host.MemoryInfo{}, errUnavailable
+		}
+		return samples[current].memory, nil
+	}
+	host.GetDiskCounters = func() (host.DiskCounters, error) {
+		if !ok {
+			return host.DiskCounters{}, errUnavailable
+		}
+		return samples[current].disk, nil
+	}
+	host.GetInterfaceCounters = func() (host.InterfaceCounters, error) {
+		if !ok {
+			return nil, errUnavailable
+		}
+		return samples[current].interfaces, nil
+	}
+	return func() {
+		host.GetCPUTimes = oldGetCPUTimes
+		host.GetMemoryInfo = oldGetMemoryInfo
+		host.GetDiskCounters = oldGetDiskCounters
+		host.GetInterfaceCounters = oldGetInterfaceCounters
+		host.Clock = oldClock
+	}
+}
+
+type metricsSample struct {
+	cpu        host.CPUTimes
+	memory     host.MemoryInfo
+	disk       host.DiskCounters
+	interfaces host.InterfaceCounters
+}
+
+func TestReporterMetrics(t *testing.T) {
+	defer stubMetrics(true, metricsSample{
+		cpu:        host.CPUTimes{Busy: 100, Total: 1000},
+		memory:     host.MemoryInfo{Total: 4096, Available: 1024},
+		disk:       host.DiskCounters{ReadBytes: 1000, WriteBytes: 2000},
+		interfaces: host.InterfaceCounters{"eth0": {RxBytes: 100, TxBytes: 200}},
+	}, metricsSample{
+		cpu:        host.CPUTimes{Busy: 350, Total: 2000},
+		memory:     host.MemoryInfo{Total: 4096, Available: 2048},
+		disk:       host.DiskCounters{ReadBytes: 11000, WriteBytes: 2000},
+		interfaces: host.InterfaceCounters{"eth0": {RxBytes: 1100, TxBytes: 400}, "eth1": {RxBytes: 5, TxBytes: 5}},
+	})()
+
+	var (
+		hostID   = "hostid"
+		nodeID   = report.MakeHostNodeID(hostID)
+		reporter = host.NewReporter(hostID, "hostname", report.Networks{})
+		keys     = []string{
+			host.CPUUsage, host.MemoryTotal, host.MemoryUsed, host.MemoryAvailable,
+			host.DiskReadRate, host.DiskWriteRate,
+			host.RxRatePrefix + "eth0", host.TxRatePrefix + "eth0",
+			host.RxRatePrefix + "eth1", host.TxRatePrefix + "eth1",
+		}
+		metrics = func(rpt report.Report) map[string]string {
+			result := map[string]string{}
+			for _, key := range keys {
+				if value, ok := rpt.Host.NodeMetadatas[nodeID].Metadata[key]; ok {
+					result[key] = value
+				}
+			}
+			return result
+		}
+	)
+
+	// The first report has nothing to compute rates against.
+	first, _ := reporter.Report()
+	if want, have := map[string]string{
+		host.MemoryTotal:     "4096",
+		host.MemoryUsed:      "3072",
+		host.MemoryAvailable: "1024",
+	}, metrics(first); !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+
+	second, _ := reporter.Report()
+	if want, have := map[string]string{
+		host.CPUUsage:              "25.00",
+		host.MemoryTotal:           "4096",
+		host.MemoryUsed:            "2048",
+		host.MemoryAvailable:       "2048",
+		host.DiskReadRate:          "1000.00",
+		host.DiskWriteRate:         "0.00",
+		host.RxRatePrefix + "eth0": "100.00",
+		host.TxRatePrefix + "eth0": "20.00",
+	}, metrics(second); !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
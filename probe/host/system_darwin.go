@@ -1,6 +1,7 @@
 package host
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
@@ -9,6 +10,8 @@ import (
 )
 
 var (
+	errNotSupported = errors.New("not supported on darwin")
+
 	unameRe  = regexp.MustCompile(`Darwin Kernel Version ([0-9\.]+)\:`)
 	loadRe   = regexp.MustCompile(`load averages: ([0-9\.]+) ([0-9\.]+) ([0-9\.]+)`)
 	uptimeRe = regexp.MustCompile(`up ([0-9]+) day[s]*,[ ]+([0-9]+)\:([0-9][0-9])`)
@@ -64,3 +67,23 @@ var GetUptime = func() (time.Duration, error) {
 	}
 	return (time.Duration(d) * 24 * time.Hour) + (time.Duration(h) * time.Hour) + (time.Duration(m) * time.Minute), nil
 }
+
+// GetCPUTimes isn't supported on darwin.
+var GetCPUTimes = func() (CPUTimes, error) {
+	return CPUTimes{}, errNotSupported
+}
+
+// GetMemoryInfo isn't supported on darwin.
+var GetMemoryInfo = func() (MemoryInfo, error) {
+	return MemoryInfo{}, errNotSupported
+}
+
+// GetDiskCounters isn't supported on darwin.
+var GetDiskCounters = func() (DiskCounters, error) {
+	return DiskCounters{}, errNotSupported
+}
+
+// GetInterfaceCounters isn't supported on darwin.
+var GetInterfaceCounters = func() (InterfaceCounters, error) {
+	return nil, errNotSupported
+}
@@ -77,3 +77,40 @@ var GetUptime = func() (time.Duration, error) {
 
 	return time.Duration(uptime) * time.Second, nil
 }
+
+// GetCPUTimes returns the cumulative CPU times of the host.
+var GetCPUTimes = func() (CPUTimes, error) {
+	buf, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return CPUTimes{}, err
+	}
+	return parseCPUTimes(buf)
+}
+
+// GetMemoryInfo returns the total and available memory of the host.
+var GetMemoryInfo = func() (MemoryInfo, error) {
+	buf, err := ioutil.ReadFile("/proc/meminfo")
+	if err != nil {
+		return MemoryInfo{}, err
+	}
+	return parseMemoryInfo(buf)
+}
+
+// GetDiskCounters returns the cumulative disk IO of the host.
+var GetDiskCounters = func() (DiskCounters, error) {
+	buf, err := ioutil.ReadFile("/proc/diskstats")
+	if err != nil {
+		return DiskCounters{}, err
+	}
+	return parseDiskCounters(buf)
+}
+
+// GetInterfaceCounters returns the cumulative traffic of each of the host's
+// network interfaces.
+var GetInterfaceCounters = func() (InterfaceCounters, error) {
+	buf, err := ioutil.ReadFile("/proc/net/dev")
+	if err != nil {
+		return nil, err
+	}
+	return parseInterfaceCounters(buf)
+}
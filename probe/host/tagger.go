@@ -6,19 +6,38 @@ import (
 
 // Tagger tags each node in each topology of a report with the origin host
 // node ID of this (probe) host. Effectively, a foreign key linking every node
-// in every topology to an origin host node in the host topology.
-type Tagger struct{ hostNodeID string }
+// in every topology to an origin host node in the host topology. Host IDs
+// aren't for people, so nodes are tagged with the hostname too, for labels.
+// Nodes are tagged with the host's labels as well, so views can filter and
+// group by them. Nodes of the cluster-wide topologies, such as pods, aren't
+// on this host in particular, so aren't tagged.
+type Tagger struct {
+	hostNodeID, hostName string
+	labels               func() map[string]string
+}
 
 // NewTagger tags each node with a foreign key linking it to its origin host
-// in the host topology.
-func NewTagger(hostID string) Tagger {
-	return Tagger{hostNodeID: report.MakeHostNodeID(hostID)}
+// in the host topology, and with the labels, if any.
+func NewTagger(hostID, hostName string, labels func() map[string]string) Tagger {
+	return Tagger{
+		hostNodeID: report.MakeHostNodeID(hostID),
+		hostName:   hostName,
+		labels:     labels,
+	}
 }
 
 // Tag implements Tagger.
 func (t Tagger) Tag(r report.Report) (report.Report, error) {
-	md := report.MakeNodeMetadataWith(map[string]string{report.HostNodeID: t.hostNodeID})
-	for _, topology := range r.Topologies() {
+	md := report.MakeNodeMetadataWith(map[string]string{
+		report.HostNodeID: t.hostNodeID,
+		HostName:          t.hostName,
+	})
+	if t.labels != nil {
+		for key, value := range t.labels() {
+			md.Metadata[LabelPrefix+key] = value
+		}
+	}
+	for _, topology := range r.HostTopologies() {
 		for nodeID := range topology.NodeMetadatas {
 			topology.NodeMetadatas[nodeID].Merge(md)
 		}
@@ -12,6 +12,7 @@ import (
 func TestTagger(t *testing.T) {
 	var (
 		hostID         = "foo"
+		hostName       = "foo.example.com"
 		endpointNodeID = report.MakeEndpointNodeID(hostID, "1.2.3.4", "56789") // hostID ignored
 		nodeMetadata   = report.MakeNodeMetadataWith(map[string]string{"foo": "bar"})
 	)
@@ -20,10 +21,55 @@ func TestTagger(t *testing.T) {
 	r.Endpoint.NodeMetadatas[endpointNodeID] = nodeMetadata
 	want := nodeMetadata.Merge(report.MakeNodeMetadataWith(map[string]string{
 		report.HostNodeID: report.MakeHostNodeID(hostID),
+		host.HostName:     hostName,
 	}))
-	rpt, _ := host.NewTagger(hostID).Tag(r)
+	rpt, _ := host.NewTagger(hostID, hostName, nil).Tag(r)
 	have := rpt.Endpoint.NodeMetadatas[endpointNodeID].Copy()
 	if !reflect.DeepEqual(want, have) {
 		t.Error(test.Diff(want, have))
 	}
 }
+
+func TestTaggerLabels(t *testing.T) {
+	var (
+		hostID        = "foo"
+		processNodeID = report.MakeProcessNodeID(hostID, "1")
+		hostNodeID    = report.MakeHostNodeID(hostID)
+		labels        = func() map[string]string { return map[string]string{"env": "prod", "zone": "eu-west-1a"} }
+	)
+
+	r := report.MakeReport()
+	r.Process.NodeMetadatas[processNodeID] = report.MakeNodeMetadata()
+	r.Host.NodeMetadatas[hostNodeID] = report.MakeNodeMetadata()
+	rpt, _ := host.NewTagger(hostID, "foo.example.com", labels).Tag(r)
+	for _, md := range []report.NodeMetadata{
+		rpt.Process.NodeMetadatas[processNodeID],
+		rpt.Host.NodeMetadatas[hostNodeID],
+	} {
+		if want, have := labels(), host.Labels(md); !reflect.DeepEqual(want, have) {
+			t.Error(test.Diff(want, have))
+		}
+	}
+}
+
+func TestTaggerClusterTopologies(t *testing.T) {
+	var (
+		podNodeID = report.MakePodNodeID("default", "web")
+		pod       = map[string]string{"kubernetes_pod_node_name": "node1"}
+	)
+
+	// Every probe reports the same pods, and whichever merges last mustn't
+	// decide their host.
+	merged := report.MakeReport()
+	for _, hostID := range []string{"node1", "node2"} {
+		r := report.MakeReport()
+		r.Pod.NodeMetadatas[podNodeID] = report.MakeNodeMetadataWith(pod)
+		labels := func() map[string]string { return map[string]string{"zone": hostID} }
+		r, _ = host.NewTagger(hostID, hostID, labels).Tag(r)
+		merged.Merge(r)
+	}
+	want := report.MakeNodeMetadataWith(pod)
+	if have := merged.Pod.NodeMetadatas[podNodeID]; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
@@ -1,6 +1,21 @@
 package main
 
-import "os"
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/weaveworks/scope/report"
+)
+
+var (
+	machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+	randRead       = rand.Read
+)
 
 func hostname() string {
 	if hostname := os.Getenv("SCOPE_HOSTNAME"); hostname != "" {
@@ -12,3 +27,68 @@ func hostname() string {
 	}
 	return hostname
 }
+
+// loadHostID returns the ID which identifies this host in reports. Unlike the
+// hostname, it doesn't change when the host is renamed, and can't have
+// delimiters in it. Unless overridden, it's the machine ID, or failing that
+// a random ID, which is saved in idFile so we get the same one next time.
+//
+// When the probe runs in a container, its own /etc/machine-id is the
+// container's, so we look under the root of PID 1 in procRoot first; with
+// --pid=host that's the host's root.
+func loadHostID(override, procRoot, idFile string) (string, error) {
+	if override != "" {
+		if err := report.ValidateIDComponent(override); err != nil {
+			return "", err
+		}
+		return override, nil
+	}
+
+	for _, root := range []string{filepath.Join(procRoot, "1", "root"), "/"} {
+		for _, path := range machineIDPaths {
+			if id, ok := readHostID(filepath.Join(root, path)); ok {
+				return id, nil
+			}
+		}
+	}
+
+	if idFile == "" {
+		return "", fmt.Errorf("no machine ID, and no host ID file")
+	}
+	if id, ok := readHostID(idFile); ok {
+		return id, nil
+	}
+	id, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(idFile), 0755); err != nil {
+		log.Printf("warning: host ID won't persist: %v", err)
+	} else if err := ioutil.WriteFile(idFile, []byte(id+"\n"), 0644); err != nil {
+		log.Printf("warning: host ID won't persist: %v", err)
+	}
+	return id, nil
+}
+
+func readHostID(path string) (string, bool) {
+	buf, err := readFile(path)
+	if err != nil {
+		return "", false
+	}
+	id := strings.TrimSpace(string(buf))
+	if id == "" || report.ValidateIDComponent(id) != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := randRead(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestLoadHostID(t *testing.T) {
+	oldReadFile, oldMachineIDPaths := readFile, machineIDPaths
+	defer func() { readFile, machineIDPaths = oldReadFile, oldMachineIDPaths }()
+
+	dir, err := ioutil.TempDir("", "hostid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{}
+	readFile = func(path string) ([]byte, error) {
+		if contents, ok := files[path]; ok {
+			return []byte(contents), nil
+		}
+		return ioutil.ReadFile(path)
+	}
+	machineIDPaths = []string{"/etc/machine-id"}
+	idFile := filepath.Join(dir, "scope", "host-id")
+	procRoot := filepath.Join(dir, "proc")
+
+	// The override wins, if it's valid.
+	files["/etc/machine-id"] = "e2f0c9a8d5b34ea1b7c3c1f4f9a6d2e0\n"
+	if id, err := loadHostID("myhost", procRoot, idFile); err != nil || id != "myhost" {
+		t.Errorf("want myhost, have %q (%v)", id, err)
+	}
+	if _, err := loadHostID("my;host", procRoot, idFile); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	// Then the machine ID.
+	if id, err := loadHostID("", procRoot, idFile); err != nil || id != "e2f0c9a8d5b34ea1b7c3c1f4f9a6d2e0" {
+		t.Errorf("want machine ID, have %q (%v)", id, err)
+	}
+
+	// In a container, the host's machine ID, seen through PID 1's root,
+	// wins over the container's.
+	hostMachineID := filepath.Join(procRoot, "1", "root", "etc", "machine-id")
+	files[hostMachineID] = "0b8f6c1e2d3a4f5e9c7b6a5d4e3f2a1b\n"
+	if id, err := loadHostID("", procRoot, idFile); err != nil || id != "0b8f6c1e2d3a4f5e9c7b6a5d4e3f2a1b" {
+		t.Errorf("want host's machine ID, have %q (%v)", id, err)
+	}
+	delete(files, hostMachineID)
+
+	// Then a random ID, which is the same next time.
+	files["/etc/machine-id"] = ""
+	first, err := loadHostID("", procRoot, idFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(first) {
+		t.Errorf("%q isn't a UUID", first)
+	}
+	second, err := loadHostID("", procRoot, idFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("host ID changed from %q to %q", first, second)
+	}
+	if _, err := os.Stat(idFile); err != nil {
+		t.Errorf("host ID not saved: %v", err)
+	}
+
+	if _, err := loadHostID("", procRoot, ""); err == nil {
+		t.Errorf("expected an error with no machine ID or host ID file")
+	}
+}
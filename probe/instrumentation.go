@@ -18,10 +18,41 @@ var (
 		},
 		[]string{},
 	)
+	reporterDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "scope",
+			Subsystem: "probe",
+			Name:      "reporter_duration_seconds",
+			Help:      "Time taken by each reporter to generate a report.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"reporter"},
+	)
+	reporterErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "scope",
+			Subsystem: "probe",
+			Name:      "reporter_errors",
+			Help:      "Number of errors generating reports, by reporter.",
+		},
+		[]string{"reporter"},
+	)
+	reporterTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "scope",
+			Subsystem: "probe",
+			Name:      "reporter_timeouts",
+			Help:      "Number of spy ticks a reporter's report wasn't ready in time for, by reporter.",
+		},
+		[]string{"reporter"},
+	)
 )
 
 func makePrometheusHandler() http.Handler {
 	prometheus.MustRegister(publishTicks)
+	prometheus.MustRegister(reporterDuration)
+	prometheus.MustRegister(reporterErrors)
+	prometheus.MustRegister(reporterTimeouts)
 	prometheus.MustRegister(endpoint.SpyDuration)
 	return prometheus.Handler()
 }
@@ -0,0 +1,266 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serviceAccountPath is where Kubernetes mounts the credentials of a pod's
+// service account.
+const serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Vars exported for testing.
+var (
+	Getenv   = os.Getenv
+	ReadFile = ioutil.ReadFile
+)
+
+// ObjectMeta is the metadata common to all Kubernetes objects, or as much of
+// it as we use.
+type ObjectMeta struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	UID               string            `json:"uid"`
+	Labels            map[string]string `json:"labels"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+}
+
+// Pod is a Kubernetes pod.
+type Pod struct {
+	ObjectMeta `json:"metadata"`
+	Spec       PodSpec   `json:"spec"`
+	Status     PodStatus `json:"status"`
+}
+
+// PodSpec is the desired state of a pod.
+type PodSpec struct {
+	NodeName   string      `json:"nodeName"`
+	Containers []Container `json:"containers"`
+}
+
+// Container is a container in a pod's spec.
+type Container struct {
+	Name  string          `json:"name"`
+	Ports []ContainerPort `json:"ports"`
+}
+
+// ContainerPort is a port a container in a pod exposes.
+type ContainerPort struct {
+	Name          string `json:"name"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// PodStatus is the observed state of a pod.
+type PodStatus struct {
+	Phase             string            `json:"phase"`
+	PodIP             string            `json:"podIP"`
+	ContainerStatuses []ContainerStatus `json:"containerStatuses"`
+}
+
+// ContainerStatus is the observed state of a container in a pod. The
+// ContainerID is prefixed with the runtime, as in docker://<id>.
+type ContainerStatus struct {
+	Name         string `json:"name"`
+	ContainerID  string `json:"containerID"`
+	RestartCount int    `json:"restartCount"`
+}
+
+// Service is a Kubernetes service.
+type Service struct {
+	ObjectMeta `json:"metadata"`
+	Spec       ServiceSpec `json:"spec"`
+}
+
+// ServiceSpec is the desired state of a service.
+type ServiceSpec struct {
+	Type      string            `json:"type"`
+	ClusterIP string            `json:"clusterIP"`
+	Selector  map[string]string `json:"selector"`
+	Ports     []ServicePort     `json:"ports"`
+}
+
+// ServicePort is a port a service exposes, and the port of the pods it's
+// forwarded to.
+type ServicePort struct {
+	Name       string      `json:"name"`
+	Protocol   string      `json:"protocol"`
+	Port       int         `json:"port"`
+	TargetPort IntOrString `json:"targetPort"`
+}
+
+// IntOrString is a value the API gives as either a number or a string, such
+// as a target port, which may be a number or the name of a container port.
+type IntOrString string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *IntOrString) UnmarshalJSON(b []byte) error {
+	var i int
+	if err := json.Unmarshal(b, &i); err == nil {
+		*s = IntOrString(strconv.Itoa(i))
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	*s = IntOrString(str)
+	return nil
+}
+
+// Namespace is a Kubernetes namespace.
+type Namespace struct {
+	ObjectMeta `json:"metadata"`
+	Status     struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// Deployment is a Kubernetes deployment.
+type Deployment struct {
+	ObjectMeta `json:"metadata"`
+	Spec       struct {
+		Replicas *int `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+	} `json:"spec"`
+	Status struct {
+		Replicas          int `json:"replicas"`
+		AvailableReplicas int `json:"availableReplicas"`
+	} `json:"status"`
+}
+
+// Selects says whether the service forwards to the pod.
+func (s Service) Selects(p Pod) bool {
+	return s.Namespace == p.Namespace && selects(s.Spec.Selector, p.Labels)
+}
+
+// Selects says whether the pod belongs to the deployment.
+func (d Deployment) Selects(p Pod) bool {
+	return d.Namespace == p.Namespace && selects(d.Spec.Selector.MatchLabels, p.Labels)
+}
+
+// selects says whether labels match a selector. An empty selector matches
+// nothing, as the API server has it for services.
+func selects(selector, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Client lists the objects of a Kubernetes cluster.
+type Client interface {
+	ListPods() ([]Pod, error)
+	ListServices() ([]Service, error)
+	ListNamespaces() ([]Namespace, error)
+	ListDeployments() ([]Deployment, error)
+}
+
+type client struct {
+	api    string
+	token  string
+	client *http.Client
+}
+
+// NewClient returns a Client for the API server at api, e.g.
+// http://localhost:8080. If api is empty, the probe is assumed to run in a
+// pod: we find the API server from the environment, and authenticate with
+// the pod's service account.
+func NewClient(api string) (Client, error) {
+	if api != "" {
+		return &client{api: strings.TrimRight(api, "/"), client: http.DefaultClient}, nil
+	}
+
+	host, port := Getenv("KUBERNETES_SERVICE_HOST"), Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("no API server given, and not running in a pod")
+	}
+	token, err := ReadFile(path.Join(serviceAccountPath, "token"))
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := ReadFile(path.Join(serviceAccountPath, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates in %s", path.Join(serviceAccountPath, "ca.crt"))
+	}
+	return &client{
+		api:   "https://" + net.JoinHostPort(host, port),
+		token: strings.TrimSpace(string(token)),
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+			Timeout:   30 * time.Second,
+		},
+	}, nil
+}
+
+func (c *client) ListPods() ([]Pod, error) {
+	var list struct {
+		Items []Pod `json:"items"`
+	}
+	err := c.get("/api/v1/pods", &list)
+	return list.Items, err
+}
+
+func (c *client) ListServices() ([]Service, error) {
+	var list struct {
+		Items []Service `json:"items"`
+	}
+	err := c.get("/api/v1/services", &list)
+	return list.Items, err
+}
+
+func (c *client) ListNamespaces() ([]Namespace, error) {
+	var list struct {
+		Items []Namespace `json:"items"`
+	}
+	err := c.get("/api/v1/namespaces", &list)
+	return list.Items, err
+}
+
+func (c *client) ListDeployments() ([]Deployment, error) {
+	var list struct {
+		Items []Deployment `json:"items"`
+	}
+	err := c.get("/apis/apps/v1/deployments", &list)
+	return list.Items, err
+}
+
+func (c *client) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", c.api+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
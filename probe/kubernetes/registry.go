@@ -0,0 +1,131 @@
+package kubernetes
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Registry keeps track of the pods, services, namespaces and deployments of
+// a Kubernetes cluster.
+type Registry interface {
+	Stop()
+	WalkPods(f func(Pod))
+	WalkServices(f func(Service))
+	WalkNamespaces(f func(Namespace))
+	WalkDeployments(f func(Deployment))
+}
+
+// registry polls the API server every interval, and keeps the last lists
+// it got. If polling fails, we keep what we had: the cluster is unlikely to
+// have gone away just because we can't see it.
+type registry struct {
+	sync.RWMutex
+	quit     chan chan struct{}
+	interval time.Duration
+	client   Client
+
+	pods        []Pod
+	services    []Service
+	namespaces  []Namespace
+	deployments []Deployment
+}
+
+// NewRegistry returns a usable Registry, polling the API server at api (see
+// NewClient). Don't forget to Stop it.
+func NewRegistry(api string, interval time.Duration) (Registry, error) {
+	client, err := NewClient(api)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &registry{
+		client:   client,
+		interval: interval,
+		quit:     make(chan chan struct{}),
+	}
+
+	go r.loop()
+	return r, nil
+}
+
+// Stop stops polling the API server.
+func (r *registry) Stop() {
+	ch := make(chan struct{})
+	r.quit <- ch
+	<-ch
+}
+
+func (r *registry) loop() {
+	tick := time.NewTicker(r.interval)
+	defer tick.Stop()
+
+	for {
+		if err := r.update(); err != nil {
+			log.Printf("kubernetes registry: %s", err)
+		}
+
+		select {
+		case <-tick.C:
+		case ch := <-r.quit:
+			close(ch)
+			return
+		}
+	}
+}
+
+func (r *registry) update() error {
+	pods, err := r.client.ListPods()
+	if err != nil {
+		return err
+	}
+	services, err := r.client.ListServices()
+	if err != nil {
+		return err
+	}
+	namespaces, err := r.client.ListNamespaces()
+	if err != nil {
+		return err
+	}
+	deployments, err := r.client.ListDeployments()
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.pods, r.services, r.namespaces, r.deployments = pods, services, namespaces, deployments
+	return nil
+}
+
+func (r *registry) WalkPods(f func(Pod)) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, pod := range r.pods {
+		f(pod)
+	}
+}
+
+func (r *registry) WalkServices(f func(Service)) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, service := range r.services {
+		f(service)
+	}
+}
+
+func (r *registry) WalkNamespaces(f func(Namespace)) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, namespace := range r.namespaces {
+		f(namespace)
+	}
+}
+
+func (r *registry) WalkDeployments(f func(Deployment)) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, deployment := range r.deployments {
+		f(deployment)
+	}
+}
@@ -0,0 +1,153 @@
+package kubernetes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+// apiServer serves canned lists, as the Kubernetes API server would.
+var apiServer = map[string]string{
+	"/api/v1/pods": `{"kind": "PodList", "items": [{
+		"metadata": {"name": "web-1", "namespace": "default", "labels": {"app": "web"}, "creationTimestamp": "2015-08-19T16:00:00Z"},
+		"spec": {"nodeName": "node1", "containers": [{"name": "nginx", "ports": [{"name": "http", "containerPort": 8080, "protocol": "TCP"}]}]},
+		"status": {"phase": "Running", "podIP": "10.244.1.5", "containerStatuses": [{"name": "nginx", "containerID": "docker://abc", "restartCount": 0}]}
+	}, {
+		"metadata": {"name": "job-1", "namespace": "default", "creationTimestamp": "2015-08-19T16:00:00Z"},
+		"spec": {"containers": [{"name": "job"}]},
+		"status": {"phase": "Pending"}
+	}]}`,
+	"/api/v1/services": `{"kind": "ServiceList", "items": [{
+		"metadata": {"name": "web", "namespace": "default"},
+		"spec": {"type": "ClusterIP", "clusterIP": "10.96.0.20", "selector": {"app": "web"}, "ports": [{"protocol": "TCP", "port": 80, "targetPort": "http"}, {"protocol": "TCP", "port": 8081}]}
+	}, {
+		"metadata": {"name": "kubernetes", "namespace": "default"},
+		"spec": {"type": "ClusterIP", "clusterIP": "10.96.0.1", "ports": [{"protocol": "TCP", "port": 443, "targetPort": 6443}]}
+	}]}`,
+	"/api/v1/namespaces": `{"kind": "NamespaceList", "items": [{
+		"metadata": {"name": "default"}, "status": {"phase": "Active"}
+	}]}`,
+	"/apis/apps/v1/deployments": `{"kind": "DeploymentList", "items": [{
+		"metadata": {"name": "web", "namespace": "default"},
+		"spec": {"replicas": 2, "selector": {"matchLabels": {"app": "web"}}},
+		"status": {"replicas": 1, "availableReplicas": 1}
+	}]}`,
+}
+
+func serve() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := apiServer[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+}
+
+func TestReporter(t *testing.T) {
+	s := serve()
+	defer s.Close()
+
+	registry, err := kubernetes.NewRegistry(s.URL, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.Stop()
+
+	reporter := kubernetes.NewReporter(registry)
+	pods := func() interface{} {
+		r, _ := reporter.Report()
+		return len(r.Pod.NodeMetadatas)
+	}
+	test.Poll(t, 100*time.Millisecond, 2, pods)
+
+	have, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPods := report.NodeMetadatas{
+		report.MakePodNodeID("default", "web-1"): report.MakeNodeMetadataWith(map[string]string{
+			kubernetes.NamespaceName:       "default",
+			kubernetes.PodName:             "web-1",
+			kubernetes.PodCreated:          "19 Aug 15 16:00 UTC",
+			kubernetes.PodIP:               "10.244.1.5",
+			kubernetes.PodPhase:            "Running",
+			kubernetes.PodNodeName:         "node1",
+			kubernetes.PodServices:         "web",
+			kubernetes.PodDeployment:       "web",
+			kubernetes.LabelPrefix + "app": "web",
+		}),
+		report.MakePodNodeID("default", "job-1"): report.MakeNodeMetadataWith(map[string]string{
+			kubernetes.NamespaceName: "default",
+			kubernetes.PodName:       "job-1",
+			kubernetes.PodCreated:    "19 Aug 15 16:00 UTC",
+			kubernetes.PodPhase:      "Pending",
+		}),
+	}
+	if !reflect.DeepEqual(wantPods, have.Pod.NodeMetadatas) {
+		t.Errorf("%s", test.Diff(wantPods, have.Pod.NodeMetadatas))
+	}
+
+	wantServices := report.NodeMetadatas{
+		report.MakeKubernetesServiceNodeID("default", "web"): report.MakeNodeMetadataWith(map[string]string{
+			kubernetes.NamespaceName:    "default",
+			kubernetes.ServiceName:      "web",
+			kubernetes.ServiceType:      "ClusterIP",
+			kubernetes.ServiceClusterIP: "10.96.0.20",
+			kubernetes.ServicePorts:     "80/TCP->http, 8081/TCP->8081",
+			kubernetes.ServicePods:      "1",
+		}),
+		report.MakeKubernetesServiceNodeID("default", "kubernetes"): report.MakeNodeMetadataWith(map[string]string{
+			kubernetes.NamespaceName:    "default",
+			kubernetes.ServiceName:      "kubernetes",
+			kubernetes.ServiceType:      "ClusterIP",
+			kubernetes.ServiceClusterIP: "10.96.0.1",
+			kubernetes.ServicePorts:     "443/TCP->6443",
+			kubernetes.ServicePods:      "0",
+		}),
+	}
+	if !reflect.DeepEqual(wantServices, have.KubernetesService.NodeMetadatas) {
+		t.Errorf("%s", test.Diff(wantServices, have.KubernetesService.NodeMetadatas))
+	}
+
+	wantNamespaces := report.NodeMetadatas{
+		report.MakeNamespaceNodeID("default"): report.MakeNodeMetadataWith(map[string]string{
+			kubernetes.NamespaceName:  "default",
+			kubernetes.NamespacePhase: "Active",
+		}),
+	}
+	if !reflect.DeepEqual(wantNamespaces, have.Namespace.NodeMetadatas) {
+		t.Errorf("%s", test.Diff(wantNamespaces, have.Namespace.NodeMetadatas))
+	}
+
+	wantDeployments := report.NodeMetadatas{
+		report.MakeDeploymentNodeID("default", "web"): report.MakeNodeMetadataWith(map[string]string{
+			kubernetes.NamespaceName:               "default",
+			kubernetes.DeploymentName:              "web",
+			kubernetes.DeploymentReplicas:          "2",
+			kubernetes.DeploymentAvailableReplicas: "1",
+		}),
+	}
+	if !reflect.DeepEqual(wantDeployments, have.Deployment.NodeMetadatas) {
+		t.Errorf("%s", test.Diff(wantDeployments, have.Deployment.NodeMetadatas))
+	}
+}
+
+func TestNewClientInCluster(t *testing.T) {
+	oldGetenv := kubernetes.Getenv
+	defer func() { kubernetes.Getenv = oldGetenv }()
+	kubernetes.Getenv = func(string) string { return "" }
+
+	if _, err := kubernetes.NewClient(""); err == nil {
+		t.Error("expected an error outside a pod")
+	}
+}
@@ -0,0 +1,157 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Keys for use in NodeMetadata
+const (
+	NamespaceName  = "kubernetes_namespace"
+	NamespacePhase = "kubernetes_namespace_phase"
+
+	PodName       = "kubernetes_pod_name"
+	PodCreated    = "kubernetes_pod_created"
+	PodIP         = "kubernetes_pod_ip"
+	PodPhase      = "kubernetes_pod_phase"
+	PodNodeName   = "kubernetes_pod_node_name"
+	PodServices   = "kubernetes_pod_services"
+	PodDeployment = "kubernetes_pod_deployment"
+
+	ServiceName      = "kubernetes_service_name"
+	ServiceType      = "kubernetes_service_type"
+	ServiceClusterIP = "kubernetes_service_cluster_ip"
+	ServicePorts     = "kubernetes_service_ports"
+	ServicePods      = "kubernetes_service_pods"
+
+	DeploymentName              = "kubernetes_deployment_name"
+	DeploymentReplicas          = "kubernetes_deployment_replicas"
+	DeploymentAvailableReplicas = "kubernetes_deployment_available_replicas"
+
+	// Each pod label has a key made up of this prefix and the label.
+	LabelPrefix = "kubernetes_label_"
+)
+
+// Reporter generates Reports containing the Pod, KubernetesService,
+// Namespace and Deployment topologies. They're the same whichever probe
+// reports them, so the app can merge them from any number of probes.
+type Reporter struct {
+	registry Registry
+}
+
+// NewReporter makes a new Reporter
+func NewReporter(registry Registry) *Reporter {
+	return &Reporter{
+		registry: registry,
+	}
+}
+
+// Report implements Reporter.
+func (r *Reporter) Report() (report.Report, error) {
+	var (
+		result      = report.MakeReport()
+		services    = []Service{}
+		deployments = []Deployment{}
+	)
+	r.registry.WalkServices(func(s Service) { services = append(services, s) })
+	r.registry.WalkDeployments(func(d Deployment) { deployments = append(deployments, d) })
+
+	servicePods := map[string]int{}
+	r.registry.WalkPods(func(p Pod) {
+		md := map[string]string{
+			NamespaceName: p.Namespace,
+			PodName:       p.Name,
+			PodCreated:    p.CreationTimestamp.Format(time.RFC822),
+			PodPhase:      p.Status.Phase,
+		}
+		if p.Status.PodIP != "" {
+			md[PodIP] = p.Status.PodIP
+		}
+		if p.Spec.NodeName != "" {
+			md[PodNodeName] = p.Spec.NodeName
+		}
+		names := []string{}
+		for _, s := range services {
+			if s.Selects(p) {
+				names = append(names, s.Name)
+				servicePods[report.MakeKubernetesServiceNodeID(s.Namespace, s.Name)]++
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			md[PodServices] = strings.Join(names, " ")
+		}
+		for _, d := range deployments {
+			if d.Selects(p) {
+				md[PodDeployment] = d.Name
+				break
+			}
+		}
+		for k, v := range p.Labels {
+			md[LabelPrefix+k] = v
+		}
+		result.Pod.NodeMetadatas[report.MakePodNodeID(p.Namespace, p.Name)] = report.MakeNodeMetadataWith(md)
+	})
+
+	for _, s := range services {
+		nodeID := report.MakeKubernetesServiceNodeID(s.Namespace, s.Name)
+		md := map[string]string{
+			NamespaceName: s.Namespace,
+			ServiceName:   s.Name,
+			ServiceType:   s.Spec.Type,
+			ServicePods:   strconv.Itoa(servicePods[nodeID]),
+		}
+		if s.Spec.ClusterIP != "" && s.Spec.ClusterIP != "None" {
+			md[ServiceClusterIP] = s.Spec.ClusterIP
+		}
+		ports := []string{}
+		for _, port := range s.Spec.Ports {
+			target := string(port.TargetPort)
+			if target == "" {
+				target = strconv.Itoa(port.Port)
+			}
+			ports = append(ports, fmt.Sprintf("%d/%s->%s", port.Port, port.Protocol, target))
+		}
+		if len(ports) > 0 {
+			md[ServicePorts] = strings.Join(ports, ", ")
+		}
+		result.KubernetesService.NodeMetadatas[nodeID] = report.MakeNodeMetadataWith(md)
+	}
+
+	r.registry.WalkNamespaces(func(n Namespace) {
+		result.Namespace.NodeMetadatas[report.MakeNamespaceNodeID(n.Name)] = report.MakeNodeMetadataWith(map[string]string{
+			NamespaceName:  n.Name,
+			NamespacePhase: n.Status.Phase,
+		})
+	})
+
+	for _, d := range deployments {
+		md := map[string]string{
+			NamespaceName:               d.Namespace,
+			DeploymentName:              d.Name,
+			DeploymentAvailableReplicas: strconv.Itoa(d.Status.AvailableReplicas),
+		}
+		if d.Spec.Replicas != nil {
+			md[DeploymentReplicas] = strconv.Itoa(*d.Spec.Replicas)
+		}
+		result.Deployment.NodeMetadatas[report.MakeDeploymentNodeID(d.Namespace, d.Name)] = report.MakeNodeMetadataWith(md)
+	}
+
+	return result, nil
+}
+
+// Labels returns the Kubernetes labels in a pod's metadata.
+func Labels(md report.NodeMetadata) map[string]string {
+	labels := map[string]string{}
+	for k, v := range md.Metadata {
+		if strings.HasPrefix(k, LabelPrefix) {
+			labels[strings.TrimPrefix(k, LabelPrefix)] = v
+		}
+	}
+	return labels
+}
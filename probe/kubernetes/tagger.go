@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+// Tagger tags the containers of pods with the pod's namespace and name, and
+// resolves connections to services' cluster IPs to the pods behind them.
+//
+// Connections to a cluster IP are forwarded by kube-proxy to one of the
+// service's pods, but all we see is the cluster IP, which no node has. So
+// edges in the endpoint topology to a service port are replaced with edges
+// to the same target port on each of the service's running pods. We can't
+// tell which pod a connection was forwarded to, so each edge gets the
+// metadata of the original.
+type Tagger struct {
+	registry Registry
+	hostID   string
+}
+
+// NewTagger returns a usable Tagger.
+func NewTagger(registry Registry, hostID string) *Tagger {
+	return &Tagger{
+		registry: registry,
+		hostID:   hostID,
+	}
+}
+
+type addrPort struct {
+	addr, port string
+}
+
+// Tag implements Tagger.
+func (t *Tagger) Tag(r report.Report) (report.Report, error) {
+	var (
+		pods     = []Pod{}
+		byID     = map[string]Pod{}
+		backends = map[addrPort][]addrPort{}
+	)
+	t.registry.WalkPods(func(p Pod) {
+		pods = append(pods, p)
+		for _, status := range p.Status.ContainerStatuses {
+			if i := strings.Index(status.ContainerID, "://"); i >= 0 {
+				byID[status.ContainerID[i+3:]] = p
+			}
+		}
+	})
+	t.registry.WalkServices(func(s Service) {
+		if s.Spec.ClusterIP == "" || s.Spec.ClusterIP == "None" {
+			return
+		}
+		for _, p := range pods {
+			if p.Status.Phase != "Running" || p.Status.PodIP == "" || !s.Selects(p) {
+				continue
+			}
+			for _, port := range s.Spec.Ports {
+				target, ok := targetPort(p, port)
+				if !ok {
+					continue
+				}
+				service := addrPort{s.Spec.ClusterIP, strconv.Itoa(port.Port)}
+				backends[service] = append(backends[service], addrPort{p.Status.PodIP, target})
+			}
+		}
+	})
+
+	for _, nmd := range r.Container.NodeMetadatas {
+		p, ok := byID[nmd.Metadata[docker.ContainerID]]
+		if !ok {
+			continue
+		}
+		nmd.Metadata[NamespaceName] = p.Namespace
+		nmd.Metadata[PodName] = p.Name
+	}
+
+	if len(backends) > 0 {
+		t.resolveClusterIPs(r.Endpoint, backends)
+	}
+	return r, nil
+}
+
+func (t *Tagger) resolveClusterIPs(topology report.Topology, backends map[addrPort][]addrPort) {
+	for adjacencyID, dstNodeIDs := range topology.Adjacency {
+		srcNodeID, ok := report.ParseAdjacencyID(adjacencyID)
+		if !ok {
+			continue
+		}
+		resolved := report.MakeIDList()
+		for _, dstNodeID := range dstNodeIDs {
+			_, addr, port, ok := report.ParseEndpointNodeID(dstNodeID)
+			pods, isService := backends[addrPort{addr, port}]
+			if !ok || !isService {
+				resolved = resolved.Add(dstNodeID)
+				continue
+			}
+
+			edgeID := report.MakeEdgeID(srcNodeID, dstNodeID)
+			md, hasMetadata := topology.EdgeMetadatas[edgeID]
+			delete(topology.EdgeMetadatas, edgeID)
+			for _, pod := range pods {
+				podNodeID := report.MakeEndpointNodeID(t.hostID, pod.addr, pod.port)
+				resolved = resolved.Add(podNodeID)
+				if hasMetadata {
+					topology.EdgeMetadatas.Merge(report.EdgeMetadatas{
+						report.MakeEdgeID(srcNodeID, podNodeID): md,
+					})
+				}
+			}
+		}
+		topology.Adjacency[adjacencyID] = resolved
+	}
+}
+
+// targetPort returns the port of the pod that a service port forwards to.
+// Target ports may name one of the ports of the pod's containers.
+func targetPort(p Pod, port ServicePort) (string, bool) {
+	target := string(port.TargetPort)
+	if target == "" {
+		return strconv.Itoa(port.Port), true
+	}
+	if _, err := strconv.Atoi(target); err == nil {
+		return target, true
+	}
+	for _, c := range p.Spec.Containers {
+		for _, containerPort := range c.Ports {
+			if containerPort.Name == target {
+				return strconv.Itoa(containerPort.ContainerPort), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,108 @@
+package kubernetes_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+type mockRegistry struct {
+	pods     []kubernetes.Pod
+	services []kubernetes.Service
+}
+
+func (m *mockRegistry) Stop() {}
+
+func (m *mockRegistry) WalkPods(f func(kubernetes.Pod)) {
+	for _, p := range m.pods {
+		f(p)
+	}
+}
+
+func (m *mockRegistry) WalkServices(f func(kubernetes.Service)) {
+	for _, s := range m.services {
+		f(s)
+	}
+}
+
+func (m *mockRegistry) WalkNamespaces(f func(kubernetes.Namespace)) {}
+
+func (m *mockRegistry) WalkDeployments(f func(kubernetes.Deployment)) {}
+
+func newMockRegistry(t *testing.T) *mockRegistry {
+	m := &mockRegistry{}
+	for _, unmarshal := range []struct {
+		path string
+		v    interface{}
+	}{
+		{"/api/v1/pods", &struct{ Items *[]kubernetes.Pod }{&m.pods}},
+		{"/api/v1/services", &struct{ Items *[]kubernetes.Service }{&m.services}},
+	} {
+		if err := json.Unmarshal([]byte(apiServer[unmarshal.path]), unmarshal.v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestTagger(t *testing.T) {
+	var (
+		client     = report.MakeEndpointNodeID("host", "10.244.2.7", "54001")
+		clusterIP  = report.MakeEndpointNodeID("host", "10.96.0.20", "80")
+		otherPort  = report.MakeEndpointNodeID("host", "10.96.0.20", "8081")
+		kubeAPI    = report.MakeEndpointNodeID("host", "10.96.0.1", "443")
+		pod        = report.MakeEndpointNodeID("host", "10.244.1.5", "8080")
+		podOther   = report.MakeEndpointNodeID("host", "10.244.1.5", "8081")
+		count      = uint64(3)
+		containers = report.NodeMetadatas{
+			report.MakeContainerNodeID("host", "abc"): report.MakeNodeMetadataWith(map[string]string{docker.ContainerID: "abc"}),
+			report.MakeContainerNodeID("host", "def"): report.MakeNodeMetadataWith(map[string]string{docker.ContainerID: "def"}),
+		}
+	)
+
+	r := report.MakeReport()
+	r.Container.NodeMetadatas = containers
+	r.Endpoint.NodeMetadatas[client] = report.MakeNodeMetadata()
+	r.Endpoint.Adjacency[report.MakeAdjacencyID(client)] = report.MakeIDList(clusterIP, otherPort, kubeAPI)
+	r.Endpoint.EdgeMetadatas[report.MakeEdgeID(client, clusterIP)] = report.EdgeMetadata{MaxConnCountTCP: &count}
+	r.Endpoint.EdgeMetadatas[report.MakeEdgeID(client, kubeAPI)] = report.EdgeMetadata{MaxConnCountTCP: &count}
+
+	r, err := kubernetes.NewTagger(newMockRegistry(t), "host").Tag(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pod's container is tagged; the other container isn't in a pod.
+	wantContainers := report.NodeMetadatas{
+		report.MakeContainerNodeID("host", "abc"): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:       "abc",
+			kubernetes.NamespaceName: "default",
+			kubernetes.PodName:       "web-1",
+		}),
+		report.MakeContainerNodeID("host", "def"): report.MakeNodeMetadataWith(map[string]string{docker.ContainerID: "def"}),
+	}
+	if !reflect.DeepEqual(wantContainers, r.Container.NodeMetadatas) {
+		t.Errorf("%s", test.Diff(wantContainers, r.Container.NodeMetadatas))
+	}
+
+	// Connections to the web service go to its pod, on the named target
+	// port; the API server has no pods, so stays as it is.
+	wantAdjacency := report.Adjacency{
+		report.MakeAdjacencyID(client): report.MakeIDList(pod, podOther, kubeAPI),
+	}
+	if !reflect.DeepEqual(wantAdjacency, r.Endpoint.Adjacency) {
+		t.Errorf("%s", test.Diff(wantAdjacency, r.Endpoint.Adjacency))
+	}
+	wantEdges := report.EdgeMetadatas{
+		report.MakeEdgeID(client, pod):     report.EdgeMetadata{MaxConnCountTCP: &count},
+		report.MakeEdgeID(client, kubeAPI): report.EdgeMetadata{MaxConnCountTCP: &count},
+	}
+	if !reflect.DeepEqual(wantEdges, r.Endpoint.EdgeMetadatas) {
+		t.Errorf("%s", test.Diff(wantEdges, r.Endpoint.EdgeMetadatas))
+	}
+}
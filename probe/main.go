@@ -9,18 +9,18 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
-	"runtime"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/weaveworks/procspy"
+	"github.com/weaveworks/scope/probe/cgroup"
+	"github.com/weaveworks/scope/probe/container"
+	"github.com/weaveworks/scope/probe/cri"
 	"github.com/weaveworks/scope/probe/docker"
-	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/filter"
 	"github.com/weaveworks/scope/probe/host"
-	"github.com/weaveworks/scope/probe/overlay"
 	"github.com/weaveworks/scope/probe/process"
-	"github.com/weaveworks/scope/probe/sniff"
 	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/xfer"
 )
@@ -29,55 +29,74 @@ var version = "dev" // set at build time
 
 func main() {
 	var (
-		targets            = []string{fmt.Sprintf("localhost:%d", xfer.AppPort), fmt.Sprintf("scope.weave.local:%d", xfer.AppPort)}
-		token              = flag.String("token", "default-token", "probe token")
-		httpListen         = flag.String("http.listen", "", "listen address for HTTP profiling and instrumentation server")
-		publishInterval    = flag.Duration("publish.interval", 3*time.Second, "publish (output) interval")
-		spyInterval        = flag.Duration("spy.interval", time.Second, "spy (scan) interval")
+		c                  = config{Targets: []string{fmt.Sprintf("localhost:%d", xfer.AppPort), fmt.Sprintf("scope.weave.local:%d", xfer.AppPort)}}
+		configFile         = flag.String("config", "", "config file, in YAML if it ends in .yaml or .yml, otherwise JSON, which overrides the flags; reloaded on SIGHUP or when it changes")
+		configInterval     = flag.Duration("config.interval", 5*time.Second, "how often to check the config file for changes")
+		httpListen         = flag.String("http.listen", "", "listen address for HTTP profiling and instrumentation server, and the config in effect at /config")
 		prometheusEndpoint = flag.String("prometheus.endpoint", "/metrics", "Prometheus metrics exposition endpoint (requires -http.listen)")
-		spyProcs           = flag.Bool("processes", true, "report processes (needs root)")
-		dockerEnabled      = flag.Bool("docker", false, "collect Docker-related attributes for processes")
-		dockerInterval     = flag.Duration("docker.interval", 10*time.Second, "how often to update Docker attributes")
-		dockerBridge       = flag.String("docker.bridge", "docker0", "the docker bridge name")
-		weaveRouterAddr    = flag.String("weave.router.addr", "", "IP address or FQDN of the Weave router")
 		procRoot           = flag.String("proc.root", "/proc", "location of the proc filesystem")
-		captureEnabled     = flag.Bool("capture", false, "perform sampled packet capture")
-		captureInterfaces  = flag.String("capture.interfaces", interfaces(), "packet capture on these interfaces")
-		captureOn          = flag.Duration("capture.on", 1*time.Second, "packet capture duty cycle 'on'")
-		captureOff         = flag.Duration("capture.off", 5*time.Second, "packet capture duty cycle 'off'")
+		hostIDOverride     = flag.String("host.id", "", "ID for this host in reports (default: its machine ID, or a random ID saved in -host.id-file)")
+		hostIDFile         = flag.String("host.id-file", "/var/lib/scope/host-id", "where to keep this host's random ID, if it has no machine ID")
+		hostLabels         = flag.String("host.labels", "", "labels for this host, such as env=prod,zone=eu-west-1a; these override labels from -host.labels-file and -host.metadata-url")
+		dockerEnv          = docker.EndpointFromEnv()
+		cgroupPatterns     = container.Patterns{}
 	)
+	flag.StringVar(&c.Token, "token", "default-token", "probe token")
+	flag.DurationVar((*time.Duration)(&c.PublishInterval), "publish.interval", 3*time.Second, "publish (output) interval")
+	flag.DurationVar((*time.Duration)(&c.SpyInterval), "spy.interval", time.Second, "spy (scan) interval")
+	flag.DurationVar((*time.Duration)(&c.ReporterTimeout), "reporter.timeout", time.Second, "how long to wait for each reporter, each spy interval; slower reports are used the next time")
+	flag.StringVar(&c.Host.LabelsFile, "host.labels-file", "", "file of key=value lines with labels for this host, read every -host.labels-interval")
+	flag.StringVar(&c.Host.MetadataURL, "host.metadata-url", "", "local metadata endpoint serving a JSON object of labels for this host, read every -host.labels-interval")
+	flag.DurationVar((*time.Duration)(&c.Host.LabelsInterval), "host.labels-interval", time.Minute, "how often to read -host.labels-file and -host.metadata-url")
+	flag.BoolVar(&c.Processes, "processes", true, "report processes (needs root)")
+	flag.BoolVar(&c.Docker.Enabled, "docker", false, "collect Docker-related attributes for processes")
+	flag.DurationVar((*time.Duration)(&c.Docker.Interval), "docker.interval", 10*time.Second, "how often to update Docker attributes")
+	flag.StringVar(&c.Docker.Bridge, "docker.bridge", "docker0", "the docker bridge name")
+	flag.StringVar(&c.Docker.Host, "docker.host", dockerEnv.Host, "Docker daemon endpoint, unix:// or tcp:// (default from $DOCKER_HOST)")
+	flag.StringVar(&c.Docker.CertPath, "docker.cert-path", dockerEnv.CertPath, "directory with the TLS client certificate, key and CA for the Docker daemon; enables TLS (default from $DOCKER_CERT_PATH)")
+	flag.BoolVar(&c.Docker.TLSVerify, "docker.tls-verify", dockerEnv.TLSVerify, "verify the Docker daemon's certificate (default from $DOCKER_TLS_VERIFY)")
+	flag.StringVar(&c.Docker.Stats, "docker.stats", "api", "where to get container stats: the Docker stats API (api), or the containers' cgroups (cgroup)")
+	flag.BoolVar(&c.CRI.Enabled, "cri", false, "collect container attributes from a CRI runtime, such as containerd or CRI-O, rather than Docker")
+	flag.StringVar(&c.CRI.Endpoint, "cri.endpoint", cri.DefaultEndpoint, "CRI runtime endpoint")
+	flag.DurationVar((*time.Duration)(&c.CRI.Interval), "cri.interval", 10*time.Second, "how often to poll the CRI runtime")
+	flag.StringVar(&c.Cgroup.Root, "cgroup.root", cgroup.DefaultRoot, "location of the cgroup filesystems")
+	flag.BoolVar(&c.Cgroup.Containers, "cgroup.containers", false, "detect containers from the cgroups of processes, for runtimes we don't talk to")
+	flag.Var(&cgroupPatterns, "cgroup.pattern", "runtime=regexp matching the cgroups of a runtime's containers, with a submatch for the container ID; may be repeated (default docker, podman, cri-o, containerd, lxc and systemd-nspawn)")
+	flag.BoolVar(&c.Systemd.Enabled, "systemd", false, "report the systemd units processes belong to, as services")
+	flag.BoolVar(&c.Systemd.DBus, "systemd.dbus", true, "get the descriptions and states of systemd units over D-Bus")
+	flag.BoolVar(&c.Kubernetes.Enabled, "kubernetes", false, "collect pods, services, namespaces and deployments from the Kubernetes API server")
+	flag.StringVar(&c.Kubernetes.API, "kubernetes.api", "", "Kubernetes API server address, e.g. http://localhost:8080 (default: the cluster the probe runs in)")
+	flag.DurationVar((*time.Duration)(&c.Kubernetes.Interval), "kubernetes.interval", 10*time.Second, "how often to poll the Kubernetes API server")
+	flag.StringVar(&c.Weave.RouterAddr, "weave.router.addr", "", "IP address or FQDN of the Weave router")
+	flag.StringVar(&c.Plugins.Root, "plugins.root", "", "directory of plugin sockets and executables, called every spy interval, such as /var/run/scope/plugins; executables must be owned by root and writable only by it (default: no plugins)")
+	flag.DurationVar((*time.Duration)(&c.Plugins.Timeout), "plugins.timeout", 500*time.Millisecond, "how long to wait for each plugin to reply")
+	flag.BoolVar(&c.Capture.Enabled, "capture", false, "perform sampled packet capture")
+	flag.StringVar(&c.Capture.Interfaces, "capture.interfaces", interfaces(), "packet capture on these interfaces")
+	flag.DurationVar((*time.Duration)(&c.Capture.On), "capture.on", 1*time.Second, "packet capture duty cycle 'on'")
+	flag.DurationVar((*time.Duration)(&c.Capture.Off), "capture.off", 5*time.Second, "packet capture duty cycle 'off'")
 	flag.Parse()
 
 	log.Printf("probe starting, version %s", version)
 
 	if len(flag.Args()) > 0 {
-		targets = flag.Args()
+		c.Targets = flag.Args()
 	}
-	log.Printf("publishing to: %s", strings.Join(targets, ", "))
-
-	procspy.SetProcRoot(*procRoot)
-
-	if *httpListen != "" {
-		log.Printf("profiling data being exported to %s", *httpListen)
-		log.Printf("go tool pprof http://%s/debug/pprof/{profile,heap,block}", *httpListen)
-		if *prometheusEndpoint != "" {
-			log.Printf("exposing Prometheus endpoint at %s%s", *httpListen, *prometheusEndpoint)
-			http.Handle(*prometheusEndpoint, makePrometheusHandler())
-		}
-		go func() {
-			err := http.ListenAndServe(*httpListen, nil)
-			log.Print(err)
-		}()
+	labels, err := host.ParseLabels(*hostLabels)
+	if err != nil {
+		log.Fatalf("invalid -host.labels: %v", err)
 	}
-
-	if *spyProcs && os.Getegid() != 0 {
-		log.Printf("warning: process reporting enabled, but that requires root to find everything")
+	c.Host.Labels = labels
+	c.Services = process.DefaultServiceRules
+	c.Filter = filter.DefaultConfig
+	for _, pattern := range cgroupPatterns {
+		c.Cgroup.Patterns = append(c.Cgroup.Patterns, pattern.Runtime+"="+pattern.Regexp.String())
+	}
+	current, err := c.load(*configFile)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
 	}
 
-	publisherFactory := func(target string) (xfer.Publisher, error) { return xfer.NewHTTPPublisher(target, *token) }
-	publishers := xfer.NewMultiPublisher(publisherFactory)
-	resolver := newStaticResolver(targets, publishers.Add)
-	defer resolver.Stop()
+	procspy.SetProcRoot(*procRoot)
 
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
@@ -91,92 +110,95 @@ func main() {
 		}
 	}
 
-	var (
-		hostName     = hostname()
-		hostID       = hostName // TODO: we should sanitize the hostname
-		taggers      = []Tagger{newTopologyTagger(), host.NewTagger(hostID)}
-		reporters    = []Reporter{host.NewReporter(hostID, hostName, localNets), endpoint.NewReporter(hostID, hostName, *spyProcs)}
-		processCache *process.CachingWalker
-	)
-
-	processCache = process.NewCachingWalker(process.NewWalker(*procRoot))
-	reporters = append(reporters, process.NewReporter(processCache, hostID))
-
-	if *dockerEnabled {
-		if err := report.AddLocalBridge(*dockerBridge); err != nil {
-			log.Fatalf("failed to get docker bridge address: %v", err)
-		}
-
-		dockerRegistry, err := docker.NewRegistry(*dockerInterval)
-		if err != nil {
-			log.Fatalf("failed to start docker registry: %v", err)
-		}
-		defer dockerRegistry.Stop()
+	hostID, err := loadHostID(*hostIDOverride, *procRoot, *hostIDFile)
+	if err != nil {
+		log.Fatalf("failed to get host ID: %v", err)
+	}
+	hostName := hostname()
+	log.Printf("host ID %s, hostname %s", hostID, hostName)
 
-		taggers = append(taggers, docker.NewTagger(dockerRegistry, processCache))
-		reporters = append(reporters, docker.NewReporter(dockerRegistry, hostID))
+	p := newProbe(hostID, hostName, *procRoot, localNets)
+	if err := p.apply(current); err != nil {
+		log.Fatal(err)
 	}
+	defer p.stop()
 
-	if *weaveRouterAddr != "" {
-		weave, err := overlay.NewWeave(*weaveRouterAddr)
-		if err != nil {
-			log.Fatalf("failed to start Weave tagger: %v", err)
+	if *httpListen != "" {
+		log.Printf("profiling data being exported to %s", *httpListen)
+		log.Printf("go tool pprof http://%s/debug/pprof/{profile,heap,block}", *httpListen)
+		if *prometheusEndpoint != "" {
+			log.Printf("exposing Prometheus endpoint at %s%s", *httpListen, *prometheusEndpoint)
+			http.Handle(*prometheusEndpoint, makePrometheusHandler())
 		}
-		taggers = append(taggers, weave)
-		reporters = append(reporters, weave)
+		http.Handle("/config", p)
+		go func() {
+			err := http.ListenAndServe(*httpListen, nil)
+			log.Print(err)
+		}()
 	}
 
-	if *captureEnabled {
-		var sniffers int
-		for _, iface := range strings.Split(*captureInterfaces, ",") {
-			source, err := sniff.NewSource(iface)
-			if err != nil {
-				log.Printf("warning: %v", err)
-				continue
-			}
-			defer source.Close()
-			log.Printf("capturing packets on %s", iface)
-			reporters = append(reporters, sniff.New(hostID, localNets, source, *captureOn, *captureOff))
-			sniffers++
-		}
-		// Packet capture can block OS threads on Linux, so we need to provide
-		// sufficient overhead in GOMAXPROCS.
-		if have, want := runtime.GOMAXPROCS(-1), (sniffers + 1); have < want {
-			runtime.GOMAXPROCS(want)
-		}
+	if current.Processes && os.Getegid() != 0 {
+		log.Printf("warning: process reporting enabled, but that requires root to find everything")
 	}
 
 	quit := make(chan struct{})
 	defer close(quit)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	var configChanged <-chan struct{}
+	if *configFile != "" {
+		configChanged = watchConfig(*configFile, *configInterval, quit)
+	}
+
 	go func() {
 		var (
-			pubTick = time.Tick(*publishInterval)
-			spyTick = time.Tick(*spyInterval)
+			pubTick = time.NewTicker(time.Duration(current.PublishInterval))
+			spyTick = time.NewTicker(time.Duration(current.SpyInterval))
 			r       = report.MakeReport()
 		)
+		defer func() { pubTick.Stop(); spyTick.Stop() }()
+
+		// Reloading keeps the report so far, so nothing is lost.
+		reloadConfig := func(current config) config {
+			next, err := c.load(*configFile)
+			if err == nil {
+				err = p.apply(next)
+			}
+			if err != nil {
+				log.Printf("config: not reloaded: %v", err)
+				return current
+			}
+			log.Printf("config: reloaded")
+			if next.PublishInterval != current.PublishInterval {
+				pubTick.Stop()
+				pubTick = time.NewTicker(time.Duration(next.PublishInterval))
+			}
+			if next.SpyInterval != current.SpyInterval {
+				spyTick.Stop()
+				spyTick = time.NewTicker(time.Duration(next.SpyInterval))
+			}
+			return next
+		}
 
 		for {
 			select {
-			case <-pubTick:
+			case <-pubTick.C:
 				publishTicks.WithLabelValues().Add(1)
-				r.Window = *publishInterval
-				if err := publishers.Publish(r); err != nil {
+				r.Window = time.Duration(current.PublishInterval)
+				if err := p.publish(r); err != nil {
 					log.Printf("publish: %v", err)
 				}
 				r = report.MakeReport()
 
-			case <-spyTick:
-				if err := processCache.Update(); err != nil {
-					log.Printf("error reading processes: %v", err)
-				}
-				for _, reporter := range reporters {
-					newReport, err := reporter.Report()
-					if err != nil {
-						log.Printf("error generating report: %v", err)
-					}
-					r.Merge(newReport)
-				}
-				r = Apply(r, taggers)
+			case <-spyTick.C:
+				r = p.spy(r)
+
+			case <-reload:
+				current = reloadConfig(current)
+
+			case <-configChanged:
+				current = reloadConfig(current)
 
 			case <-quit:
 				return
@@ -3,15 +3,20 @@ package overlay
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/report"
 )
 
+// Keys for use in NodeMetadata
 const (
 	// WeavePeerName is the key for the peer name, typically a MAC address.
 	WeavePeerName = "weave_peer_name"
@@ -19,65 +24,205 @@ const (
 	// WeavePeerNickName is the key for the peer nickname, typically a
 	// hostname.
 	WeavePeerNickName = "weave_peer_nick_name"
+
+	// WeaveIPAMAddresses is the key for the number of addresses in the
+	// IPAM range that the peer owns, and so can allocate to containers.
+	WeaveIPAMAddresses = "weave_ipam_addresses"
+
+	// WeaveConnectionPrefix makes up the key for the state of a peer's
+	// connection to another peer, with the other peer's name.
+	WeaveConnectionPrefix = "weave_connection_"
+
+	// WeaveIPs and WeaveDNSHostnames are the keys for the weave IPs and
+	// WeaveDNS names of a container, space separated.
+	WeaveIPs          = "weave_ips"
+	WeaveDNSHostnames = "weave_dns_hostnames"
 )
 
+var weaveClient = &http.Client{Timeout: 2 * time.Second}
+
 // Weave represents a single Weave router, presumably on the same host
 // as the probe. It is both a Reporter and a Tagger: it produces an Overlay
-// topology, and (in theory) can tag existing topologies with foreign keys to
-// overlay -- though I'm not sure what that would look like in practice right
-// now.
+// topology of the router's peers and the connections between them, and tags
+// containers with their weave IPs and WeaveDNS names.
+//
+// The tagger uses the router status fetched by the last Report, so should
+// run after it, as taggers do.
 type Weave struct {
-	url string
+	url    string
+	hostID string
+
+	mtx    sync.Mutex
+	status weaveStatus
+}
+
+// weaveStatus is the part of the router's /report we use.
+type weaveStatus struct {
+	Router struct {
+		Name  string `json:"Name"`
+		Peers []struct {
+			Name        string `json:"Name"`
+			NickName    string `json:"NickName"`
+			Connections []struct {
+				Name        string `json:"Name"`
+				Address     string `json:"Address"`
+				Established bool   `json:"Established"`
+			} `json:"Connections"`
+		} `json:"Peers"`
+		Connections []struct {
+			Address string `json:"Address"`
+			State   string `json:"State"`
+			Attrs   struct {
+				Encrypted bool `json:"encrypted"`
+			} `json:"Attrs"`
+		} `json:"Connections"`
+	} `json:"Router"`
+
+	IPAM struct {
+		Entries []struct {
+			Peer string `json:"Peer"`
+			Size int    `json:"Size"`
+		} `json:"Entries"`
+	} `json:"IPAM"`
+
+	DNS struct {
+		Entries []struct {
+			Hostname    string `json:"Hostname"`
+			ContainerID string `json:"ContainerID"`
+			Address     string `json:"Address"`
+			Tombstone   int64  `json:"Tombstone"`
+		} `json:"Entries"`
+	} `json:"DNS"`
 }
 
 // NewWeave returns a new Weave tagger based on the Weave router at
 // address. The address should be an IP or FQDN, no port.
-func NewWeave(weaveRouterAddress string) (*Weave, error) {
-	s, err := sanitize("http://", 6784, "/status-json")(weaveRouterAddress)
+func NewWeave(hostID, weaveRouterAddress string) (*Weave, error) {
+	s, err := sanitize("http://", 6784, "/report")(weaveRouterAddress)
 	if err != nil {
 		return nil, err
 	}
-	return &Weave{s}, nil
+	return &Weave{url: s, hostID: hostID}, nil
 }
 
 // Tag implements Tagger.
-func (w Weave) Tag(r report.Report) (report.Report, error) {
-	// The status-json endpoint doesn't return any link information, so
-	// there's nothing to tag, yet.
+func (w *Weave) Tag(r report.Report) (report.Report, error) {
+	w.mtx.Lock()
+	status := w.status
+	w.mtx.Unlock()
+
+	ips, hostnames := map[string][]string{}, map[string][]string{}
+	for _, entry := range status.DNS.Entries {
+		if entry.Tombstone > 0 {
+			continue
+		}
+		ips[entry.ContainerID] = appendUnique(ips[entry.ContainerID], entry.Address)
+		hostnames[entry.ContainerID] = appendUnique(hostnames[entry.ContainerID], strings.TrimSuffix(entry.Hostname, "."))
+	}
+
+	for _, nmd := range r.Container.NodeMetadatas {
+		id := nmd.Metadata[docker.ContainerID]
+		if len(ips[id]) > 0 {
+			nmd.Metadata[WeaveIPs] = strings.Join(ips[id], " ")
+		}
+		if len(hostnames[id]) > 0 {
+			nmd.Metadata[WeaveDNSHostnames] = strings.Join(hostnames[id], " ")
+		}
+	}
 	return r, nil
 }
 
 // Report implements Reporter.
-func (w Weave) Report() (report.Report, error) {
+func (w *Weave) Report() (report.Report, error) {
 	r := report.MakeReport()
 
-	resp, err := http.Get(w.url)
+	resp, err := weaveClient.Get(w.url)
 	if err != nil {
-		log.Printf("Weave Tagger: %v", err)
 		return r, err
 	}
 	defer resp.Body.Close()
 
-	var status struct {
-		Peers []struct {
-			Name     string `json:"Name"`
-			NickName string `json:"NickName"`
-		} `json:"Peers"`
-	}
+	var status weaveStatus
 	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
-		log.Printf("Weave Tagger: %v", err)
 		return r, err
 	}
 
-	for _, peer := range status.Peers {
-		r.Overlay.NodeMetadatas[report.MakeOverlayNodeID(peer.Name)] = report.MakeNodeMetadataWith(map[string]string{
-			WeavePeerName:     peer.Name,
-			WeavePeerNickName: peer.NickName,
+	w.mtx.Lock()
+	w.status = status
+	w.mtx.Unlock()
+
+	owned := map[string]int{}
+	for _, entry := range status.IPAM.Entries {
+		owned[entry.Peer] += entry.Size
+	}
+
+	for _, peer := range status.Router.Peers {
+		md := report.MakeNodeMetadataWith(map[string]string{
+			WeavePeerName:      peer.Name,
+			WeavePeerNickName:  peer.NickName,
+			WeaveIPAMAddresses: strconv.Itoa(owned[peer.Name]),
 		})
+		if peer.Name == status.Router.Name {
+			md.Metadata[report.HostNodeID] = report.MakeHostNodeID(w.hostID)
+		}
+		r.Overlay.NodeMetadatas[report.MakeOverlayNodeID(peer.Name)] = md
+	}
+
+	for _, peer := range status.Router.Peers {
+		nodeID := report.MakeOverlayNodeID(peer.Name)
+		adjacencyID := report.MakeAdjacencyID(nodeID)
+		for _, conn := range peer.Connections {
+			otherID := report.MakeOverlayNodeID(conn.Name)
+			if _, ok := r.Overlay.NodeMetadatas[otherID]; !ok || !conn.Established {
+				continue
+			}
+			r.Overlay.Adjacency[adjacencyID] = r.Overlay.Adjacency[adjacencyID].Add(otherID)
+		}
+
+		// Only our own router knows the state of its connections.
+		if peer.Name != status.Router.Name {
+			continue
+		}
+		md := r.Overlay.NodeMetadatas[nodeID]
+		for _, conn := range peer.Connections {
+			for _, c := range status.Router.Connections {
+				if c.Address != conn.Address {
+					continue
+				}
+				encryption := "unencrypted"
+				if c.Attrs.Encrypted {
+					encryption = "encrypted"
+				}
+				md.Metadata[WeaveConnectionPrefix+conn.Name] = fmt.Sprintf("%s, %s", c.State, encryption)
+			}
+		}
 	}
 	return r, nil
 }
 
+// Connections returns the state of a peer's connections from its metadata,
+// keyed by the name of the peer at the other end.
+func Connections(md report.NodeMetadata) map[string]string {
+	connections := map[string]string{}
+	for k, v := range md.Metadata {
+		if strings.HasPrefix(k, WeaveConnectionPrefix) {
+			connections[strings.TrimPrefix(k, WeaveConnectionPrefix)] = v
+		}
+	}
+	return connections
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	list = append(list, s)
+	sort.Strings(list)
+	return list
+}
+
 func sanitize(scheme string, port int, path string) func(string) (string, error) {
 	return func(s string) (string, error) {
 		if s == "" {
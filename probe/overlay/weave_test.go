@@ -1,12 +1,12 @@
 package overlay_test
 
 import (
-	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
 
+	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/probe/overlay"
 	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test"
@@ -16,7 +16,7 @@ func TestWeaveTaggerOverlayTopology(t *testing.T) {
 	s := httptest.NewServer(http.HandlerFunc(mockWeaveRouter))
 	defer s.Close()
 
-	w, err := overlay.NewWeave(s.URL)
+	w, err := overlay.NewWeave("host", s.URL)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -25,32 +25,110 @@ func TestWeaveTaggerOverlayTopology(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	var (
+		localID  = report.MakeOverlayNodeID(mockWeavePeerName)
+		remoteID = report.MakeOverlayNodeID(mockRemotePeerName)
+	)
 	if want, have := (report.Topology{
-		Adjacency:     report.Adjacency{},
+		Adjacency: report.Adjacency{
+			report.MakeAdjacencyID(localID):  report.MakeIDList(remoteID),
+			report.MakeAdjacencyID(remoteID): report.MakeIDList(localID),
+		},
 		EdgeMetadatas: report.EdgeMetadatas{},
 		NodeMetadatas: report.NodeMetadatas{
-			report.MakeOverlayNodeID(mockWeavePeerName): report.MakeNodeMetadataWith(map[string]string{
-				overlay.WeavePeerName:     mockWeavePeerName,
-				overlay.WeavePeerNickName: mockWeavePeerNickName,
+			localID: report.MakeNodeMetadataWith(map[string]string{
+				overlay.WeavePeerName:                              mockWeavePeerName,
+				overlay.WeavePeerNickName:                          mockWeavePeerNickName,
+				overlay.WeaveIPAMAddresses:                         "524288",
+				overlay.WeaveConnectionPrefix + mockRemotePeerName: "established, encrypted",
+				report.HostNodeID:                                  report.MakeHostNodeID("host"),
+			}),
+			remoteID: report.MakeNodeMetadataWith(map[string]string{
+				overlay.WeavePeerName:      mockRemotePeerName,
+				overlay.WeavePeerNickName:  mockRemotePeerNickName,
+				overlay.WeaveIPAMAddresses: "524288",
 			}),
 		},
 	}), have.Overlay; !reflect.DeepEqual(want, have) {
 		t.Error(test.Diff(want, have))
 	}
+
+	r := report.MakeReport()
+	r.Container.NodeMetadatas[report.MakeContainerNodeID("host", mockContainerID)] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ContainerID: mockContainerID,
+	})
+	r.Container.NodeMetadatas[report.MakeContainerNodeID("host", "other")] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ContainerID: "other",
+	})
+	r, err = w.Tag(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := (report.NodeMetadatas{
+		report.MakeContainerNodeID("host", mockContainerID): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID:        mockContainerID,
+			overlay.WeaveIPs:          "10.32.0.2",
+			overlay.WeaveDNSHostnames: "db.weave.local web.weave.local",
+		}),
+		report.MakeContainerNodeID("host", "other"): report.MakeNodeMetadataWith(map[string]string{
+			docker.ContainerID: "other",
+		}),
+	}), r.Container.NodeMetadatas; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
 }
 
 const (
-	mockWeavePeerName     = "winnebago"
-	mockWeavePeerNickName = "winny"
+	mockWeavePeerName      = "ce:31:e0:06:45:1a"
+	mockWeavePeerNickName  = "winny"
+	mockRemotePeerName     = "2e:a1:b5:3d:9e:0c"
+	mockRemotePeerNickName = "bago"
+	mockContainerID        = "a1b2c3d4e5"
 )
 
 func mockWeaveRouter(w http.ResponseWriter, r *http.Request) {
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
-		"Peers": []map[string]interface{}{{
-			"Name":     mockWeavePeerName,
-			"NickName": mockWeavePeerNickName,
-		}},
-	}); err != nil {
-		panic(err)
+	if r.URL.Path != "/report" {
+		http.NotFound(w, r)
+		return
 	}
+	w.Write([]byte(`{
+		"Version": "1.4.0",
+		"Router": {
+			"Name": "` + mockWeavePeerName + `",
+			"NickName": "` + mockWeavePeerNickName + `",
+			"Peers": [{
+				"Name": "` + mockWeavePeerName + `",
+				"NickName": "` + mockWeavePeerNickName + `",
+				"Connections": [
+					{"Name": "` + mockRemotePeerName + `", "Address": "192.168.48.12:6783", "Outbound": true, "Established": true},
+					{"Name": "ghost", "Address": "192.168.48.13:6783", "Outbound": true, "Established": true}
+				]
+			}, {
+				"Name": "` + mockRemotePeerName + `",
+				"NickName": "` + mockRemotePeerNickName + `",
+				"Connections": [
+					{"Name": "` + mockWeavePeerName + `", "Address": "192.168.48.11:51234", "Outbound": false, "Established": true}
+				]
+			}],
+			"Connections": [
+				{"Address": "192.168.48.12:6783", "Outbound": true, "State": "established", "Info": "encrypted fastdp", "Attrs": {"encrypted": true, "name": "fastdp"}},
+				{"Address": "192.168.48.14:6783", "Outbound": true, "State": "failed", "Info": "connection refused"}
+			]
+		},
+		"IPAM": {
+			"Range": "10.32.0.0/12",
+			"Entries": [
+				{"Token": "10.32.0.0", "Size": 524288, "Peer": "` + mockWeavePeerName + `", "Nickname": "` + mockWeavePeerNickName + `", "IsKnownPeer": true},
+				{"Token": "10.40.0.0", "Size": 524288, "Peer": "` + mockRemotePeerName + `", "Nickname": "` + mockRemotePeerNickName + `", "IsKnownPeer": true}
+			]
+		},
+		"DNS": {
+			"Domain": "weave.local.",
+			"Entries": [
+				{"Hostname": "web.weave.local.", "Origin": "` + mockWeavePeerName + `", "ContainerID": "` + mockContainerID + `", "Address": "10.32.0.2", "Tombstone": 0},
+				{"Hostname": "db.weave.local.", "Origin": "` + mockWeavePeerName + `", "ContainerID": "` + mockContainerID + `", "Address": "10.32.0.2", "Tombstone": 0},
+				{"Hostname": "old.weave.local.", "Origin": "` + mockWeavePeerName + `", "ContainerID": "` + mockContainerID + `", "Address": "10.32.0.9", "Tombstone": 1440000000}
+			]
+		}
+	}`))
 }
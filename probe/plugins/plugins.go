@@ -0,0 +1,311 @@
+// Package plugins lets processes outside the probe add to its reports.
+//
+// A plugin is either a Unix socket or an executable file in the plugins
+// directory; the probe looks for them every spy tick, so plugins can come
+// and go while it runs. Every tick, each plugin is sent a request, as JSON:
+//
+//   {"host_id": "host1"}
+//
+// Sockets get it as the body of a POST to /report, over HTTP; executables
+// are run with it on stdin. Either replies with a response, as JSON, on the
+// HTTP response body or stdout:
+//
+//   {
+//     "report": {
+//       "Container": {
+//         "Adjacency": {},
+//         "EdgeMetadatas": {},
+//         "NodeMetadatas": {"host1;abc": {"Metadata": {"key": "value"}}}
+//       }
+//     },
+//     "annotations": {
+//       "container": {"host1;def": {"key": "value"}}
+//     }
+//   }
+//
+// Both are optional. The report has the same form as the probe's own, and
+// is merged into it, like a Reporter's. Annotations are keyed by topology
+// (endpoint, address, process, container, container_image, service, pod,
+// kubernetes_service, namespace, deployment, host or overlay) and node ID,
+// and are merged into the metadata of nodes the probe already has, like a
+// Tagger's; annotations of nodes that don't exist are dropped.
+//
+// The probe runs as root, so it only runs executables owned by root which
+// only root can write to; others are ignored.
+//
+// A plugin which doesn't reply in time, fails, or replies with something
+// that isn't a valid report is skipped for that tick, and doesn't affect
+// the others.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// ExecutableOwner is the user ID executable plugins must be owned by.
+// Exposed for testing.
+var ExecutableOwner uint32
+
+// Request is what the probe sends to each plugin.
+type Request struct {
+	HostID string `json:"host_id"`
+}
+
+// Response is what each plugin replies with.
+type Response struct {
+	Report      report.Report                           `json:"report"`
+	Annotations map[string]map[string]map[string]string `json:"annotations"`
+}
+
+// Registry finds the plugins in a directory, and calls them. It is both a
+// Reporter and a Tagger: the report of each plugin is merged by Report,
+// and its annotations, from the same call, are applied by Tag.
+type Registry struct {
+	root    string
+	hostID  string
+	timeout time.Duration
+
+	mtx         sync.Mutex
+	annotations []map[string]map[string]map[string]string
+}
+
+// NewRegistry returns a Registry of the plugins in root, which calls each
+// with the given timeout.
+func NewRegistry(root, hostID string, timeout time.Duration) *Registry {
+	return &Registry{
+		root:    root,
+		hostID:  hostID,
+		timeout: timeout,
+	}
+}
+
+// Report implements Reporter.
+func (r *Registry) Report() (report.Report, error) {
+	result := report.MakeReport()
+
+	paths, err := r.plugins()
+	if err != nil {
+		return result, err
+	}
+	request, err := json.Marshal(Request{HostID: r.hostID})
+	if err != nil {
+		return result, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		responses = make([]*Response, len(paths))
+	)
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			response, err := r.call(path, request)
+			if err != nil {
+				log.Printf("plugin %s: %v", filepath.Base(path), err)
+				return
+			}
+			responses[i] = response
+		}(i, path)
+	}
+	wg.Wait()
+
+	annotations := []map[string]map[string]map[string]string{}
+	for _, response := range responses {
+		if response == nil {
+			continue
+		}
+		result.Merge(response.Report)
+		annotations = append(annotations, response.Annotations)
+	}
+
+	r.mtx.Lock()
+	r.annotations = annotations
+	r.mtx.Unlock()
+	return result, nil
+}
+
+// Tag implements Tagger.
+func (r *Registry) Tag(rpt report.Report) (report.Report, error) {
+	r.mtx.Lock()
+	annotations := r.annotations
+	r.mtx.Unlock()
+
+	topologies := topologies(&rpt)
+	for _, annotation := range annotations {
+		for name, nodes := range annotation {
+			topology, ok := topologies[name]
+			if !ok {
+				continue
+			}
+			for nodeID, md := range nodes {
+				if nmd, ok := topology.NodeMetadatas[nodeID]; ok {
+					nmd.Merge(report.MakeNodeMetadataWith(md))
+				}
+			}
+		}
+	}
+	return rpt, nil
+}
+
+// plugins returns the paths of the sockets and executables in the root,
+// leaving out executables which anyone but their owner can write to, or
+// which aren't owned by ExecutableOwner.
+func (r *Registry) plugins() ([]string, error) {
+	infos, err := ioutil.ReadDir(r.root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, info := range infos {
+		mode := info.Mode()
+		switch {
+		case mode&os.ModeSocket != 0:
+		case mode.IsRegular() && mode&0111 != 0:
+			if !trusted(info) {
+				log.Printf("plugin %s: ignored, as it's writable by others or not owned by %d", info.Name(), ExecutableOwner)
+				continue
+			}
+		default:
+			continue
+		}
+		paths = append(paths, filepath.Join(r.root, info.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func trusted(info os.FileInfo) bool {
+	if info.Mode()&0022 != 0 {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid == ExecutableOwner
+}
+
+func (r *Registry) call(path string, request []byte) (*Response, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var buf []byte
+	if info.Mode()&os.ModeSocket != 0 {
+		buf, err = callSocket(ctx, path, request)
+	} else {
+		buf, err = callExecutable(ctx, path, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := Response{Report: report.MakeReport()}
+	if err := json.Unmarshal(buf, &response); err != nil {
+		return nil, err
+	}
+	if err := response.Report.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Taggers expect every node to have metadata they can add to, and the
+	// window and sampling are the probe's business.
+	for _, topology := range topologies(&response.Report) {
+		for nodeID, nmd := range topology.NodeMetadatas {
+			if nmd.Metadata == nil {
+				topology.NodeMetadatas[nodeID] = report.MakeNodeMetadata()
+			}
+		}
+	}
+	response.Report.Window, response.Report.Sampling = 0, report.Sampling{}
+	return &response, nil
+}
+
+func callSocket(ctx context.Context, path string, request []byte) ([]byte, error) {
+	// Each call dials the socket afresh, so connections mustn't be kept
+	// around for the next.
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+	client := http.Client{Transport: transport}
+	req, err := http.NewRequest("POST", "http://plugin/report", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func callExecutable(ctx context.Context, path string, request []byte) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return stdout.Bytes(), err
+	case <-ctx.Done():
+		// Kill the plugin's whole process group, so that nothing it started
+		// keeps its stdout open.
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return nil, ctx.Err()
+	}
+}
+
+func topologies(r *report.Report) map[string]*report.Topology {
+	return map[string]*report.Topology{
+		"endpoint":           &(r.Endpoint),
+		"address":            &(r.Address),
+		"process":            &(r.Process),
+		"container":          &(r.Container),
+		"container_image":    &(r.ContainerImage),
+		"service":            &(r.Service),
+		"pod":                &(r.Pod),
+		"kubernetes_service": &(r.KubernetesService),
+		"namespace":          &(r.Namespace),
+		"deployment":         &(r.Deployment),
+		"host":               &(r.Host),
+		"overlay":            &(r.Overlay),
+	}
+}
@@ -0,0 +1,141 @@
+package plugins_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/plugins"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+const (
+	execPlugin = `#!/bin/sh
+host=$(sed 's/.*"host_id":"\([^"]*\)".*/\1/')
+echo '{"report": {"Container": {"NodeMetadatas": {"'$host';abc": {"Metadata": {"plugin": "exec"}}}}},
+	"annotations": {"process": {"'$host';1": {"exec": "yes"}, "'$host';2": {"exec": "no"}}, "nonsense": {"x": {"y": "z"}}}}'
+`
+	slowPlugin = `#!/bin/sh
+sleep 10
+echo '{"report": {"Container": {"NodeMetadatas": {"host;slow": {"Metadata": {}}}}}}'
+`
+	brokenPlugin = `#!/bin/sh
+echo '{"report": {"Container": {"EdgeMetadatas": {"host;a|host;b": {}}}}}'
+`
+	writablePlugin = `#!/bin/sh
+echo '{"report": {"Container": {"NodeMetadatas": {"host;writable": {"Metadata": {}}}}}}'
+`
+	failingPlugin = `#!/bin/sh
+exit 1
+`
+)
+
+func TestRegistry(t *testing.T) {
+	oldOwner := plugins.ExecutableOwner
+	defer func() { plugins.ExecutableOwner = oldOwner }()
+	plugins.ExecutableOwner = uint32(os.Getuid())
+
+	root, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for name, script := range map[string]string{
+		"exec":    execPlugin,
+		"slow":    slowPlugin,
+		"broken":  brokenPlugin,
+		"failing": failingPlugin,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "README"), []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Anyone could have put this one there, so it isn't run.
+	writable := filepath.Join(root, "writable")
+	if err := ioutil.WriteFile(writable, []byte(writablePlugin), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(writable, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(root, "socket"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request plugins.Request
+		if r.Method != "POST" || r.URL.Path != "/report" || json.NewDecoder(r.Body).Decode(&request) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(plugins.Response{
+			Report: report.Report{
+				Host: report.Topology{
+					NodeMetadatas: report.NodeMetadatas{
+						report.MakeHostNodeID(request.HostID): report.MakeNodeMetadataWith(map[string]string{"plugin": "socket"}),
+					},
+				},
+			},
+			Annotations: map[string]map[string]map[string]string{
+				"process": {report.MakeProcessNodeID(request.HostID, "1"): {"socket": "yes"}},
+			},
+		})
+	}))
+
+	registry := plugins.NewRegistry(root, "host", 500*time.Millisecond)
+	start := time.Now()
+	have, err := registry.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("slow plugin wasn't timed out: took %s", took)
+	}
+
+	want := report.MakeReport()
+	want.Container.NodeMetadatas[report.MakeContainerNodeID("host", "abc")] = report.MakeNodeMetadataWith(map[string]string{"plugin": "exec"})
+	want.Host.NodeMetadatas[report.MakeHostNodeID("host")] = report.MakeNodeMetadataWith(map[string]string{"plugin": "socket"})
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	r := report.MakeReport()
+	r.Process.NodeMetadatas[report.MakeProcessNodeID("host", "1")] = report.MakeNodeMetadataWith(map[string]string{"pid": "1"})
+	r, err = registry.Tag(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantProcesses := report.NodeMetadatas{
+		report.MakeProcessNodeID("host", "1"): report.MakeNodeMetadataWith(map[string]string{
+			"pid":    "1",
+			"exec":   "yes",
+			"socket": "yes",
+		}),
+	}
+	if !reflect.DeepEqual(wantProcesses, r.Process.NodeMetadatas) {
+		t.Error(test.Diff(wantProcesses, r.Process.NodeMetadatas))
+	}
+}
+
+func TestRegistryNoRoot(t *testing.T) {
+	have, err := plugins.NewRegistry("/does/not/exist", "host", time.Second).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := report.MakeReport(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/scope/probe/cgroup"
+	"github.com/weaveworks/scope/probe/container"
+	"github.com/weaveworks/scope/probe/cri"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/filter"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/overlay"
+	"github.com/weaveworks/scope/probe/plugins"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/probe/sniff"
+	"github.com/weaveworks/scope/probe/systemd"
+	"github.com/weaveworks/scope/probe/systemd/units"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/xfer"
+)
+
+// probe is the reporters, taggers and publishers a config calls for.
+// Applying a new config only restarts the components whose settings
+// changed, so the others keep their state.
+type probe struct {
+	hostID       string
+	hostName     string
+	procRoot     string
+	localNets    report.Networks
+	processCache *process.CachingWalker
+	taggers      []Tagger
+	reporters    []*timedReporter
+
+	mtx        sync.Mutex
+	config     config
+	publishers *xfer.MultiPublisher
+	resolver   staticResolver
+	components map[string]*component
+}
+
+// component is an optional part of the probe, started from its settings.
+type component struct {
+	settings  string
+	reporters []Reporter
+	taggers   []Tagger
+	stop      func()
+
+	timed []*timedReporter
+}
+
+// components are the optional parts of the probe, in the order their
+// taggers run. The settings of each are the parts of the config which, if
+// they change, mean it has to be restarted.
+var components = []struct {
+	name     string
+	settings func(config) interface{}
+	start    func(*probe, config) (*component, error)
+}{
+	{"endpoint", func(c config) interface{} { return c.Processes }, (*probe).startEndpoint},
+	{"services", func(c config) interface{} { return c.Services }, (*probe).startServices},
+	{"docker", func(c config) interface{} { return []interface{}{c.Docker, c.Cgroup.Root} }, (*probe).startDocker},
+	{"cri", func(c config) interface{} { return c.CRI }, (*probe).startCRI},
+	{"cgroup", func(c config) interface{} { return c.Cgroup }, (*probe).startCgroup},
+	{"systemd", func(c config) interface{} { return []interface{}{c.Systemd, c.Cgroup.Root} }, (*probe).startSystemd},
+	{"kubernetes", func(c config) interface{} { return c.Kubernetes }, (*probe).startKubernetes},
+	{"weave", func(c config) interface{} { return c.Weave }, (*probe).startWeave},
+	{"plugins", func(c config) interface{} { return c.Plugins }, (*probe).startPlugins},
+	{"capture", func(c config) interface{} { return c.Capture }, (*probe).startCapture},
+	{"host", func(c config) interface{} { return c.Host }, (*probe).startHost},
+	{"filter", func(c config) interface{} { return c.Filter }, (*probe).startFilter},
+}
+
+func newProbe(hostID, hostName, procRoot string, localNets report.Networks) *probe {
+	processCache := process.NewCachingWalker(process.NewWalker(procRoot))
+	return &probe{
+		hostID:       hostID,
+		hostName:     hostName,
+		procRoot:     procRoot,
+		localNets:    localNets,
+		processCache: processCache,
+		taggers:      []Tagger{newTopologyTagger()},
+		reporters: []*timedReporter{
+			newTimedReporter("host", host.NewReporter(hostID, hostName, localNets)),
+			newTimedReporter("process", process.NewReporter(processCache, hostID)),
+		},
+		components: map[string]*component{},
+	}
+}
+
+// apply starts the components the config calls for, and stops the ones it
+// doesn't. If any component fails to start, nothing changes.
+func (p *probe) apply(c config) error {
+	started := map[string]*component{}
+	for _, def := range components {
+		settings, err := json.Marshal(def.settings(c))
+		if err != nil {
+			return err
+		}
+		if old, ok := p.components[def.name]; ok && old.settings == string(settings) {
+			continue
+		}
+		comp, err := def.start(p, c)
+		if err != nil {
+			for _, s := range started {
+				s.close()
+			}
+			return fmt.Errorf("failed to start %s: %v", def.name, err)
+		}
+		comp.settings = string(settings)
+		for _, reporter := range comp.reporters {
+			comp.timed = append(comp.timed, newTimedReporter(def.name, reporter))
+		}
+		started[def.name] = comp
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for name, comp := range started {
+		if old, ok := p.components[name]; ok {
+			log.Printf("config: restarting %s", name)
+			old.close()
+		}
+		p.components[name] = comp
+	}
+
+	if p.publishers == nil || !reflect.DeepEqual(c.Targets, p.config.Targets) || c.Token != p.config.Token {
+		if p.publishers != nil {
+			p.resolver.Stop()
+		}
+		token := c.Token
+		p.publishers = xfer.NewMultiPublisher(func(target string) (xfer.Publisher, error) {
+			return xfer.NewHTTPPublisher(target, token)
+		})
+		p.resolver = newStaticResolver(c.Targets, p.publishers.Add)
+		log.Printf("publishing to: %s", strings.Join(c.Targets, ", "))
+	}
+
+	p.config = c
+	return nil
+}
+
+// stop stops all the components and publishers.
+func (p *probe) stop() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for _, comp := range p.components {
+		comp.close()
+	}
+	if p.publishers != nil {
+		p.resolver.Stop()
+	}
+}
+
+// spy adds a report from every reporter to r, and tags it. The reporters
+// run concurrently, and any that take longer than the reporter timeout are
+// left out until their report is ready.
+func (p *probe) spy(r report.Report) report.Report {
+	p.mtx.Lock()
+	timeout := time.Duration(p.config.ReporterTimeout)
+	reporters, taggers := append([]*timedReporter{}, p.reporters...), append([]Tagger{}, p.taggers...)
+	for _, def := range components {
+		if comp, ok := p.components[def.name]; ok {
+			reporters = append(reporters, comp.timed...)
+			taggers = append(taggers, comp.taggers...)
+		}
+	}
+	p.mtx.Unlock()
+
+	if err := p.processCache.Update(); err != nil {
+		log.Printf("error reading processes: %v", err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		reports = make([]report.Report, len(reporters))
+	)
+	for i, reporter := range reporters {
+		wg.Add(1)
+		go func(i int, reporter *timedReporter) {
+			defer wg.Done()
+			newReport, err := reporter.reportWithin(timeout)
+			if err != nil {
+				log.Printf("error generating %s report: %v", reporter.name, err)
+			}
+			reports[i] = newReport
+		}(i, reporter)
+	}
+	wg.Wait()
+	for _, newReport := range reports {
+		r.Merge(newReport)
+	}
+	return Apply(r, taggers)
+}
+
+// publish publishes r to all the targets.
+func (p *probe) publish(r report.Report) error {
+	p.mtx.Lock()
+	publishers := p.publishers
+	p.mtx.Unlock()
+	return publishers.Publish(r)
+}
+
+// ServeHTTP shows the config in effect, as JSON, without the token.
+func (p *probe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mtx.Lock()
+	c := p.config
+	p.mtx.Unlock()
+	if c.Token != "" {
+		c.Token = "<redacted>"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c); err != nil {
+		log.Printf("config: %v", err)
+	}
+}
+
+func (c *component) close() {
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+// startHost starts the host tagger, which tags every node with this host,
+// and its labels. It comes after the other taggers, as some of them, such
+// as the container, systemd and Kubernetes taggers, add nodes.
+func (p *probe) startHost(c config) (*component, error) {
+	labels := host.NewLabelLoader(c.Host.Labels, c.Host.LabelsFile, c.Host.MetadataURL, time.Duration(c.Host.LabelsInterval))
+	return &component{
+		taggers: []Tagger{host.NewTagger(p.hostID, p.hostName, labels.Labels)},
+		stop:    labels.Stop,
+	}, nil
+}
+
+func (p *probe) startEndpoint(c config) (*component, error) {
+	return &component{
+		reporters: []Reporter{endpoint.NewReporter(p.hostID, p.hostName, p.procRoot, c.Processes)},
+	}, nil
+}
+
+func (p *probe) startServices(c config) (*component, error) {
+	tagger, err := process.NewServiceTagger(p.procRoot, c.Services)
+	if err != nil {
+		return nil, err
+	}
+	return &component{taggers: []Tagger{tagger}}, nil
+}
+
+func (p *probe) startDocker(c config) (*component, error) {
+	if !c.Docker.Enabled {
+		return &component{}, nil
+	}
+	if err := report.AddLocalBridge(c.Docker.Bridge); err != nil {
+		return nil, fmt.Errorf("failed to get docker bridge address: %v", err)
+	}
+
+	var cgroups *cgroup.Reader
+	if c.Docker.Stats == "cgroup" {
+		cgroups = cgroup.NewReader(c.Cgroup.Root, p.procRoot)
+	}
+
+	dockerEndpoint := docker.Endpoint{
+		Host:      c.Docker.Host,
+		CertPath:  c.Docker.CertPath,
+		TLSVerify: c.Docker.TLSVerify,
+	}
+	dockerRegistry, err := docker.NewRegistry(dockerEndpoint, time.Duration(c.Docker.Interval), cgroups)
+	if err != nil {
+		return nil, err
+	}
+	return &component{
+		reporters: []Reporter{docker.NewReporter(dockerRegistry, p.hostID)},
+		taggers:   []Tagger{docker.NewTagger(dockerRegistry, p.processCache)},
+		stop:      dockerRegistry.Stop,
+	}, nil
+}
+
+func (p *probe) startCRI(c config) (*component, error) {
+	if !c.CRI.Enabled {
+		return &component{}, nil
+	}
+	criRegistry, err := cri.NewRegistry(c.CRI.Endpoint, time.Duration(c.CRI.Interval))
+	if err != nil {
+		return nil, err
+	}
+	return &component{
+		reporters: []Reporter{docker.NewReporter(criRegistry, p.hostID)},
+		taggers:   []Tagger{docker.NewTagger(criRegistry, p.processCache)},
+		stop:      criRegistry.Stop,
+	}, nil
+}
+
+func (p *probe) startCgroup(c config) (*component, error) {
+	if !c.Cgroup.Containers {
+		return &component{}, nil
+	}
+	patterns := container.Patterns{}
+	for _, pattern := range c.Cgroup.Patterns {
+		if err := patterns.Set(pattern); err != nil {
+			return nil, err
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = container.DefaultPatterns
+	}
+	return &component{
+		taggers: []Tagger{container.NewTagger(cgroup.NewReader(c.Cgroup.Root, p.procRoot), patterns, p.hostID)},
+	}, nil
+}
+
+func (p *probe) startSystemd(c config) (*component, error) {
+	if !c.Systemd.Enabled {
+		return &component{}, nil
+	}
+	comp := &component{}
+	var unitList systemd.Units
+	if c.Systemd.DBus {
+		conn, err := units.New()
+		if err != nil {
+			log.Printf("warning: failed to connect to systemd: %v", err)
+		} else {
+			unitList, comp.stop = conn, conn.Close
+		}
+	}
+	comp.taggers = []Tagger{systemd.NewTagger(cgroup.NewReader(c.Cgroup.Root, p.procRoot), unitList, p.hostID)}
+	return comp, nil
+}
+
+func (p *probe) startKubernetes(c config) (*component, error) {
+	if !c.Kubernetes.Enabled {
+		return &component{}, nil
+	}
+	kubernetesRegistry, err := kubernetes.NewRegistry(c.Kubernetes.API, time.Duration(c.Kubernetes.Interval))
+	if err != nil {
+		return nil, err
+	}
+	return &component{
+		reporters: []Reporter{kubernetes.NewReporter(kubernetesRegistry)},
+		taggers:   []Tagger{kubernetes.NewTagger(kubernetesRegistry, p.hostID)},
+		stop:      kubernetesRegistry.Stop,
+	}, nil
+}
+
+func (p *probe) startWeave(c config) (*component, error) {
+	if c.Weave.RouterAddr == "" {
+		return &component{}, nil
+	}
+	weave, err := overlay.NewWeave(p.hostID, c.Weave.RouterAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &component{
+		reporters: []Reporter{weave},
+		taggers:   []Tagger{weave},
+	}, nil
+}
+
+func (p *probe) startPlugins(c config) (*component, error) {
+	if c.Plugins.Root == "" {
+		return &component{}, nil
+	}
+	pluginRegistry := plugins.NewRegistry(c.Plugins.Root, p.hostID, time.Duration(c.Plugins.Timeout))
+	return &component{
+		reporters: []Reporter{pluginRegistry},
+		taggers:   []Tagger{pluginRegistry},
+	}, nil
+}
+
+func (p *probe) startCapture(c config) (*component, error) {
+	if !c.Capture.Enabled {
+		return &component{}, nil
+	}
+	var (
+		comp    = &component{}
+		sources = []sniff.Source{}
+	)
+	for _, iface := range strings.Split(c.Capture.Interfaces, ",") {
+		source, err := sniff.NewSource(iface)
+		if err != nil {
+			log.Printf("warning: %v", err)
+			continue
+		}
+		log.Printf("capturing packets on %s", iface)
+		comp.reporters = append(comp.reporters, sniff.New(p.hostID, p.localNets, source, time.Duration(c.Capture.On), time.Duration(c.Capture.Off)))
+		sources = append(sources, source)
+	}
+	comp.stop = func() {
+		for _, source := range sources {
+			source.Close()
+		}
+	}
+	// Packet capture can block OS threads on Linux, so we need to provide
+	// sufficient overhead in GOMAXPROCS.
+	if have, want := runtime.GOMAXPROCS(-1), (len(sources) + 1); have < want {
+		runtime.GOMAXPROCS(want)
+	}
+	return comp, nil
+}
+
+// startFilter starts the filter, which has to be the last tagger, so it sees
+// everything the others add.
+func (p *probe) startFilter(c config) (*component, error) {
+	f, err := filter.New(c.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return &component{taggers: []Tagger{f}}, nil
+}
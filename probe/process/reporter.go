@@ -2,6 +2,7 @@ package process
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/weaveworks/scope/report"
 )
@@ -13,6 +14,17 @@ const (
 	PPID    = "ppid"
 	Cmdline = "cmdline"
 	Threads = "threads"
+
+	Exe       = "exe"
+	UID       = "uid"
+	User      = "user"
+	StartTime = "start_time"        // RFC3339
+	CPUUsage  = "cpu_usage_percent" // of a single CPU
+	RSS       = "rss_bytes"
+	VSZ       = "vsz_bytes"
+	OpenFiles = "open_files"
+
+	ServiceName = "service_name" // set by ServiceTagger
 )
 
 // Reporter generates Reports containing the Process topology.
@@ -54,7 +66,37 @@ func (r *Reporter) processTopology() (report.Topology, error) {
 		if p.PPID > 0 {
 			t.NodeMetadatas[nodeID].Metadata[PPID] = strconv.Itoa(p.PPID)
 		}
+		addResources(t.NodeMetadatas[nodeID], p)
 	})
 
 	return t, err
 }
+
+// addResources adds whatever the walker found out about the resources the
+// process uses.
+func addResources(md report.NodeMetadata, p Process) {
+	for key, value := range map[string]string{
+		Exe:  p.Exe,
+		UID:  p.UID,
+		User: p.User,
+	} {
+		if value != "" {
+			md.Metadata[key] = value
+		}
+	}
+	if !p.StartTime.IsZero() {
+		md.Metadata[StartTime] = p.StartTime.UTC().Format(time.RFC3339)
+	}
+	if p.HasCPUUsage {
+		md.Metadata[CPUUsage] = strconv.FormatFloat(p.CPUUsage, 'f', 2, 64)
+	}
+	if p.RSS > 0 {
+		md.Metadata[RSS] = strconv.FormatUint(p.RSS, 10)
+	}
+	if p.VSZ > 0 {
+		md.Metadata[VSZ] = strconv.FormatUint(p.VSZ, 10)
+	}
+	if p.OpenFiles > 0 {
+		md.Metadata[OpenFiles] = strconv.Itoa(p.OpenFiles)
+	}
+}
@@ -3,6 +3,7 @@ package process_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/weaveworks/scope/probe/process"
 	"github.com/weaveworks/scope/report"
@@ -27,6 +28,12 @@ func TestReporter(t *testing.T) {
 			{PID: 2, PPID: 1, Comm: "bash"},
 			{PID: 3, PPID: 1, Comm: "apache", Threads: 2},
 			{PID: 4, PPID: 2, Comm: "ping", Cmdline: "ping foo.bar.local"},
+			{
+				PID: 5, PPID: 1, Comm: "nginx", Threads: 1,
+				Exe: "/usr/sbin/nginx", UID: "33", User: "www-data",
+				StartTime: time.Unix(1440000000, 0), CPUTime: time.Second, CPUUsage: 12.5, HasCPUUsage: true,
+				RSS: 1 << 20, VSZ: 1 << 24, OpenFiles: 12,
+			},
 		},
 	}
 
@@ -63,6 +70,21 @@ func TestReporter(t *testing.T) {
 				process.Cmdline: "ping foo.bar.local",
 				process.Threads: "0",
 			}),
+			report.MakeProcessNodeID("", "5"): report.MakeNodeMetadataWith(map[string]string{
+				process.PID:       "5",
+				process.Comm:      "nginx",
+				process.PPID:      "1",
+				process.Cmdline:   "",
+				process.Threads:   "1",
+				process.Exe:       "/usr/sbin/nginx",
+				process.UID:       "33",
+				process.User:      "www-data",
+				process.StartTime: "2015-08-19T16:00:00Z",
+				process.CPUUsage:  "12.50",
+				process.RSS:       "1048576",
+				process.VSZ:       "16777216",
+				process.OpenFiles: "12",
+			}),
 		},
 	}
 
@@ -71,3 +93,49 @@ func TestReporter(t *testing.T) {
 		t.Errorf("%s (%v)", test.Diff(want, have), err)
 	}
 }
+
+func TestReporterCPUUsage(t *testing.T) {
+	oldNow := process.Now
+	defer func() { process.Now = oldNow }()
+	now := time.Unix(1440000000, 0)
+	process.Now = func() time.Time { return now }
+
+	var (
+		started = time.Unix(1430000000, 0)
+		walker  = &mockWalker{processes: []process.Process{
+			{PID: 1, StartTime: started, CPUTime: 10 * time.Second},
+			{PID: 2, StartTime: started},
+		}}
+		cachingWalker = process.NewCachingWalker(walker)
+		reporter      = process.NewReporter(cachingWalker, "")
+		nodeID        = func(pid string) string { return report.MakeProcessNodeID("", pid) }
+	)
+
+	// On the first walk, we don't know how much CPU anything is using.
+	if err := cachingWalker.Update(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pid := range []string{"1", "2"} {
+		if usage, ok := r.Process.NodeMetadatas[nodeID(pid)].Metadata[process.CPUUsage]; ok {
+			t.Errorf("%s: want no CPU usage, have %q", pid, usage)
+		}
+	}
+
+	// On the next, we do, even if it's none at all.
+	now = now.Add(10 * time.Second)
+	if err := cachingWalker.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = reporter.Report(); err != nil {
+		t.Fatal(err)
+	}
+	for pid, want := range map[string]string{"1": "0.00", "2": "0.00"} {
+		if have := r.Process.NodeMetadatas[nodeID(pid)].Metadata[process.CPUUsage]; want != have {
+			t.Errorf("%s: want CPU usage %q, have %q", pid, want, have)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// ReadEnviron reads the environment of a process, as in /proc/<pid>/environ.
+// Exposed for mocking.
+var ReadEnviron = func(procRoot, pid string) ([]byte, error) {
+	return ioutil.ReadFile(path.Join(procRoot, pid, "environ"))
+}
+
+// ServiceRule is a rule for naming the service a process is part of. A rule
+// with an Env takes the value of that environment variable of the process.
+// A rule with a Cmdline takes the first submatch, or failing that the
+// whole match, of that regexp in the process' command line.
+type ServiceRule struct {
+	Env     string `json:"env,omitempty"`
+	Cmdline string `json:"cmdline,omitempty"`
+}
+
+// DefaultServiceRules name processes by their SERVICE_NAME environment
+// variable, or failing that, by the jar a Java process runs (without its
+// version), or the script an interpreter runs.
+var DefaultServiceRules = []ServiceRule{
+	{Env: "SERVICE_NAME"},
+	{Cmdline: `\s-jar\s+(?:\S*/)?([^\s/]+?)(?:-[0-9][^\s/]*)?\.jar(?:\s|$)`},
+	{Cmdline: `^(?:\S*/)?(?:python[0-9.]*|node|nodejs|ruby|perl|php|bash|sh)\s+(?:-\S+\s+)*(?:\S*/)?([^\s/]+?)\.(?:py|js|rb|pl|php|sh)(?:\s|$)`},
+}
+
+type serviceRule struct {
+	env     string
+	cmdline *regexp.Regexp
+}
+
+// ServiceTagger tags processes with the name of the service they're part
+// of, from the first of its rules which names them, so processes running
+// the same interpreter or VM can be told apart. Processes no rule names
+// aren't tagged.
+type ServiceTagger struct {
+	procRoot string
+	rules    []serviceRule
+	envs     []string
+
+	mtx     sync.Mutex
+	environ map[string]environ // by node ID
+}
+
+// environ is the variables of a process' environment the rules need. The
+// environment a process started with doesn't change, so it's only read
+// once per process.
+type environ struct {
+	startTime string
+	vars      map[string]string
+}
+
+// NewServiceTagger returns a ServiceTagger with the rules, or an error if
+// any of them are invalid.
+func NewServiceTagger(procRoot string, rules []ServiceRule) (*ServiceTagger, error) {
+	t := &ServiceTagger{
+		procRoot: procRoot,
+		environ:  map[string]environ{},
+	}
+	for _, rule := range rules {
+		switch {
+		case rule.Env != "" && rule.Cmdline == "":
+			t.rules = append(t.rules, serviceRule{env: rule.Env})
+			t.envs = append(t.envs, rule.Env)
+		case rule.Cmdline != "" && rule.Env == "":
+			re, err := regexp.Compile(rule.Cmdline)
+			if err != nil {
+				return nil, err
+			}
+			t.rules = append(t.rules, serviceRule{cmdline: re})
+		default:
+			return nil, fmt.Errorf("service rule needs one of env and cmdline")
+		}
+	}
+	return t, nil
+}
+
+// Tag implements Tagger.
+func (t *ServiceTagger) Tag(r report.Report) (report.Report, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	seen := map[string]struct{}{}
+	for nodeID, nmd := range r.Process.NodeMetadatas {
+		pid, ok := nmd.Metadata[PID]
+		if !ok {
+			continue
+		}
+		seen[nodeID] = struct{}{}
+		if name := t.name(nodeID, pid, nmd); name != "" {
+			nmd.Metadata[ServiceName] = name
+		}
+	}
+	for nodeID := range t.environ {
+		if _, ok := seen[nodeID]; !ok {
+			delete(t.environ, nodeID)
+		}
+	}
+	return r, nil
+}
+
+func (t *ServiceTagger) name(nodeID, pid string, nmd report.NodeMetadata) string {
+	for _, rule := range t.rules {
+		if rule.cmdline != nil {
+			match := rule.cmdline.FindStringSubmatch(nmd.Metadata[Cmdline])
+			if match == nil {
+				continue
+			}
+			if len(match) > 1 && match[1] != "" {
+				return match[1]
+			}
+			return strings.TrimSpace(match[0])
+		}
+		if value := t.env(nodeID, pid, nmd)[rule.env]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// env returns the variables of the process' environment the rules need.
+// Must be called with the lock held.
+func (t *ServiceTagger) env(nodeID, pid string, nmd report.NodeMetadata) map[string]string {
+	startTime := nmd.Metadata[StartTime]
+	if e, ok := t.environ[nodeID]; ok && e.startTime == startTime {
+		return e.vars
+	}
+	e := environ{startTime: startTime, vars: map[string]string{}}
+	if buf, err := ReadEnviron(t.procRoot, pid); err == nil {
+		for _, entry := range bytes.Split(buf, []byte{0}) {
+			parts := strings.SplitN(string(entry), "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			for _, name := range t.envs {
+				if parts[0] == name {
+					e.vars[name] = parts[1]
+				}
+			}
+		}
+	}
+	t.environ[nodeID] = e
+	return e.vars
+}
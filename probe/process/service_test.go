@@ -0,0 +1,83 @@
+package process_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestServiceTagger(t *testing.T) {
+	oldReadEnviron := process.ReadEnviron
+	defer func() { process.ReadEnviron = oldReadEnviron }()
+
+	reads := 0
+	process.ReadEnviron = func(procRoot, pid string) ([]byte, error) {
+		reads++
+		switch pid {
+		case "1", "2":
+			return []byte("PATH=/bin\x00SERVICE_NAME=billing\x00HOME=/\x00"), nil
+		}
+		return nil, fmt.Errorf("no such process")
+	}
+
+	tagger, err := process.NewServiceTagger("/proc", process.DefaultServiceRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmdlines := map[string]string{
+		"1": "java -jar /opt/billing/billing-2.1.0.jar ",
+		"2": "python worker.py ",
+		"3": "java -Xmx1g -jar /opt/orders/order-service-1.4.2-SNAPSHOT.jar --port 8080 ",
+		"4": "/usr/bin/python3 -u /srv/app/scheduler.py --once ",
+		"5": "node server.js ",
+		"6": "java -cp app.jar com.example.Main ",
+		"7": "nginx: worker process",
+	}
+	want := map[string]string{
+		"1": "billing",
+		"2": "billing",
+		"3": "order-service",
+		"4": "scheduler",
+		"5": "server",
+	}
+
+	r := report.MakeReport()
+	for pid, cmdline := range cmdlines {
+		r.Process.NodeMetadatas[report.MakeProcessNodeID("host", pid)] = report.MakeNodeMetadataWith(map[string]string{
+			process.PID:     pid,
+			process.Cmdline: cmdline,
+		})
+	}
+	for i := 0; i < 2; i++ {
+		r, err = tagger.Tag(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for pid := range cmdlines {
+		have, ok := r.Process.NodeMetadatas[report.MakeProcessNodeID("host", pid)].Metadata[process.ServiceName]
+		if want, wantOK := want[pid]; want != have || wantOK != ok {
+			t.Errorf("%s: want %q, have %q", cmdlines[pid], want, have)
+		}
+	}
+
+	// Environments are only read once per process.
+	if want := len(cmdlines); reads != want {
+		t.Errorf("want %d reads, have %d", want, reads)
+	}
+}
+
+func TestServiceRulesInvalid(t *testing.T) {
+	for _, rules := range [][]process.ServiceRule{
+		{{}},
+		{{Env: "SERVICE_NAME", Cmdline: "foo"}},
+		{{Cmdline: "("}},
+	} {
+		if _, err := process.NewServiceTagger("/proc", rules); err == nil {
+			t.Errorf("%+v: expected an error", rules)
+		}
+	}
+}
@@ -1,26 +1,52 @@
 package process
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
-// Process represents a single process.
+// Process represents a single process. Fields other than the first few are
+// only filled in by walkers which can find them, and are zero otherwise.
 type Process struct {
 	PID, PPID int
 	Comm      string
 	Cmdline   string
 	Threads   int
+
+	Exe       string        // path of the executable
+	UID       string        // real user ID
+	User      string        // name of UID
+	StartTime time.Time     // when the process started
+	CPUTime   time.Duration // user + system time used, cumulatively
+	CPUUsage  float64       // % of a CPU used since the previous walk; set by CachingWalker
+	RSS       uint64        // resident set size, in bytes
+	VSZ       uint64        // virtual memory size, in bytes
+	OpenFiles int           // number of open file descriptors
+
+	// HasCPUUsage is set when CPUUsage is known, i.e. when CachingWalker
+	// saw the process on its previous walk too.
+	HasCPUUsage bool
 }
 
+// Now is the clock used by CachingWalker, exposed for mocking.
+var Now = time.Now
+
 // Walker is something that walks the /proc directory
 type Walker interface {
 	Walk(func(Process)) error
 }
 
 // CachingWalker is a walker than caches a copy of the output from another
-// Walker, and then allows other concurrent readers to Walk that copy.
+// Walker, and then allows other concurrent readers to Walk that copy. It
+// also turns each process' CPU time into a rate, between Updates.
 type CachingWalker struct {
 	cache     []Process
 	cacheLock sync.RWMutex
 	source    Walker
+
+	// Only accessed by Update
+	previous     map[int]Process
+	previousTime time.Time
 }
 
 // NewCachingWalker returns a new CachingWalker
@@ -41,13 +67,25 @@ func (c *CachingWalker) Walk(f func(Process)) error {
 
 // Update updates cached copy of process list
 func (c *CachingWalker) Update() error {
-	newCache := []Process{}
+	var (
+		now      = Now()
+		elapsed  = now.Sub(c.previousTime)
+		newCache = []Process{}
+		current  = map[int]Process{}
+	)
 	err := c.source.Walk(func(p Process) {
+		// A different start time means the PID has been reused.
+		if prev, ok := c.previous[p.PID]; ok && prev.StartTime.Equal(p.StartTime) && elapsed > 0 {
+			p.CPUUsage = 100 * float64(p.CPUTime-prev.CPUTime) / float64(elapsed)
+			p.HasCPUUsage = true
+		}
 		newCache = append(newCache, p)
+		current[p.PID] = p
 	})
 	if err != nil {
 		return err
 	}
+	c.previous, c.previousTime = current, now
 
 	c.cacheLock.Lock()
 	defer c.cacheLock.Unlock()
@@ -3,17 +3,32 @@ package process
 import (
 	"bytes"
 	"io/ioutil"
+	"os"
+	"os/user"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Hooks exposed for mocking
 var (
-	ReadDir  = ioutil.ReadDir
-	ReadFile = ioutil.ReadFile
+	ReadDir    = ioutil.ReadDir
+	ReadFile   = ioutil.ReadFile
+	Readlink   = os.Readlink
+	LookupUser = func(uid string) (string, error) {
+		u, err := user.LookupId(uid)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	}
 )
 
+// clockTicks is the unit of the times in /proc/<pid>/stat (USER_HZ), which
+// is 100 on every architecture we care about.
+const clockTicks = 100
+
 type walker struct {
 	procRoot string
 }
@@ -33,6 +48,11 @@ func (w *walker) Walk(f func(Process)) error {
 		return err
 	}
 
+	var (
+		bootTime = w.bootTime()
+		users    = map[string]string{}
+		pageSize = uint64(os.Getpagesize())
+	)
 	for _, dirEntry := range dirEntries {
 		filename := dirEntry.Name()
 		pid, err := strconv.Atoi(filename)
@@ -44,13 +64,23 @@ func (w *walker) Walk(f func(Process)) error {
 		if err != nil {
 			continue
 		}
-		splits := strings.Fields(string(stat))
-		ppid, err := strconv.Atoi(splits[3])
+		// The command name in stat is in parentheses, and can itself
+		// contain spaces and parentheses, so we split the fields after the
+		// last ")". splits[0] is the state, the third field in proc(5).
+		end := bytes.LastIndexByte(stat, ')')
+		if end < 0 {
+			continue
+		}
+		splits := strings.Fields(string(stat[end+1:]))
+		if len(splits) < 18 {
+			continue
+		}
+		ppid, err := strconv.Atoi(splits[1])
 		if err != nil {
 			return err
 		}
 
-		threads, err := strconv.Atoi(splits[19])
+		threads, err := strconv.Atoi(splits[17])
 		if err != nil {
 			return err
 		}
@@ -66,14 +96,78 @@ func (w *walker) Walk(f func(Process)) error {
 			comm = strings.TrimSpace(string(commBuf))
 		}
 
-		f(Process{
+		p := Process{
 			PID:     pid,
 			PPID:    ppid,
 			Comm:    comm,
 			Cmdline: cmdline,
 			Threads: threads,
-		})
+		}
+
+		// Resource usage; see proc(5) for the fields of stat.
+		if len(splits) > 21 {
+			utime, _ := strconv.ParseUint(splits[11], 10, 64)
+			stime, _ := strconv.ParseUint(splits[12], 10, 64)
+			p.CPUTime = time.Duration(utime+stime) * time.Second / clockTicks
+			if starttime, err := strconv.ParseUint(splits[19], 10, 64); err == nil && !bootTime.IsZero() {
+				p.StartTime = bootTime.Add(time.Duration(starttime) * time.Second / clockTicks)
+			}
+			p.VSZ, _ = strconv.ParseUint(splits[20], 10, 64)
+			if rss, err := strconv.ParseUint(splits[21], 10, 64); err == nil {
+				p.RSS = rss * pageSize
+			}
+		}
+
+		if fds, err := ReadDir(path.Join(w.procRoot, filename, "fd")); err == nil {
+			p.OpenFiles = len(fds)
+		}
+
+		if exe, err := Readlink(path.Join(w.procRoot, filename, "exe")); err == nil {
+			p.Exe = exe
+		}
+
+		if uid, ok := w.uid(filename); ok {
+			if _, ok := users[uid]; !ok {
+				users[uid], _ = LookupUser(uid)
+			}
+			p.UID, p.User = uid, users[uid]
+		}
+
+		f(p)
 	}
 
 	return nil
 }
+
+// bootTime reads the time the system booted, which process start times are
+// relative to, from /proc/stat. It returns the zero time if it can't.
+func (w *walker) bootTime() time.Time {
+	buf, err := ReadFile(path.Join(w.procRoot, "stat"))
+	if err != nil {
+		return time.Time{}
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			if btime, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				return time.Unix(btime, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// uid reads the real user ID of a process from /proc/<pid>/status.
+func (w *walker) uid(pid string) (string, bool) {
+	buf, err := ReadFile(path.Join(w.procRoot, pid, "status"))
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[0] == "Uid:" {
+			return fields[1], true
+		}
+	}
+	return "", false
+}
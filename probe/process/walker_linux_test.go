@@ -40,6 +40,9 @@ func TestWalker(t *testing.T) {
 	}
 
 	process.ReadDir = func(path string) ([]os.FileInfo, error) {
+		if path != "unused" {
+			return nil, fmt.Errorf("not found")
+		}
 		result := []os.FileInfo{}
 		for _, p := range processes {
 			result = append(result, p)
@@ -63,7 +66,7 @@ func TestWalker(t *testing.T) {
 		case "stat":
 			pid, _ := strconv.Atoi(splits[len(splits)-2])
 			parent := pid - 1
-			return []byte(fmt.Sprintf("%d na R %d 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1", pid, parent)), nil
+			return []byte(fmt.Sprintf("%d (na) R %d 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1", pid, parent)), nil
 		case "cmdline":
 			return []byte(process.cmdline), nil
 		}
@@ -88,3 +91,85 @@ func TestWalker(t *testing.T) {
 		t.Errorf("%v (%v)", test.Diff(want, have), err)
 	}
 }
+
+func TestWalkerResources(t *testing.T) {
+	oldReadDir, oldReadFile, oldReadlink, oldLookupUser := process.ReadDir, process.ReadFile, process.Readlink, process.LookupUser
+	defer func() {
+		process.ReadDir = oldReadDir
+		process.ReadFile = oldReadFile
+		process.Readlink = oldReadlink
+		process.LookupUser = oldLookupUser
+	}()
+
+	process.ReadDir = func(path string) ([]os.FileInfo, error) {
+		switch path {
+		case "/proc":
+			return []os.FileInfo{mockProcess{name: "42"}}, nil
+		case "/proc/42/fd":
+			return []os.FileInfo{mockProcess{name: "0"}, mockProcess{name: "1"}, mockProcess{name: "2"}}, nil
+		}
+		return nil, fmt.Errorf("not found")
+	}
+	process.ReadFile = func(path string) ([]byte, error) {
+		switch path {
+		case "/proc/stat":
+			return []byte("cpu  1 2 3 4\nbtime 1440000000\nprocesses 42\n"), nil
+		case "/proc/42/stat":
+			return []byte("42 (apache) S 1 42 42 0 -1 4194560 100 0 0 0 150 50 0 0 20 0 4 0 1000 104857600 2560 18446744073709551615"), nil
+		case "/proc/42/comm":
+			return []byte("apache\n"), nil
+		case "/proc/42/status":
+			return []byte("Name:\tapache\nState:\tS (sleeping)\nUid:\t33\t33\t33\t33\nGid:\t33\t33\t33\t33\n"), nil
+		}
+		return nil, fmt.Errorf("not found")
+	}
+	process.Readlink = func(path string) (string, error) {
+		if path == "/proc/42/exe" {
+			return "/usr/sbin/apache2", nil
+		}
+		return "", fmt.Errorf("not found")
+	}
+	process.LookupUser = func(uid string) (string, error) {
+		if uid == "33" {
+			return "www-data", nil
+		}
+		return "", fmt.Errorf("unknown user %s", uid)
+	}
+
+	want := []process.Process{{
+		PID:       42,
+		PPID:      1,
+		Comm:      "apache",
+		Threads:   4,
+		Exe:       "/usr/sbin/apache2",
+		UID:       "33",
+		User:      "www-data",
+		StartTime: time.Unix(1440000010, 0),
+		CPUTime:   2 * time.Second,
+		RSS:       2560 * uint64(os.Getpagesize()),
+		VSZ:       104857600,
+		OpenFiles: 3,
+	}}
+	have, err := all(process.NewWalker("/proc"))
+	if err != nil || !reflect.DeepEqual(want, have) {
+		t.Errorf("%v (%v)", test.Diff(want, have), err)
+	}
+
+	// A command name with spaces and parentheses doesn't shift the fields
+	// after it.
+	readFile := process.ReadFile
+	process.ReadFile = func(path string) ([]byte, error) {
+		switch path {
+		case "/proc/42/stat":
+			return []byte("42 (tmux: server) (x) S 1 42 42 0 -1 4194560 100 0 0 0 150 50 0 0 20 0 4 0 1000 104857600 2560 18446744073709551615"), nil
+		case "/proc/42/comm":
+			return []byte("tmux: server) (x\n"), nil
+		}
+		return readFile(path)
+	}
+	want[0].Comm = "tmux: server) (x"
+	have, err = all(process.NewWalker("/proc"))
+	if err != nil || !reflect.DeepEqual(want, have) {
+		t.Errorf("%v (%v)", test.Diff(want, have), err)
+	}
+}
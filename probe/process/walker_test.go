@@ -3,6 +3,7 @@ package process_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/weaveworks/scope/probe/process"
 	"github.com/weaveworks/scope/test"
@@ -57,6 +58,45 @@ func TestCache(t *testing.T) {
 	}
 }
 
+func TestCacheCPUUsage(t *testing.T) {
+	oldNow := process.Now
+	defer func() { process.Now = oldNow }()
+	now := time.Unix(1440000000, 0)
+	process.Now = func() time.Time { return now }
+
+	var (
+		started = time.Unix(1430000000, 0)
+		walker  = &mockWalker{processes: []process.Process{
+			{PID: 1, StartTime: started, CPUTime: 10 * time.Second},
+			{PID: 2, StartTime: started, CPUTime: 10 * time.Second},
+		}}
+		cachingWalker = process.NewCachingWalker(walker)
+	)
+	if err := cachingWalker.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ten seconds later, process 1 has used five seconds of CPU, and
+	// process 2 has been replaced by another with the same PID.
+	now = now.Add(10 * time.Second)
+	walker.processes = []process.Process{
+		{PID: 1, StartTime: started, CPUTime: 15 * time.Second},
+		{PID: 2, StartTime: now, CPUTime: 1 * time.Second},
+	}
+	if err := cachingWalker.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []process.Process{
+		{PID: 1, StartTime: started, CPUTime: 15 * time.Second, CPUUsage: 50, HasCPUUsage: true},
+		{PID: 2, StartTime: now, CPUTime: 1 * time.Second},
+	}
+	have, err := all(cachingWalker)
+	if err != nil || !reflect.DeepEqual(want, have) {
+		t.Errorf("%v (%v)", test.Diff(want, have), err)
+	}
+}
+
 func all(w process.Walker) ([]process.Process, error) {
 	all := []process.Process{}
 	err := w.Walk(func(p process.Process) {
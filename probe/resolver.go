@@ -37,7 +37,7 @@ func newStaticResolver(peers []string, add func(string)) staticResolver {
 		add:   add,
 		peers: prepareNames(peers),
 	}
-	go r.loop()
+	go r.loop(tick(time.Minute))
 	return r
 }
 
@@ -65,9 +65,8 @@ func prepareNames(strs []string) []peer {
 	return results
 }
 
-func (r staticResolver) loop() {
+func (r staticResolver) loop(t <-chan time.Time) {
 	r.resolveHosts()
-	t := tick(time.Minute)
 	for {
 		select {
 		case <-t:
@@ -0,0 +1,138 @@
+package systemd
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/scope/probe/cgroup"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// Node metadata keys.
+const (
+	Unit            = "systemd_unit"
+	UnitSlice       = "systemd_unit_slice"
+	UnitDescription = "systemd_unit_description"
+	UnitActiveState = "systemd_unit_active_state"
+	UnitSubState    = "systemd_unit_sub_state"
+)
+
+// UnitStatus is the part of a unit's status, as systemd reports it, that
+// the Tagger uses.
+type UnitStatus struct {
+	Name        string
+	Description string
+	ActiveState string
+	SubState    string
+}
+
+// Units lists the units systemd has loaded. The D-Bus implementation lives
+// in probe/systemd/units, so that importing this package for its metadata
+// keys doesn't pull in a D-Bus client.
+type Units interface {
+	ListUnits() ([]UnitStatus, error)
+}
+
+// Tagger works out the systemd unit each process belongs to from its
+// cgroup, tags the process with it, and adds a node for each unit to the
+// service topology. If it has Units, the nodes are decorated with the
+// units' descriptions and states.
+type Tagger struct {
+	cgroups *cgroup.Reader
+	units   Units
+	hostID  string
+}
+
+// NewTagger returns a usable Tagger. units may be nil.
+func NewTagger(cgroups *cgroup.Reader, units Units, hostID string) *Tagger {
+	return &Tagger{
+		cgroups: cgroups,
+		units:   units,
+		hostID:  hostID,
+	}
+}
+
+// Tag implements Tagger.
+func (t *Tagger) Tag(r report.Report) (report.Report, error) {
+	found := map[string]report.NodeMetadata{}
+	for nodeID, nmd := range r.Process.NodeMetadatas {
+		pid, err := strconv.Atoi(nmd.Metadata[process.PID])
+		if err != nil {
+			continue
+		}
+		paths, err := t.cgroups.PathsOf(pid)
+		if err != nil {
+			// The process has probably gone away.
+			continue
+		}
+		unit, slice, ok := unitOf(paths)
+		if !ok {
+			continue
+		}
+
+		r.Process.NodeMetadatas[nodeID].Merge(report.MakeNodeMetadataWith(map[string]string{
+			Unit: unit,
+		}))
+		service := report.MakeNodeMetadataWith(map[string]string{
+			Unit:              unit,
+			report.HostNodeID: report.MakeHostNodeID(t.hostID),
+		})
+		if slice != "" {
+			service.Metadata[UnitSlice] = slice
+		}
+		found[unit] = service
+	}
+
+	if len(found) > 0 && t.units != nil {
+		statuses, err := t.units.ListUnits()
+		if err != nil {
+			log.Printf("systemd: %v", err)
+		}
+		for _, status := range statuses {
+			if nmd, ok := found[status.Name]; ok {
+				nmd.Metadata[UnitDescription] = status.Description
+				nmd.Metadata[UnitActiveState] = status.ActiveState
+				nmd.Metadata[UnitSubState] = status.SubState
+			}
+		}
+	}
+
+	for unit, nmd := range found {
+		r.Service.NodeMetadatas[report.MakeServiceNodeID(t.hostID, unit)] = nmd
+	}
+	return r, nil
+}
+
+// unitOf returns the systemd unit, and the slice containing it, that the
+// process with the given cgroups belongs to. systemd keeps its units in the
+// v2 cgroup, or on older hosts in a v1 hierarchy of its own, as e.g.
+//
+//   /system.slice/nginx.service
+//   /user.slice/user-1000.slice/session-2.scope
+//
+// The innermost service or scope is the unit, so processes of a container
+// started by a service belong to the container's scope.
+func unitOf(paths cgroup.Paths) (unit, slice string, ok bool) {
+	for _, key := range []string{cgroup.Unified, cgroup.Systemd} {
+		p, ok := paths[key]
+		if !ok {
+			continue
+		}
+		elements := strings.Split(p, "/")
+		for i := len(elements) - 1; i >= 0; i-- {
+			if !strings.HasSuffix(elements[i], ".service") && !strings.HasSuffix(elements[i], ".scope") {
+				continue
+			}
+			for j := i - 1; j >= 0; j-- {
+				if strings.HasSuffix(elements[j], ".slice") {
+					slice = elements[j]
+					break
+				}
+			}
+			return elements[i], slice, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,128 @@
+package systemd_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/cgroup"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/probe/systemd"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+var cgroups = map[string]string{
+	"/proc/1/cgroup": "0::/init.scope\n",
+	"/proc/2/cgroup": "0::/\n",
+	"/proc/3/cgroup": "0::/system.slice/nginx.service\n",
+	"/proc/4/cgroup": "0::/system.slice/nginx.service\n",
+	"/proc/5/cgroup": "4:memory:/\n1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n",
+	"/proc/6/cgroup": "0::/system.slice/containerd.service/kubepods-pod1.slice/cri-containerd-abc.scope\n",
+}
+
+type mockUnits struct {
+	units []systemd.UnitStatus
+	err   error
+}
+
+func (m mockUnits) ListUnits() ([]systemd.UnitStatus, error) {
+	return m.units, m.err
+}
+
+func stubCgroups() func() {
+	oldReadFile := cgroup.ReadFile
+	cgroup.ReadFile = func(filename string) ([]byte, error) {
+		if contents, ok := cgroups[filename]; ok {
+			return []byte(contents), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	return func() { cgroup.ReadFile = oldReadFile }
+}
+
+func processReport(pids ...string) report.Report {
+	r := report.MakeReport()
+	for _, pid := range pids {
+		r.Process.NodeMetadatas[report.MakeProcessNodeID("host", pid)] = report.MakeNodeMetadataWith(map[string]string{process.PID: pid})
+	}
+	return r
+}
+
+func service(md map[string]string) report.NodeMetadata {
+	md[report.HostNodeID] = report.MakeHostNodeID("host")
+	return report.MakeNodeMetadataWith(md)
+}
+
+func TestTagger(t *testing.T) {
+	defer stubCgroups()()
+
+	units := mockUnits{units: []systemd.UnitStatus{
+		{Name: "nginx.service", Description: "A high performance web server", ActiveState: "active", SubState: "running"},
+		{Name: "sshd.service", Description: "OpenSSH server daemon", ActiveState: "active", SubState: "running"},
+	}}
+	tagger := systemd.NewTagger(cgroup.NewReader(cgroup.DefaultRoot, "/proc"), units, "host")
+	r, err := tagger.Tag(processReport("1", "2", "3", "4", "5", "6", "7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pid, want := range map[string]string{
+		"1": "init.scope",
+		"2": "",
+		"3": "nginx.service",
+		"4": "nginx.service",
+		"5": "session-2.scope",
+		"6": "cri-containerd-abc.scope",
+		"7": "",
+	} {
+		if have := r.Process.NodeMetadatas[report.MakeProcessNodeID("host", pid)].Metadata[systemd.Unit]; want != have {
+			t.Errorf("pid %s: want unit %q, have %q", pid, want, have)
+		}
+	}
+
+	want := report.NodeMetadatas{
+		report.MakeServiceNodeID("host", "init.scope"): service(map[string]string{
+			systemd.Unit: "init.scope",
+		}),
+		report.MakeServiceNodeID("host", "nginx.service"): service(map[string]string{
+			systemd.Unit:            "nginx.service",
+			systemd.UnitSlice:       "system.slice",
+			systemd.UnitDescription: "A high performance web server",
+			systemd.UnitActiveState: "active",
+			systemd.UnitSubState:    "running",
+		}),
+		report.MakeServiceNodeID("host", "session-2.scope"): service(map[string]string{
+			systemd.Unit:      "session-2.scope",
+			systemd.UnitSlice: "user-1000.slice",
+		}),
+		report.MakeServiceNodeID("host", "cri-containerd-abc.scope"): service(map[string]string{
+			systemd.Unit:      "cri-containerd-abc.scope",
+			systemd.UnitSlice: "kubepods-pod1.slice",
+		}),
+	}
+	if have := r.Service.NodeMetadatas; !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestTaggerWithoutDBus(t *testing.T) {
+	defer stubCgroups()()
+
+	want := report.NodeMetadatas{
+		report.MakeServiceNodeID("host", "nginx.service"): service(map[string]string{
+			systemd.Unit:      "nginx.service",
+			systemd.UnitSlice: "system.slice",
+		}),
+	}
+	for _, units := range []systemd.Units{nil, mockUnits{err: errors.New("no bus")}} {
+		r, err := systemd.NewTagger(cgroup.NewReader(cgroup.DefaultRoot, "/proc"), units, "host").Tag(processReport("3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := r.Service.NodeMetadatas; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s", test.Diff(want, have))
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package units
+
+import (
+	"github.com/coreos/go-systemd/dbus"
+
+	"github.com/weaveworks/scope/probe/systemd"
+)
+
+// Conn lists systemd's units over its D-Bus API. It implements
+// systemd.Units.
+type Conn struct {
+	conn *dbus.Conn
+}
+
+// New connects to systemd over the system bus. Don't forget to Close it.
+func New() (*Conn, error) {
+	conn, err := dbus.NewSystemConnection()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn: conn}, nil
+}
+
+// ListUnits implements systemd.Units.
+func (c *Conn) ListUnits() ([]systemd.UnitStatus, error) {
+	statuses, err := c.conn.ListUnits()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]systemd.UnitStatus, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, systemd.UnitStatus{
+			Name:        status.Name,
+			Description: status.Description,
+			ActiveState: status.ActiveState,
+			SubState:    status.SubState,
+		})
+	}
+	return result, nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() {
+	c.conn.Close()
+}
@@ -42,13 +42,18 @@ func newTopologyTagger() Tagger {
 // Tag implements Tagger
 func (topologyTagger) Tag(r report.Report) (report.Report, error) {
 	for val, topology := range map[string]*report.Topology{
-		"endpoint":        &(r.Endpoint),
-		"address":         &(r.Address),
-		"process":         &(r.Process),
-		"container":       &(r.Container),
-		"container_image": &(r.ContainerImage),
-		"host":            &(r.Host),
-		"overlay":         &(r.Overlay),
+		"endpoint":           &(r.Endpoint),
+		"address":            &(r.Address),
+		"process":            &(r.Process),
+		"container":          &(r.Container),
+		"container_image":    &(r.ContainerImage),
+		"service":            &(r.Service),
+		"pod":                &(r.Pod),
+		"kubernetes_service": &(r.KubernetesService),
+		"namespace":          &(r.Namespace),
+		"deployment":         &(r.Deployment),
+		"host":               &(r.Host),
+		"overlay":            &(r.Overlay),
 	} {
 		md := report.MakeNodeMetadataWith(map[string]string{Topology: val})
 		for nodeID := range topology.NodeMetadatas {
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+var (
+	errTimedOut = errors.New("timed out")
+	after       = time.After
+)
+
+// timedReporter wraps a Reporter, so that it can be run alongside the others
+// and given up on if it's slow. A report that comes too late isn't lost: the
+// next call returns it, rather than asking for another, so a slow reporter
+// reports every few ticks rather than holding up every one.
+type timedReporter struct {
+	name     string
+	reporter Reporter
+	running  chan reportResult // while a Report is in progress
+}
+
+type reportResult struct {
+	report report.Report
+	err    error
+}
+
+func newTimedReporter(name string, reporter Reporter) *timedReporter {
+	return &timedReporter{
+		name:     name,
+		reporter: reporter,
+	}
+}
+
+// reportWithin returns the reporter's report, or errTimedOut if it isn't
+// ready within the timeout. It mustn't be called concurrently.
+func (t *timedReporter) reportWithin(timeout time.Duration) (report.Report, error) {
+	if t.running == nil {
+		running := make(chan reportResult, 1)
+		go func(begin time.Time) {
+			r, err := t.reporter.Report()
+			reporterDuration.WithLabelValues(t.name).Observe(time.Since(begin).Seconds())
+			if err != nil {
+				reporterErrors.WithLabelValues(t.name).Inc()
+			}
+			running <- reportResult{r, err}
+		}(time.Now())
+		t.running = running
+	}
+
+	select {
+	case result := <-t.running:
+		t.running = nil
+		return result.report, result.err
+	case <-after(timeout):
+		reporterTimeouts.WithLabelValues(t.name).Inc()
+		return report.MakeReport(), errTimedOut
+	}
+}
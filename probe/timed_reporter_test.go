@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+type blockingReporter struct {
+	reports chan report.Report
+}
+
+func (b blockingReporter) Report() (report.Report, error) {
+	return <-b.reports, nil
+}
+
+func TestTimedReporter(t *testing.T) {
+	oldAfter := after
+	defer func() { after = oldAfter }()
+	timeouts := make(chan time.Time)
+	after = func(time.Duration) <-chan time.Time { return timeouts }
+
+	var (
+		reporter = blockingReporter{make(chan report.Report)}
+		timed    = newTimedReporter("blocking", reporter)
+		want     = report.MakeReport()
+		results  = make(chan error)
+	)
+	want.Host.NodeMetadatas[report.MakeHostNodeID("host")] = report.MakeNodeMetadata()
+
+	// The reporter is slow, so the first call times out...
+	go func() {
+		_, err := timed.reportWithin(time.Second)
+		results <- err
+	}()
+	timeouts <- time.Now()
+	if err := <-results; err != errTimedOut {
+		t.Fatalf("want %v, have %v", errTimedOut, err)
+	}
+
+	// ...and the next gets the report it was waiting for, without asking
+	// for another.
+	var have report.Report
+	go func() {
+		var err error
+		have, err = timed.reportWithin(time.Second)
+		results <- err
+	}()
+	reporter.reports <- want
+	if err := <-results; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Now it's quick.
+	go func() { reporter.reports <- want }()
+	have, err := timed.reportWithin(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
@@ -4,23 +4,35 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"time"
 
+	"github.com/weaveworks/scope/probe/container"
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/probe/endpoint"
 	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/overlay"
 	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/probe/systemd"
 	"github.com/weaveworks/scope/report"
 )
 
 const (
-	mb                 = 1 << 20
-	connectionsRank    = 100
-	containerImageRank = 4
-	containerRank      = 3
-	processRank        = 2
-	hostRank           = 1
-	endpointRank       = 0 // this is the least important table, so sort to bottom
-	addressRank        = 0 // also least important; never merged with endpoints
+	mb                    = 1 << 20
+	connectionsRank       = 100
+	kubernetesServiceRank = 6
+	podRank               = 5
+	containerImageRank    = 4
+	containerRank         = 3
+	serviceRank           = 3 // processes are shown by container or by service
+	processRank           = 2
+	hostRank              = 1
+	overlayRank           = 1
+	namespaceRank         = 0 // ties sort in the order the tables are added
+	listeningRank         = 0
+	eventsRank            = 0
+	endpointRank          = 0 // this is the least important table, so sort to bottom
+	addressRank           = 0 // also least important; never merged with endpoints
 )
 
 // DetailedNode is the data type that's yielded to the JavaScript layer when
@@ -55,6 +67,17 @@ func (t tables) Len() int           { return len(t) }
 func (t tables) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 func (t tables) Less(i, j int) bool { return t[i].Rank > t[j].Rank }
 
+func shortenByteRate(rate float64) (major, minor string) {
+	switch {
+	case rate > 1024*1024:
+		return fmt.Sprintf("%.2f", rate/1024/1024), "MBps"
+	case rate > 1024:
+		return fmt.Sprintf("%.1f", rate/1024), "KBps"
+	default:
+		return fmt.Sprintf("%.0f", rate), "Bps"
+	}
+}
+
 // MakeDetailedNode transforms a renderable node to a detailed node. It uses
 // aggregate metadata, plus the set of origin node IDs, to produce tables.
 func MakeDetailedNode(r report.Report, n RenderableNode) DetailedNode {
@@ -68,16 +91,6 @@ func MakeDetailedNode(r report.Report, n RenderableNode) DetailedNode {
 		}
 		return float64(*u) / sec, true
 	}
-	shortenByteRate := func(rate float64) (major, minor string) {
-		switch {
-		case rate > 1024*1024:
-			return fmt.Sprintf("%.2f", rate/1024/1024), "MBps"
-		case rate > 1024:
-			return fmt.Sprintf("%.1f", rate/1024), "KBps"
-		default:
-			return fmt.Sprintf("%.0f", rate), "Bps"
-		}
-	}
 
 	tables := tables{}
 	{
@@ -85,6 +98,18 @@ func MakeDetailedNode(r report.Report, n RenderableNode) DetailedNode {
 		if n.EdgeMetadata.MaxConnCountTCP != nil {
 			rows = append(rows, Row{"TCP connections", strconv.FormatUint(*n.EdgeMetadata.MaxConnCountTCP, 10), ""})
 		}
+		if n.EdgeMetadata.Direction != 0 {
+			rows = append(rows, Row{"Direction", n.EdgeMetadata.Direction.String(), ""})
+		}
+		states := []string{}
+		for state := range n.EdgeMetadata.ConnCountByState {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+		for _, state := range states {
+			count := n.EdgeMetadata.ConnCountByState[state]
+			rows = append(rows, Row{fmt.Sprintf("TCP connections (%s)", state), strconv.FormatUint(count, 10), ""})
+		}
 		if rate, ok := rate(n.EdgeMetadata.EgressPacketCount); ok {
 			rows = append(rows, Row{"Egress packet rate", fmt.Sprintf("%.0f", rate), "packets/sec"})
 		}
@@ -108,20 +133,36 @@ func MakeDetailedNode(r report.Report, n RenderableNode) DetailedNode {
 	// multiple origins. The ultimate goal here is to generate tables to view
 	// in the UI, so we skip the intermediate representations, but we could
 	// add them later.
-	connections := []Row{}
+	connections, listening, events, namespaces := []Row{}, []Row{}, []Row{}, map[string]string{}
 	for _, id := range n.Origins {
+		if nmd, ok := r.Container.NodeMetadatas[id]; ok {
+			events = append(events, eventRows(nmd)...)
+		}
 		if table, ok := OriginTable(r, id); ok {
 			tables = append(tables, table)
 		} else if nmd, ok := r.Endpoint.NodeMetadatas[id]; ok {
 			connections = append(connections, connectionDetailsRows(r.Endpoint, id, nmd)...)
+			listening = append(listening, listeningRows(nmd)...)
+			if namespace, ok := nmd.Metadata[endpoint.NetNamespace]; ok {
+				namespaces[namespace] = nmd.Metadata[endpoint.Veth]
+			}
 		}
 	}
+	if len(namespaces) > 0 {
+		tables = append(tables, namespacesTable(namespaces))
+	}
+	if len(listening) > 0 {
+		tables = append(tables, listeningTable(listening))
+	}
+	if len(events) > 0 {
+		tables = append(tables, eventsTable(events))
+	}
 	if len(connections) > 0 {
 		tables = append(tables, connectionDetailsTable(connections))
 	}
 
 	// Sort tables by rank
-	sort.Sort(tables)
+	sort.Stable(tables)
 
 	return DetailedNode{
 		ID:         n.ID,
@@ -147,6 +188,18 @@ func OriginTable(r report.Report, originID string) (Table, bool) {
 	if nmd, ok := r.ContainerImage.NodeMetadatas[originID]; ok {
 		return containerImageOriginTable(nmd)
 	}
+	if nmd, ok := r.Service.NodeMetadatas[originID]; ok {
+		return serviceOriginTable(nmd)
+	}
+	if nmd, ok := r.Pod.NodeMetadatas[originID]; ok {
+		return podOriginTable(nmd)
+	}
+	if nmd, ok := r.KubernetesService.NodeMetadatas[originID]; ok {
+		return kubernetesServiceOriginTable(nmd)
+	}
+	if nmd, ok := r.Overlay.NodeMetadatas[originID]; ok {
+		return overlayOriginTable(nmd)
+	}
 	if nmd, ok := r.Host.NodeMetadatas[originID]; ok {
 		return hostOriginTable(nmd)
 	}
@@ -169,6 +222,69 @@ func connectionDetailsRows(endpointTopology report.Topology, originID string, nm
 	return rows
 }
 
+func listeningRows(nmd report.NodeMetadata) []Row {
+	transports, ok := nmd.Metadata[endpoint.Listening]
+	if !ok {
+		return []Row{}
+	}
+	return []Row{{
+		Key:        fmt.Sprintf("%s:%s", nmd.Metadata[endpoint.Addr], nmd.Metadata[endpoint.Port]),
+		ValueMajor: transports,
+	}}
+}
+
+func eventRows(nmd report.NodeMetadata) []Row {
+	rows := []Row{}
+	for _, e := range docker.DecodeEvents(nmd.Metadata[docker.ContainerEvents]) {
+		row := Row{Key: e.Time.Format(time.RFC3339), ValueMajor: e.Status}
+		if e.ExitCode != "" {
+			row.ValueMinor = "exit code " + e.ExitCode
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func eventsTable(eventRows []Row) Table {
+	sort.Stable(rows(eventRows))
+	return Table{
+		Title:   "Events",
+		Numeric: false,
+		Rows:    eventRows,
+		Rank:    eventsRank,
+	}
+}
+
+func listeningTable(listeningRows []Row) Table {
+	sort.Sort(rows(listeningRows))
+	return Table{
+		Title:   "Listening on",
+		Numeric: false,
+		Rows:    listeningRows,
+		Rank:    listeningRank,
+	}
+}
+
+func namespacesTable(namespaces map[string]string) Table {
+	namespaceRows := []Row{}
+	for namespace, veths := range namespaces {
+		namespaceRows = append(namespaceRows, Row{Key: namespace, ValueMajor: veths})
+	}
+	sort.Sort(rows(namespaceRows))
+	return Table{
+		Title:   "Network Namespaces",
+		Numeric: false,
+		Rows:    append([]Row{{Key: "Namespace", ValueMajor: "Host interfaces"}}, namespaceRows...),
+		Rank:    namespaceRank,
+	}
+}
+
+type rows []Row
+
+func (r rows) Len() int           { return len(r) }
+func (r rows) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r rows) Less(i, j int) bool { return r[i].Key < r[j].Key }
+
 func connectionDetailsTable(connectionRows []Row) Table {
 	return Table{
 		Title:   "Connection Details",
@@ -195,16 +311,40 @@ func processOriginTable(nmd report.NodeMetadata) (Table, bool) {
 	rows := []Row{}
 	for _, tuple := range []struct{ key, human string }{
 		{process.Comm, "Name (comm)"},
+		{process.ServiceName, "Service"},
 		{process.PID, "PID"},
 		{process.PPID, "Parent PID"},
 		{process.Cmdline, "Command"},
 		{process.Threads, "# Threads"},
+		{process.Exe, "Executable"},
+		{process.User, "User"},
+		{process.OpenFiles, "Open files"},
 	} {
 		if val, ok := nmd.Metadata[tuple.key]; ok {
 			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
 		}
 	}
 
+	if val, ok := nmd.Metadata[process.CPUUsage]; ok {
+		rows = append(rows, Row{Key: "CPU usage", ValueMajor: val, ValueMinor: "%"})
+	}
+	for _, tuple := range []struct{ key, human string }{
+		{process.RSS, "Memory (RSS)"},
+		{process.VSZ, "Virtual memory"},
+	} {
+		if val, ok := nmd.Metadata[tuple.key]; ok {
+			if bytes, err := strconv.ParseFloat(val, 64); err == nil {
+				rows = append(rows, Row{Key: tuple.human, ValueMajor: fmt.Sprintf("%0.2f", bytes/float64(mb)), ValueMinor: "MB"})
+			}
+		}
+	}
+	if val, ok := nmd.Metadata[process.StartTime]; ok {
+		if started, err := time.Parse(time.RFC3339, val); err == nil {
+			age := time.Since(started)
+			rows = append(rows, Row{Key: "Age", ValueMajor: (age - age%time.Second).String(), ValueMinor: ""})
+		}
+	}
+
 	return Table{
 		Title:   "Origin Process",
 		Numeric: false,
@@ -218,24 +358,84 @@ func containerOriginTable(nmd report.NodeMetadata) (Table, bool) {
 	for _, tuple := range []struct{ key, human string }{
 		{docker.ContainerID, "ID"},
 		{docker.ContainerName, "Name"},
+		{container.Runtime, "Runtime"},
 		{docker.ImageID, "Image ID"},
 		{docker.ContainerPorts, "Ports"},
 		{docker.ContainerCreated, "Created"},
 		{docker.ContainerCommand, "Command"},
+		{docker.ContainerState, "State"},
+		{docker.ContainerHealth, "Health"},
+		{docker.ContainerExitCode, "Exit code"},
+		{docker.ContainerRestartCount, "Restarts"},
+		{docker.ContainerHostname, "Hostname"},
+		{docker.ContainerNetworkMode, "Network mode"},
+		{overlay.WeaveIPs, "Weave IPs"},
+		{overlay.WeaveDNSHostnames, "WeaveDNS"},
 	} {
 		if val, ok := nmd.Metadata[tuple.key]; ok {
 			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
 		}
 	}
 
+	ips := docker.NetworkIPs(nmd)
+	for _, network := range sortedKeys(ips) {
+		rows = append(rows, Row{Key: "IP on " + network, ValueMajor: ips[network], ValueMinor: ""})
+	}
+
+	if val, ok := nmd.Metadata[docker.CPUUsagePercent]; ok {
+		rows = append(rows, Row{Key: "CPU Usage", ValueMajor: val, ValueMinor: "%"})
+	}
+
 	if val, ok := nmd.Metadata[docker.MemoryUsage]; ok {
 		memory, err := strconv.ParseFloat(val, 64)
 		if err == nil {
-			memoryStr := fmt.Sprintf("%0.2f", memory/float64(mb))
-			rows = append(rows, Row{Key: "Memory Usage (MB):", ValueMajor: memoryStr, ValueMinor: ""})
+			memoryStr, limitStr := fmt.Sprintf("%0.2f", memory/float64(mb)), ""
+			if limit, err := strconv.ParseFloat(nmd.Metadata[docker.MemoryLimit], 64); err == nil && limit > 0 {
+				limitStr = fmt.Sprintf("of %0.2f", limit/float64(mb))
+			}
+			rows = append(rows, Row{Key: "Memory Usage (MB):", ValueMajor: memoryStr, ValueMinor: limitStr})
+		}
+	}
+
+	for _, tuple := range []struct{ key, human string }{
+		{docker.BlkioReadBytes, "Block I/O read (MB)"},
+		{docker.BlkioWriteBytes, "Block I/O written (MB)"},
+	} {
+		if bytes, err := strconv.ParseFloat(nmd.Metadata[tuple.key], 64); err == nil {
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: fmt.Sprintf("%0.2f", bytes/float64(mb)), ValueMinor: ""})
+		}
+	}
+
+	if val, ok := nmd.Metadata[docker.PidsCurrent]; ok {
+		rows = append(rows, Row{Key: "Processes", ValueMajor: val, ValueMinor: ""})
+	}
+
+	for _, tuple := range []struct{ key, human string }{
+		{docker.NetworkRxRate, "Network ingress rate"},
+		{docker.NetworkTxRate, "Network egress rate"},
+	} {
+		if rate, err := strconv.ParseFloat(nmd.Metadata[tuple.key], 64); err == nil {
+			s, unit := shortenByteRate(rate)
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: s, ValueMinor: unit})
+		}
+	}
+
+	for _, tuple := range []struct{ key, human string }{
+		{docker.NetworkRxDropped, "Ingress packets dropped"},
+		{docker.NetworkRxErrors, "Ingress errors"},
+		{docker.NetworkTxDropped, "Egress packets dropped"},
+		{docker.NetworkTxErrors, "Egress errors"},
+	} {
+		if val, ok := nmd.Metadata[tuple.key]; ok {
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
 		}
 	}
 
+	labels := docker.Labels(nmd)
+	for _, label := range sortedKeys(labels) {
+		rows = append(rows, Row{Key: "Label " + label, ValueMajor: labels[label], ValueMinor: ""})
+	}
+
 	return Table{
 		Title:   "Origin Container",
 		Numeric: false,
@@ -249,11 +449,23 @@ func containerImageOriginTable(nmd report.NodeMetadata) (Table, bool) {
 	for _, tuple := range []struct{ key, human string }{
 		{docker.ImageID, "Image ID"},
 		{docker.ImageName, "Image name"},
+		{docker.ImageTags, "Tags"},
+		{docker.ImageCreated, "Created"},
+		{docker.ImageRunningContainers, "Running containers"},
 	} {
 		if val, ok := nmd.Metadata[tuple.key]; ok {
 			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
 		}
 	}
+
+	if size, err := strconv.ParseFloat(nmd.Metadata[docker.ImageVirtualSize], 64); err == nil {
+		rows = append(rows, Row{Key: "Virtual size (MB)", ValueMajor: fmt.Sprintf("%0.2f", size/float64(mb)), ValueMinor: ""})
+	}
+
+	labels := docker.ImageLabels(nmd)
+	for _, label := range sortedKeys(labels) {
+		rows = append(rows, Row{Key: "Label " + label, ValueMajor: labels[label], ValueMinor: ""})
+	}
 	return Table{
 		Title:   "Origin Container Image",
 		Numeric: false,
@@ -262,6 +474,107 @@ func containerImageOriginTable(nmd report.NodeMetadata) (Table, bool) {
 	}, len(rows) > 0
 }
 
+func serviceOriginTable(nmd report.NodeMetadata) (Table, bool) {
+	rows := []Row{}
+	for _, tuple := range []struct{ key, human string }{
+		{systemd.Unit, "Unit"},
+		{systemd.UnitDescription, "Description"},
+		{systemd.UnitSlice, "Slice"},
+	} {
+		if val, ok := nmd.Metadata[tuple.key]; ok {
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
+		}
+	}
+	if val, ok := nmd.Metadata[systemd.UnitActiveState]; ok {
+		rows = append(rows, Row{Key: "State", ValueMajor: val, ValueMinor: nmd.Metadata[systemd.UnitSubState]})
+	}
+
+	return Table{
+		Title:   "Origin Service",
+		Numeric: false,
+		Rows:    rows,
+		Rank:    serviceRank,
+	}, len(rows) > 0
+}
+
+func podOriginTable(nmd report.NodeMetadata) (Table, bool) {
+	rows := []Row{}
+	for _, tuple := range []struct{ key, human string }{
+		{kubernetes.PodName, "Name"},
+		{kubernetes.NamespaceName, "Namespace"},
+		{kubernetes.PodCreated, "Created"},
+		{kubernetes.PodPhase, "Phase"},
+		{kubernetes.PodIP, "IP"},
+		{kubernetes.PodNodeName, "Node"},
+		{kubernetes.PodServices, "Services"},
+		{kubernetes.PodDeployment, "Deployment"},
+	} {
+		if val, ok := nmd.Metadata[tuple.key]; ok {
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
+		}
+	}
+
+	labels := kubernetes.Labels(nmd)
+	for _, label := range sortedKeys(labels) {
+		rows = append(rows, Row{Key: "Label " + label, ValueMajor: labels[label], ValueMinor: ""})
+	}
+
+	return Table{
+		Title:   "Origin Pod",
+		Numeric: false,
+		Rows:    rows,
+		Rank:    podRank,
+	}, len(rows) > 0
+}
+
+func kubernetesServiceOriginTable(nmd report.NodeMetadata) (Table, bool) {
+	rows := []Row{}
+	for _, tuple := range []struct{ key, human string }{
+		{kubernetes.ServiceName, "Name"},
+		{kubernetes.NamespaceName, "Namespace"},
+		{kubernetes.ServiceType, "Type"},
+		{kubernetes.ServiceClusterIP, "Cluster IP"},
+		{kubernetes.ServicePorts, "Ports"},
+		{kubernetes.ServicePods, "Pods"},
+	} {
+		if val, ok := nmd.Metadata[tuple.key]; ok {
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
+		}
+	}
+
+	return Table{
+		Title:   "Origin Kubernetes Service",
+		Numeric: false,
+		Rows:    rows,
+		Rank:    kubernetesServiceRank,
+	}, len(rows) > 0
+}
+
+func overlayOriginTable(nmd report.NodeMetadata) (Table, bool) {
+	rows := []Row{}
+	for _, tuple := range []struct{ key, human string }{
+		{overlay.WeavePeerName, "Peer name"},
+		{overlay.WeavePeerNickName, "Nickname"},
+		{overlay.WeaveIPAMAddresses, "IPAM addresses"},
+	} {
+		if val, ok := nmd.Metadata[tuple.key]; ok {
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: val, ValueMinor: ""})
+		}
+	}
+
+	connections := overlay.Connections(nmd)
+	for _, peer := range sortedKeys(connections) {
+		rows = append(rows, Row{Key: "Connection to " + peer, ValueMajor: connections[peer], ValueMinor: ""})
+	}
+
+	return Table{
+		Title:   "Origin Weave Peer",
+		Numeric: false,
+		Rows:    rows,
+		Rank:    overlayRank,
+	}, len(rows) > 0
+}
+
 func hostOriginTable(nmd report.NodeMetadata) (Table, bool) {
 	rows := []Row{}
 	for _, tuple := range []struct{ key, human string }{
@@ -276,6 +589,39 @@ func hostOriginTable(nmd report.NodeMetadata) (Table, bool) {
 		}
 	}
 
+	if val, ok := nmd.Metadata[host.CPUUsage]; ok {
+		rows = append(rows, Row{Key: "CPU usage", ValueMajor: val, ValueMinor: "%"})
+	}
+	for _, tuple := range []struct{ key, human string }{
+		{host.MemoryUsed, "Memory used"},
+		{host.MemoryAvailable, "Memory available"},
+	} {
+		if bytes, err := strconv.ParseFloat(nmd.Metadata[tuple.key], 64); err == nil {
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: fmt.Sprintf("%0.2f", bytes/float64(mb)), ValueMinor: "MB"})
+		}
+	}
+	rates := []struct{ key, human string }{
+		{host.DiskReadRate, "Disk read rate"},
+		{host.DiskWriteRate, "Disk write rate"},
+	}
+	for _, name := range host.Interfaces(nmd) {
+		rates = append(rates,
+			struct{ key, human string }{host.RxRatePrefix + name, name + " ingress rate"},
+			struct{ key, human string }{host.TxRatePrefix + name, name + " egress rate"},
+		)
+	}
+	for _, tuple := range rates {
+		if rate, err := strconv.ParseFloat(nmd.Metadata[tuple.key], 64); err == nil {
+			s, unit := shortenByteRate(rate)
+			rows = append(rows, Row{Key: tuple.human, ValueMajor: s, ValueMinor: unit})
+		}
+	}
+
+	labels := host.Labels(nmd)
+	for _, label := range sortedKeys(labels) {
+		rows = append(rows, Row{Key: "Label " + label, ValueMajor: labels[label], ValueMinor: ""})
+	}
+
 	return Table{
 		Title:   "Origin Host",
 		Numeric: false,
@@ -283,3 +629,12 @@ func hostOriginTable(nmd report.NodeMetadata) (Table, bool) {
 		Rank:    hostRank,
 	}, len(rows) > 0
 }
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
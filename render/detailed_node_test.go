@@ -5,7 +5,14 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/probe/systemd"
 	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test"
 )
 
@@ -27,6 +34,7 @@ func TestOriginTable(t *testing.T) {
 			Rank:    2,
 			Rows: []render.Row{
 				{"Name (comm)", "apache", ""},
+				{"Service", test.ServerProcessService, ""},
 				{"PID", test.ServerPID, ""},
 			},
 		},
@@ -52,6 +60,203 @@ func TestOriginTable(t *testing.T) {
 	}
 }
 
+func TestProcessOriginTableResources(t *testing.T) {
+	var (
+		rpt    = report.MakeReport()
+		nodeID = report.MakeProcessNodeID(test.ServerHostID, test.ServerPID)
+	)
+	rpt.Process.NodeMetadatas[nodeID] = report.MakeNodeMetadataWith(map[string]string{
+		process.PID:       test.ServerPID,
+		process.Comm:      "apache",
+		process.User:      "www-data",
+		process.CPUUsage:  "12.50",
+		process.RSS:       "10485760",
+		process.OpenFiles: "12",
+	})
+
+	want := render.Table{
+		Title:   "Origin Process",
+		Numeric: false,
+		Rank:    2,
+		Rows: []render.Row{
+			{"Name (comm)", "apache", ""},
+			{"PID", test.ServerPID, ""},
+			{"User", "www-data", ""},
+			{"Open files", "12", ""},
+			{"CPU usage", "12.50", "%"},
+			{"Memory (RSS)", "10.00", "MB"},
+		},
+	}
+	have, ok := render.OriginTable(rpt, nodeID)
+	if !ok || !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestHostOriginTableMetrics(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Host.NodeMetadatas[test.ServerHostNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		host.HostName:              test.ServerHostName,
+		host.CPUUsage:              "25.00",
+		host.MemoryUsed:            "10485760",
+		host.DiskReadRate:          "2048.00",
+		host.RxRatePrefix + "eth0": "100.00",
+		host.TxRatePrefix + "eth0": "2097152.00",
+	})
+
+	want := render.Table{
+		Title:   "Origin Host",
+		Numeric: false,
+		Rank:    1,
+		Rows: []render.Row{
+			{"Host name", test.ServerHostName, ""},
+			{"CPU usage", "25.00", "%"},
+			{"Memory used", "10.00", "MB"},
+			{"Disk read rate", "2.0", "KBps"},
+			{"eth0 ingress rate", "100", "Bps"},
+			{"eth0 egress rate", "2.00", "MBps"},
+		},
+	}
+	have, ok := render.OriginTable(rpt, test.ServerHostNodeID)
+	if !ok || !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestContainerOriginTableStats(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.NodeMetadatas[test.ServerContainerNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ContainerID:                test.ServerContainerID,
+		docker.ContainerState:             "running",
+		docker.LabelPrefix + "tier":       "frontend",
+		docker.NetworkIPPrefix + "bridge": "172.17.0.2",
+		docker.CPUUsagePercent:            "50.00",
+		docker.MemoryUsage:                "10485760",
+		docker.MemoryLimit:                "536870912",
+		docker.BlkioReadBytes:             "1048576",
+		docker.BlkioWriteBytes:            "0",
+		docker.PidsCurrent:                "4",
+		docker.NetworkRxRate:              "2048.00",
+		docker.NetworkTxRate:              "0.00",
+		docker.NetworkRxDropped:           "3",
+		docker.NetworkRxErrors:            "0",
+	})
+
+	want := render.Table{
+		Title:   "Origin Container",
+		Numeric: false,
+		Rank:    3,
+		Rows: []render.Row{
+			{"ID", test.ServerContainerID, ""},
+			{"State", "running", ""},
+			{"IP on bridge", "172.17.0.2", ""},
+			{"CPU Usage", "50.00", "%"},
+			{"Memory Usage (MB):", "10.00", "of 512.00"},
+			{"Block I/O read (MB)", "1.00", ""},
+			{"Block I/O written (MB)", "0.00", ""},
+			{"Processes", "4", ""},
+			{"Network ingress rate", "2.0", "KBps"},
+			{"Network egress rate", "0", "Bps"},
+			{"Ingress packets dropped", "3", ""},
+			{"Ingress errors", "0", ""},
+			{"Label tier", "frontend", ""},
+		},
+	}
+	have, ok := render.OriginTable(rpt, test.ServerContainerNodeID)
+	if !ok || !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestContainerImageOriginTable(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.ContainerImage.NodeMetadatas[test.ServerContainerImageNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ImageID:                         test.ServerContainerImageID,
+		docker.ImageName:                       test.ServerContainerImageName,
+		docker.ImageTags:                       test.ServerContainerImageName + ", image/server:1.0",
+		docker.ImageCreated:                    "19 Aug 15 16:00 UTC",
+		docker.ImageRunningContainers:          "2",
+		docker.ImageVirtualSize:                "209715200",
+		docker.ImageLabelPrefix + "maintainer": "me",
+	})
+
+	want := render.Table{
+		Title:   "Origin Container Image",
+		Numeric: false,
+		Rank:    4,
+		Rows: []render.Row{
+			{"Image ID", test.ServerContainerImageID, ""},
+			{"Image name", test.ServerContainerImageName, ""},
+			{"Tags", test.ServerContainerImageName + ", image/server:1.0", ""},
+			{"Created", "19 Aug 15 16:00 UTC", ""},
+			{"Running containers", "2", ""},
+			{"Virtual size (MB)", "200.00", ""},
+			{"Label maintainer", "me", ""},
+		},
+	}
+	have, ok := render.OriginTable(rpt, test.ServerContainerImageNodeID)
+	if !ok || !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestServiceOriginTable(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Service.NodeMetadatas[test.ServerServiceNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		systemd.Unit:            test.ServerServiceUnit,
+		systemd.UnitSlice:       "system.slice",
+		systemd.UnitDescription: "The Apache HTTP Server",
+		systemd.UnitActiveState: "active",
+		systemd.UnitSubState:    "running",
+	})
+
+	want := render.Table{
+		Title:   "Origin Service",
+		Numeric: false,
+		Rank:    3,
+		Rows: []render.Row{
+			{"Unit", test.ServerServiceUnit, ""},
+			{"Description", "The Apache HTTP Server", ""},
+			{"Slice", "system.slice", ""},
+			{"State", "active", "running"},
+		},
+	}
+	have, ok := render.OriginTable(rpt, test.ServerServiceNodeID)
+	if !ok || !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestPodOriginTable(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Pod.NodeMetadatas[test.ServerPodNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		kubernetes.NamespaceName:       test.KubernetesNamespace,
+		kubernetes.PodName:             test.ServerPodName,
+		kubernetes.PodPhase:            "Running",
+		kubernetes.PodIP:               "10.244.1.5",
+		kubernetes.PodServices:         test.ServerKubernetesServiceName,
+		kubernetes.LabelPrefix + "app": "server",
+	})
+
+	want := render.Table{
+		Title:   "Origin Pod",
+		Numeric: false,
+		Rank:    5,
+		Rows: []render.Row{
+			{"Name", test.ServerPodName, ""},
+			{"Namespace", test.KubernetesNamespace, ""},
+			{"Phase", "Running", ""},
+			{"IP", "10.244.1.5", ""},
+			{"Services", test.ServerKubernetesServiceName, ""},
+			{"Label app", "server", ""},
+		},
+	}
+	have, ok := render.OriginTable(rpt, test.ServerPodNodeID)
+	if !ok || !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
 func TestMakeDetailedNode(t *testing.T) {
 	renderableNode := render.ContainerRenderer.Render(test.Report)[test.ServerContainerID]
 	have := render.MakeDetailedNode(test.Report, renderableNode)
@@ -86,6 +291,7 @@ func TestMakeDetailedNode(t *testing.T) {
 				Rank:    2,
 				Rows: []render.Row{
 					{"Name (comm)", "apache", ""},
+					{"Service", test.ServerProcessService, ""},
 					{"PID", test.ServerPID, ""},
 				},
 			},
@@ -142,3 +348,66 @@ func TestMakeDetailedNode(t *testing.T) {
 		t.Errorf("%s", test.Diff(want, have))
 	}
 }
+
+func TestMakeDetailedNodeListening(t *testing.T) {
+	var (
+		rpt    = report.MakeReport()
+		nodeID = report.MakeEndpointNodeID(test.ServerHostID, "0.0.0.0", test.ServerPort)
+	)
+	rpt.Endpoint.NodeMetadatas[nodeID] = report.MakeNodeMetadataWith(map[string]string{
+		endpoint.Addr:      "0.0.0.0",
+		endpoint.Port:      test.ServerPort,
+		endpoint.Listening: "tcp",
+	})
+
+	have := render.MakeDetailedNode(rpt, render.RenderableNode{ID: "foo", Origins: report.MakeIDList(nodeID)})
+	want := render.DetailedNode{
+		ID: "foo",
+		Tables: []render.Table{
+			{
+				Title:   "Listening on",
+				Numeric: false,
+				Rows: []render.Row{
+					{fmt.Sprintf("0.0.0.0:%s", test.ServerPort), "tcp", ""},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
+
+func TestMakeDetailedNodeEvents(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.NodeMetadatas[test.ServerContainerNodeID] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ContainerID:     test.ServerContainerID,
+		docker.ContainerEvents: "2015-08-19T16:00:00Z start,2015-08-19T16:01:00Z die 137",
+	})
+
+	have := render.MakeDetailedNode(rpt, render.RenderableNode{ID: "foo", Origins: report.MakeIDList(test.ServerContainerNodeID)})
+	want := render.DetailedNode{
+		ID: "foo",
+		Tables: []render.Table{
+			{
+				Title:   "Origin Container",
+				Numeric: false,
+				Rank:    3,
+				Rows: []render.Row{
+					{"ID", test.ServerContainerID, ""},
+				},
+			},
+			{
+				Title:   "Events",
+				Numeric: false,
+				Rows: []render.Row{
+					{"2015-08-19T16:00:00Z", "start", ""},
+					{"2015-08-19T16:01:00Z", "die", "exit code 137"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("%s", test.Diff(want, have))
+	}
+}
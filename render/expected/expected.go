@@ -11,6 +11,7 @@ import (
 // Exported for testing.
 var (
 	uncontainedServerID  = render.MakePseudoNodeID(render.UncontainedID, test.ServerHostName)
+	unmanagedServerID    = render.MakePseudoNodeID(render.UnmanagedID, test.ServerHostName)
 	unknownPseudoNode1ID = render.MakePseudoNodeID("10.10.10.10", test.ServerIP, "80")
 	unknownPseudoNode2ID = render.MakePseudoNodeID("10.10.10.11", test.ServerIP, "80")
 	unknownPseudoNode1   = render.RenderableNode{
@@ -182,6 +183,68 @@ var (
 		render.TheInternetID: theInternetNode,
 	}
 
+	RenderedProcessServices = render.RenderableNodes{
+		"curl": {
+			ID:         "curl",
+			LabelMajor: "curl",
+			LabelMinor: "",
+			Rank:       "curl",
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(test.ServerProcessService),
+			Origins: report.MakeIDList(
+				test.Client54001NodeID,
+				test.Client54002NodeID,
+				test.ClientProcess1NodeID,
+				test.ClientProcess2NodeID,
+				test.ClientHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(30),
+				EgressByteCount:   newu64(300),
+			},
+		},
+		test.ServerProcessService: {
+			ID:         test.ServerProcessService,
+			LabelMajor: test.ServerProcessService,
+			LabelMinor: "",
+			Rank:       test.ServerProcessService,
+			Pseudo:     false,
+			Adjacency: report.MakeIDList(
+				"curl",
+				unknownPseudoNode1ID,
+				unknownPseudoNode2ID,
+				render.TheInternetID,
+			),
+			Origins: report.MakeIDList(
+				test.Server80NodeID,
+				test.ServerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(150),
+				EgressByteCount:   newu64(1500),
+			},
+		},
+		"bash": {
+			ID:         "bash",
+			LabelMajor: "bash",
+			LabelMinor: "",
+			Rank:       "bash",
+			Pseudo:     false,
+			Origins: report.MakeIDList(
+				test.NonContainerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{},
+		},
+		unknownPseudoNode1ID: unknownPseudoNode1,
+		unknownPseudoNode2ID: unknownPseudoNode2,
+		render.TheInternetID: theInternetNode,
+	}
+
 	RenderedContainers = render.RenderableNodes{
 		test.ClientContainerID: {
 			ID:         test.ClientContainerID,
@@ -239,6 +302,247 @@ var (
 		render.TheInternetID: theInternetNode,
 	}
 
+	RenderedContainerServices = render.RenderableNodes{
+		"client": {
+			ID:         "client",
+			LabelMajor: "client",
+			LabelMinor: "",
+			Rank:       "client",
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList("server"),
+			Origins: report.MakeIDList(
+				test.ClientContainerNodeID,
+				test.Client54001NodeID,
+				test.Client54002NodeID,
+				test.ClientProcess1NodeID,
+				test.ClientProcess2NodeID,
+				test.ClientHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(30),
+				EgressByteCount:   newu64(300),
+			},
+		},
+		"server": {
+			ID:         "server",
+			LabelMajor: "server",
+			LabelMinor: "",
+			Rank:       "server",
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList("client", render.TheInternetID),
+			Origins: report.MakeIDList(
+				test.ServerContainerNodeID,
+				test.Server80NodeID,
+				test.ServerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(150),
+				EgressByteCount:   newu64(1500),
+			},
+		},
+		uncontainedServerID: {
+			ID:         uncontainedServerID,
+			LabelMajor: render.UncontainedMajor,
+			LabelMinor: test.ServerHostName,
+			Rank:       "",
+			Pseudo:     true,
+			Origins: report.MakeIDList(
+				test.NonContainerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{},
+		},
+		render.TheInternetID: theInternetNode,
+	}
+
+	ClientServiceID = render.MakeServiceID(test.ClientHostID, test.ClientServiceUnit)
+	ServerServiceID = render.MakeServiceID(test.ServerHostID, test.ServerServiceUnit)
+
+	RenderedServices = render.RenderableNodes{
+		ClientServiceID: {
+			ID:         ClientServiceID,
+			LabelMajor: test.ClientServiceUnit,
+			LabelMinor: test.ClientHostID,
+			Rank:       test.ClientServiceUnit,
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(ServerServiceID),
+			Origins: report.MakeIDList(
+				test.ClientServiceNodeID,
+				test.Client54001NodeID,
+				test.Client54002NodeID,
+				test.ClientProcess1NodeID,
+				test.ClientProcess2NodeID,
+				test.ClientHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(30),
+				EgressByteCount:   newu64(300),
+			},
+		},
+		ServerServiceID: {
+			ID:         ServerServiceID,
+			LabelMajor: test.ServerServiceUnit,
+			LabelMinor: test.ServerHostID,
+			Rank:       test.ServerServiceUnit,
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(ClientServiceID, render.TheInternetID),
+			Origins: report.MakeIDList(
+				test.ServerServiceNodeID,
+				test.Server80NodeID,
+				test.ServerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(150),
+				EgressByteCount:   newu64(1500),
+			},
+		},
+		unmanagedServerID: {
+			ID:         unmanagedServerID,
+			LabelMajor: render.UnmanagedMajor,
+			LabelMinor: test.ServerHostName,
+			Rank:       "",
+			Pseudo:     true,
+			Origins: report.MakeIDList(
+				test.NonContainerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{},
+		},
+		render.TheInternetID: theInternetNode,
+	}
+
+	ClientPodID               = render.MakePodID(test.KubernetesNamespace, test.ClientPodName)
+	ServerPodID               = render.MakePodID(test.KubernetesNamespace, test.ServerPodName)
+	ServerKubernetesServiceID = render.MakeKubernetesServiceID(test.KubernetesNamespace, test.ServerKubernetesServiceName)
+	unexposedID               = render.MakePseudoNodeID(render.UnexposedID, test.KubernetesNamespace)
+
+	RenderedPods = render.RenderableNodes{
+		ClientPodID: {
+			ID:         ClientPodID,
+			LabelMajor: test.ClientPodName,
+			LabelMinor: test.KubernetesNamespace,
+			Rank:       test.KubernetesNamespace,
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(ServerPodID),
+			Origins: report.MakeIDList(
+				test.ClientPodNodeID,
+				test.ClientContainerNodeID,
+				test.Client54001NodeID,
+				test.Client54002NodeID,
+				test.ClientProcess1NodeID,
+				test.ClientProcess2NodeID,
+				test.ClientHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(30),
+				EgressByteCount:   newu64(300),
+			},
+		},
+		ServerPodID: {
+			ID:         ServerPodID,
+			LabelMajor: test.ServerPodName,
+			LabelMinor: test.KubernetesNamespace,
+			Rank:       test.KubernetesNamespace,
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(ClientPodID, render.TheInternetID),
+			Origins: report.MakeIDList(
+				test.ServerPodNodeID,
+				test.ServerContainerNodeID,
+				test.Server80NodeID,
+				test.ServerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(150),
+				EgressByteCount:   newu64(1500),
+			},
+		},
+		uncontainedServerID: {
+			ID:         uncontainedServerID,
+			LabelMajor: render.UncontainedMajor,
+			LabelMinor: test.ServerHostName,
+			Rank:       "",
+			Pseudo:     true,
+			Origins: report.MakeIDList(
+				test.NonContainerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{},
+		},
+		render.TheInternetID: theInternetNode,
+	}
+
+	RenderedPodServices = render.RenderableNodes{
+		ServerKubernetesServiceID: {
+			ID:         ServerKubernetesServiceID,
+			LabelMajor: test.ServerKubernetesServiceName,
+			LabelMinor: test.KubernetesNamespace,
+			Rank:       test.KubernetesNamespace,
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(unexposedID, render.TheInternetID),
+			Origins: report.MakeIDList(
+				test.ServerKubernetesServiceNodeID,
+				test.ServerPodNodeID,
+				test.ServerContainerNodeID,
+				test.Server80NodeID,
+				test.ServerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(150),
+				EgressByteCount:   newu64(1500),
+			},
+		},
+		unexposedID: {
+			ID:         unexposedID,
+			LabelMajor: render.UnexposedMajor,
+			LabelMinor: test.KubernetesNamespace,
+			Rank:       "",
+			Pseudo:     true,
+			Adjacency:  report.MakeIDList(ServerKubernetesServiceID),
+			Origins: report.MakeIDList(
+				test.ClientPodNodeID,
+				test.ClientContainerNodeID,
+				test.Client54001NodeID,
+				test.Client54002NodeID,
+				test.ClientProcess1NodeID,
+				test.ClientProcess2NodeID,
+				test.ClientHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{
+				EgressPacketCount: newu64(30),
+				EgressByteCount:   newu64(300),
+			},
+		},
+		uncontainedServerID: {
+			ID:         uncontainedServerID,
+			LabelMajor: render.UncontainedMajor,
+			LabelMinor: test.ServerHostName,
+			Rank:       "",
+			Pseudo:     true,
+			Origins: report.MakeIDList(
+				test.NonContainerProcessNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{},
+		},
+		render.TheInternetID: theInternetNode,
+	}
+
 	RenderedContainerImages = render.RenderableNodes{
 		test.ClientContainerImageName: {
 			ID:         test.ClientContainerImageName,
@@ -351,6 +655,40 @@ var (
 		},
 		render.TheInternetID: theInternetNode,
 	}
+
+	ClientOverlayID = render.MakeOverlayID(test.ClientPeerName)
+	ServerOverlayID = render.MakeOverlayID(test.ServerPeerName)
+
+	RenderedOverlay = render.RenderableNodes{
+		ClientOverlayID: {
+			ID:         ClientOverlayID,
+			LabelMajor: test.ClientHostName,
+			LabelMinor: test.ClientPeerName,
+			Rank:       test.ClientPeerName,
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(ServerOverlayID),
+			Origins: report.MakeIDList(
+				test.ClientOverlayNodeID,
+				test.ClientHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{},
+		},
+		ServerOverlayID: {
+			ID:         ServerOverlayID,
+			LabelMajor: test.ServerHostName,
+			LabelMinor: test.ServerPeerName,
+			Rank:       test.ServerPeerName,
+			Pseudo:     false,
+			Adjacency:  report.MakeIDList(ClientOverlayID),
+			Origins: report.MakeIDList(
+				test.ServerOverlayNodeID,
+				test.ServerHostNodeID,
+			),
+			NodeMetadata: report.MakeNodeMetadata(),
+			EdgeMetadata: report.EdgeMetadata{},
+		},
+	}
 )
 
 func newu64(value uint64) *uint64 { return &value }
@@ -15,6 +15,33 @@ func MakeProcessID(hostID, pid string) string {
 	return fmt.Sprintf("process:%s:%s", hostID, pid)
 }
 
+// MakeServiceID makes a service node ID for rendered nodes.
+func MakeServiceID(hostID, unit string) string {
+	return fmt.Sprintf("service:%s:%s", hostID, unit)
+}
+
+// MakePodID makes a pod node ID for rendered nodes.
+func MakePodID(namespace, name string) string {
+	return fmt.Sprintf("pod:%s:%s", namespace, name)
+}
+
+// MakeKubernetesServiceID makes a Kubernetes service node ID for rendered
+// nodes.
+func MakeKubernetesServiceID(namespace, name string) string {
+	return fmt.Sprintf("kubernetes_service:%s:%s", namespace, name)
+}
+
+// MakeOverlayID makes an overlay node ID for rendered nodes.
+func MakeOverlayID(peerName string) string {
+	return fmt.Sprintf("overlay:%s", peerName)
+}
+
+// MakeHostLabelID makes a node ID for rendered nodes grouping everything
+// on hosts with the same value for a host label.
+func MakeHostLabelID(key, value string) string {
+	return fmt.Sprintf("host_label:%s:%s", key, value)
+}
+
 // MakeAddressID makes an address node ID for rendered nodes.
 func MakeAddressID(hostID, addr string) string {
 	return fmt.Sprintf("address:%s:%s", hostID, addr)
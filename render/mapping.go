@@ -3,12 +3,16 @@ package render
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"strings"
 
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/probe/endpoint"
 	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/overlay"
 	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/probe/systemd"
 	"github.com/weaveworks/scope/report"
 )
 
@@ -17,10 +21,31 @@ const (
 	UncontainedID    = "uncontained"
 	UncontainedMajor = "Uncontained"
 
+	UnmanagedID    = "unmanaged"
+	UnmanagedMajor = "Unmanaged"
+
+	UnexposedID    = "unexposed"
+	UnexposedMajor = "Unexposed"
+
+	UnlabelledID    = "unlabelled"
+	UnlabelledMajor = "Unlabelled"
+
 	TheInternetID    = "theinternet"
 	TheInternetMajor = "The Internet"
 )
 
+// ContainerServicePatterns are the names Docker Compose and Swarm give the
+// containers of a service, for containers without the labels that say which
+// service they're in. The "service" submatch is the service, and the
+// "project" submatch, if there is one, the Compose project, which we take
+// to end at the first dash (or, before v2, underscore). A name must have a
+// project, service and number to match, so e.g. redis-6 is left alone.
+var ContainerServicePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(?P<service>.+)\.[0-9]+\.[0-9a-z]{25}$`),          // swarm tasks: web.1.<task id>
+	regexp.MustCompile(`^(?P<project>[a-z0-9]+)_(?P<service>.+)_[0-9]+$`),  // compose v1: project_web_1
+	regexp.MustCompile(`^(?P<project>[a-z0-9_]+)-(?P<service>.+)-[0-9]+$`), // compose v2: project-web-1
+}
+
 // LeafMapFunc is anything which can take an arbitrary NodeMetadata, which is
 // always one-to-one with nodes in a topology, and return a specific
 // representation of the referenced node, in the form of a node ID and a
@@ -63,7 +88,7 @@ func MapEndpointIdentity(m report.NodeMetadata) (RenderableNode, bool) {
 	var (
 		id    = MakeEndpointID(report.ExtractHostID(m), addr, port)
 		major = fmt.Sprintf("%s:%s", addr, port)
-		minor = report.ExtractHostID(m)
+		minor = hostLabel(m)
 		rank  = major
 	)
 
@@ -86,7 +111,7 @@ func MapProcessIdentity(m report.NodeMetadata) (RenderableNode, bool) {
 	var (
 		id    = MakeProcessID(report.ExtractHostID(m), pid)
 		major = m.Metadata["comm"]
-		minor = fmt.Sprintf("%s (%s)", report.ExtractHostID(m), pid)
+		minor = fmt.Sprintf("%s (%s)", hostLabel(m), pid)
 		rank  = m.Metadata["comm"]
 	)
 
@@ -104,7 +129,7 @@ func MapContainerIdentity(m report.NodeMetadata) (RenderableNode, bool) {
 
 	var (
 		major = m.Metadata[docker.ContainerName]
-		minor = report.ExtractHostID(m)
+		minor = hostLabel(m)
 		rank  = m.Metadata[docker.ImageID]
 	)
 
@@ -128,6 +153,87 @@ func MapContainerImageIdentity(m report.NodeMetadata) (RenderableNode, bool) {
 	return NewRenderableNode(id, major, "", rank, m), true
 }
 
+// MapServiceIdentity maps a service topology node to a service renderable
+// node. As it is only ever run on service topology nodes, we expect that
+// certain keys are present.
+func MapServiceIdentity(m report.NodeMetadata) (RenderableNode, bool) {
+	unit, ok := m.Metadata[systemd.Unit]
+	if !ok {
+		return RenderableNode{}, false
+	}
+
+	var (
+		id    = MakeServiceID(report.ExtractHostID(m), unit)
+		major = unit
+		minor = hostLabel(m)
+		rank  = unit
+	)
+
+	return NewRenderableNode(id, major, minor, rank, m), true
+}
+
+// MapPodIdentity maps a pod topology node to a pod renderable node. As it
+// is only ever run on pod topology nodes, we expect that certain keys are
+// present.
+func MapPodIdentity(m report.NodeMetadata) (RenderableNode, bool) {
+	name, ok := m.Metadata[kubernetes.PodName]
+	if !ok {
+		return RenderableNode{}, false
+	}
+
+	var (
+		namespace = m.Metadata[kubernetes.NamespaceName]
+		id        = MakePodID(namespace, name)
+		major     = name
+		minor     = namespace
+		rank      = namespace
+	)
+
+	return NewRenderableNode(id, major, minor, rank, m), true
+}
+
+// MapKubernetesServiceIdentity maps a Kubernetes service topology node to a
+// Kubernetes service renderable node. As it is only ever run on Kubernetes
+// service topology nodes, we expect that certain keys are present.
+func MapKubernetesServiceIdentity(m report.NodeMetadata) (RenderableNode, bool) {
+	name, ok := m.Metadata[kubernetes.ServiceName]
+	if !ok {
+		return RenderableNode{}, false
+	}
+
+	var (
+		namespace = m.Metadata[kubernetes.NamespaceName]
+		id        = MakeKubernetesServiceID(namespace, name)
+		major     = name
+		minor     = namespace
+		rank      = namespace
+	)
+
+	return NewRenderableNode(id, major, minor, rank, m), true
+}
+
+// MapOverlayIdentity maps an overlay topology node to an overlay renderable
+// node. As it is only ever run on overlay topology nodes, we expect that
+// certain keys are present.
+func MapOverlayIdentity(m report.NodeMetadata) (RenderableNode, bool) {
+	name, ok := m.Metadata[overlay.WeavePeerName]
+	if !ok {
+		return RenderableNode{}, false
+	}
+
+	var (
+		id    = MakeOverlayID(name)
+		major = m.Metadata[overlay.WeavePeerNickName]
+		minor = name
+		rank  = name
+	)
+	if major == "" {
+		major = name
+	}
+
+	return NewRenderableNode(id, major, minor, rank, m), true
+}
+
 // MapAddressIdentity maps an address topology node to an address renderable
 // node. As it is only ever run on address topology nodes, we expect that
 // certain keys are present.
@@ -140,7 +246,7 @@ func MapAddressIdentity(m report.NodeMetadata) (RenderableNode, bool) {
 	var (
 		id    = MakeAddressID(report.ExtractHostID(m), addr)
 		major = addr
-		minor = report.ExtractHostID(m)
+		minor = hostLabel(m)
 		rank  = major
 	)
 
@@ -223,13 +329,103 @@ func MapProcess2Container(n RenderableNode) (RenderableNode, bool) {
 		hostID := report.ExtractHostID(n.NodeMetadata)
 		id = MakePseudoNodeID(UncontainedID, hostID)
 		node := newDerivedPseudoNode(id, UncontainedMajor, n)
-		node.LabelMinor = hostID
+		node.LabelMinor = hostLabel(n.NodeMetadata)
+		return node, true
+	}
+
+	return newDerivedNode(id, n), true
+}
+
+// MapProcess2Service maps process RenderableNodes to service
+// RenderableNodes.
+//
+// If this function is given a node without a systemd_unit (including other
+// pseudo nodes), it will produce an "Unmanaged" pseudo node.
+//
+// Otherwise, this function will produce a node with the correct ID
+// format for a service, but without any Major or Minor labels.
+// It does not have enough info to do that, and the resulting graph
+// must be merged with a service graph to get that info.
+func MapProcess2Service(n RenderableNode) (RenderableNode, bool) {
+	// Propogate the internet pseudo node
+	if n.ID == TheInternetID {
+		return n, true
+	}
+
+	// Don't propogate non-internet pseudo nodes
+	if n.Pseudo {
+		return n, false
+	}
+
+	hostID := report.ExtractHostID(n.NodeMetadata)
+	unit, ok := n.NodeMetadata.Metadata[systemd.Unit]
+	if !ok {
+		id := MakePseudoNodeID(UnmanagedID, hostID)
+		node := newDerivedPseudoNode(id, UnmanagedMajor, n)
+		node.LabelMinor = hostLabel(n.NodeMetadata)
+		return node, true
+	}
+
+	return newDerivedNode(MakeServiceID(hostID, unit), n), true
+}
+
+// MapContainer2Pod maps container RenderableNodes to pod RenderableNodes.
+//
+// If this function is given a pseudo node, then it will just return it.
+// Containers that aren't in a pod are grouped into a per-host "Unmanaged"
+// pseudo node.
+//
+// Otherwise, this function will produce a node with the correct ID
+// format for a pod, but without any Major or Minor labels.
+// It does not have enough info to do that, and the resulting graph
+// must be merged with a pod graph to get that info.
+func MapContainer2Pod(n RenderableNode) (RenderableNode, bool) {
+	if n.Pseudo {
+		return n, true
+	}
+
+	name, ok := n.NodeMetadata.Metadata[kubernetes.PodName]
+	if !ok {
+		hostID := report.ExtractHostID(n.NodeMetadata)
+		id := MakePseudoNodeID(UnmanagedID, hostID)
+		node := newDerivedPseudoNode(id, UnmanagedMajor, n)
+		node.LabelMinor = hostLabel(n.NodeMetadata)
 		return node, true
 	}
 
+	id := MakePodID(n.NodeMetadata.Metadata[kubernetes.NamespaceName], name)
 	return newDerivedNode(id, n), true
 }
 
+// MapPod2KubernetesService maps pod RenderableNodes to Kubernetes service
+// RenderableNodes. Pods in more than one service are mapped to the first,
+// by name.
+//
+// If this function is given a pseudo node, then it will just return it.
+// Pods that no service selects are grouped into a per-namespace
+// "Unexposed" pseudo node.
+//
+// Otherwise, this function will produce a node with the correct ID
+// format for a Kubernetes service, but without any Major or Minor labels.
+// It does not have enough info to do that, and the resulting graph
+// must be merged with a Kubernetes service graph to get that info.
+func MapPod2KubernetesService(n RenderableNode) (RenderableNode, bool) {
+	if n.Pseudo {
+		return n, true
+	}
+
+	namespace := n.NodeMetadata.Metadata[kubernetes.NamespaceName]
+	services := strings.Fields(n.NodeMetadata.Metadata[kubernetes.PodServices])
+	if len(services) == 0 {
+		id := MakePseudoNodeID(UnexposedID, namespace)
+		node := newDerivedPseudoNode(id, UnexposedMajor, n)
+		node.LabelMinor = namespace
+		return node, true
+	}
+
+	return newDerivedNode(MakeKubernetesServiceID(namespace, services[0]), n), true
+}
+
 // MapProcess2Name maps process RenderableNodes to RenderableNodes
 // for each process name.
 //
@@ -252,6 +448,29 @@ func MapProcess2Name(n RenderableNode) (RenderableNode, bool) {
 	return node, true
 }
 
+// MapProcess2ServiceName maps process RenderableNodes to RenderableNodes
+// for each service, going by the service names the probe gives processes
+// (see process.ServiceTagger), or failing that, by their names, like
+// MapProcess2Name. So, unlike by name, java and python processes running
+// different services are kept apart.
+func MapProcess2ServiceName(n RenderableNode) (RenderableNode, bool) {
+	if n.Pseudo {
+		return n, true
+	}
+
+	name, ok := n.NodeMetadata.Metadata[process.ServiceName]
+	if !ok {
+		if name, ok = n.NodeMetadata.Metadata[process.Comm]; !ok {
+			return RenderableNode{}, false
+		}
+	}
+
+	node := newDerivedNode(name, n)
+	node.LabelMajor = name
+	node.Rank = name
+	return node, true
+}
+
 // MapContainer2ContainerImage maps container RenderableNodes to container
 // image RenderableNodes.
 //
@@ -306,6 +525,85 @@ func MapContainerImage2Name(n RenderableNode) (RenderableNode, bool) {
 	return node, true
 }
 
+// MapContainer2Label returns a MapFunc which maps container RenderableNodes
+// to RenderableNodes for each value of the given Docker label.
+//
+// Like MapContainerImage2Name, it outputs properly-formed nodes. Containers
+// without the label are dropped.
+func MapContainer2Label(label string) MapFunc {
+	return func(n RenderableNode) (RenderableNode, bool) {
+		if n.Pseudo {
+			return n, true
+		}
+
+		value, ok := n.NodeMetadata.Metadata[docker.LabelPrefix+label]
+		if !ok {
+			return RenderableNode{}, false
+		}
+
+		node := newDerivedNode(value, n)
+		node.LabelMajor = value
+		node.LabelMinor = label
+		node.Rank = value
+		return node, true
+	}
+}
+
+// MapContainer2ServiceName maps container RenderableNodes to
+// RenderableNodes for each Docker Compose or Swarm service, going by the
+// labels Swarm and Compose give containers, or failing that, by their names
+// (see ContainerServicePatterns). Containers that don't look like they're
+// part of a service are kept as they are, under their own names.
+//
+// Like MapContainerImage2Name, it outputs properly-formed nodes.
+func MapContainer2ServiceName(n RenderableNode) (RenderableNode, bool) {
+	if n.Pseudo {
+		return n, true
+	}
+
+	var (
+		labels  = docker.Labels(n.NodeMetadata)
+		service = n.LabelMajor
+		group   string
+	)
+	if name, ok := labels[docker.SwarmServiceLabel]; ok {
+		service, group = name, labels[docker.SwarmStackLabel]
+	} else if name, ok := labels[docker.ComposeServiceLabel]; ok {
+		service, group = name, labels[docker.ComposeProjectLabel]
+	} else {
+		for _, pattern := range ContainerServicePatterns {
+			if submatches := pattern.FindStringSubmatch(service); submatches != nil {
+				for i, name := range pattern.SubexpNames() {
+					switch name {
+					case "service":
+						service = submatches[i]
+					case "project":
+						group = submatches[i]
+					}
+				}
+				break
+			}
+		}
+	}
+	if service == "" {
+		return RenderableNode{}, false
+	}
+
+	// Whether or not they're labelled, and whichever version of Compose
+	// named them, the containers of a service get the same ID. Swarm
+	// service names already start with the stack's.
+	id := service
+	if group != "" && !strings.HasPrefix(service, group+"_") {
+		id = group + "_" + service
+	}
+
+	node := newDerivedNode(id, n)
+	node.LabelMajor = service
+	node.LabelMinor = group
+	node.Rank = id
+	return node, true
+}
+
 // MapAddress2Host maps address RenderableNodes to host RenderableNodes.
 //
 // Otherthan pseudo nodes, we can assume all nodes have a HostID
@@ -348,6 +646,15 @@ func PanicPseudoNode(src string, srcMapped RenderableNode, dst string, local rep
 	panic(dst)
 }
 
+// hostLabel is the name of the host a node is from, for labels. Nodes from
+// older probes aren't tagged with it, so it falls back to the host ID.
+func hostLabel(m report.NodeMetadata) string {
+	if name, ok := m.Metadata[host.HostName]; ok {
+		return name
+	}
+	return report.ExtractHostID(m)
+}
+
 // trySplitAddr is basically ParseArbitraryNodeID, since its callsites
 // (pseudo funcs) just have opaque node IDs and don't know what topology they
 // come from. Without changing how pseudo funcs work, we can't make it much
@@ -5,6 +5,8 @@ import (
 
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/kubernetes"
 	"github.com/weaveworks/scope/probe/process"
 	"github.com/weaveworks/scope/render"
 	"github.com/weaveworks/scope/report"
@@ -31,6 +33,24 @@ func TestMapProcessIdentity(t *testing.T) {
 	}
 }
 
+func TestMapProcessIdentityHostName(t *testing.T) {
+	// Nodes are identified by host ID, but labelled with the hostname.
+	have, ok := render.MapProcessIdentity(report.MakeNodeMetadataWith(map[string]string{
+		process.PID:       "201",
+		report.HostNodeID: report.MakeHostNodeID("e2f0c9a8"),
+		host.HostName:     "server.example.com",
+	}))
+	if !ok {
+		t.Fatal("expected a node")
+	}
+	if want := render.MakeProcessID("e2f0c9a8", "201"); have.ID != want {
+		t.Errorf("want ID %q, have %q", want, have.ID)
+	}
+	if want := "server.example.com (201)"; have.LabelMinor != want {
+		t.Errorf("want minor label %q, have %q", want, have.LabelMinor)
+	}
+}
+
 func TestMapContainerIdentity(t *testing.T) {
 	for _, input := range []testcase{
 		{report.MakeNodeMetadata(), false},
@@ -66,6 +86,81 @@ func TestMapHostIdentity(t *testing.T) {
 	}
 }
 
+func TestMapPodIdentity(t *testing.T) {
+	for _, input := range []testcase{
+		{report.MakeNodeMetadata(), false},
+		{report.MakeNodeMetadataWith(map[string]string{kubernetes.NamespaceName: "default", kubernetes.PodName: "web-1"}), true},
+	} {
+		testMap(t, render.MapPodIdentity, input)
+	}
+}
+
+func TestMapContainer2Pod(t *testing.T) {
+	for _, input := range []struct {
+		node   render.RenderableNode
+		wantID string
+	}{
+		{render.NewRenderableNode("a1b2c3", "", "", "", report.MakeNodeMetadataWith(map[string]string{kubernetes.NamespaceName: "default", kubernetes.PodName: "web-1"})), render.MakePodID("default", "web-1")},
+		{render.NewRenderableNode("a1b2c3", "", "", "", report.MakeNodeMetadataWith(map[string]string{report.HostNodeID: report.MakeHostNodeID("foo")})), render.MakePseudoNodeID(render.UnmanagedID, "foo")},
+		{render.RenderableNode{ID: render.TheInternetID, Pseudo: true}, render.TheInternetID},
+	} {
+		have, ok := render.MapContainer2Pod(input.node)
+		if !ok || have.ID != input.wantID {
+			t.Errorf("%v: want %q, have %q %v", input.node.NodeMetadata, input.wantID, have.ID, ok)
+		}
+	}
+}
+
+func TestMapContainer2Label(t *testing.T) {
+	mapper := render.MapContainer2Label("tier")
+	for _, input := range []struct {
+		node   render.RenderableNode
+		wantID string
+		ok     bool
+	}{
+		{render.NewRenderableNode("a1b2c3", "", "", "", report.MakeNodeMetadataWith(map[string]string{docker.LabelPrefix + "tier": "frontend"})), "frontend", true},
+		{render.NewRenderableNode("a1b2c3", "", "", "", report.MakeNodeMetadataWith(map[string]string{docker.LabelPrefix + "app": "frontend"})), "", false},
+		{render.NewRenderableNode("a1b2c3", "", "", "", report.MakeNodeMetadata()), "", false},
+		{render.RenderableNode{ID: render.TheInternetID, Pseudo: true}, render.TheInternetID, true},
+	} {
+		have, ok := mapper(input.node)
+		if ok != input.ok || (ok && have.ID != input.wantID) {
+			t.Errorf("%v: want %q %v, have %q %v", input.node.NodeMetadata, input.wantID, input.ok, have.ID, ok)
+		}
+	}
+}
+
+func TestMapContainer2ServiceName(t *testing.T) {
+	container := func(name string, labels map[string]string) render.RenderableNode {
+		md := report.MakeNodeMetadata()
+		for k, v := range labels {
+			md.Metadata[docker.LabelPrefix+k] = v
+		}
+		return render.NewRenderableNode("a1b2c3", name, "", "", md)
+	}
+	for _, input := range []struct {
+		node                     render.RenderableNode
+		wantID, wantMajor, group string
+	}{
+		{container("proj_web_1", map[string]string{docker.ComposeProjectLabel: "proj", docker.ComposeServiceLabel: "web"}), "proj_web", "web", "proj"},
+		{container("stack_web.1.0123456789abcdefghijklmno", map[string]string{docker.SwarmServiceLabel: "stack_web", docker.SwarmStackLabel: "stack"}), "stack_web", "stack_web", "stack"},
+		{container("web.2.0123456789abcdefghijklmno", nil), "web", "web", ""},
+		{container("proj-web-1", map[string]string{docker.ComposeProjectLabel: "proj", docker.ComposeServiceLabel: "web"}), "proj_web", "web", "proj"},
+		{container("proj_web_2", nil), "proj_web", "web", "proj"},
+		{container("proj-web-3", nil), "proj_web", "web", "proj"},
+		{container("proj-web-api-1", nil), "proj_web-api", "web-api", "proj"},
+		{container("redis", nil), "redis", "redis", ""},
+		{container("redis-6", nil), "redis-6", "redis-6", ""},
+		{container("redis_6", nil), "redis_6", "redis_6", ""},
+		{render.RenderableNode{ID: render.TheInternetID, Pseudo: true}, render.TheInternetID, "", ""},
+	} {
+		have, ok := render.MapContainer2ServiceName(input.node)
+		if !ok || have.ID != input.wantID || have.LabelMajor != input.wantMajor || have.LabelMinor != input.group {
+			t.Errorf("%s: want %q (%q, %q), have %q (%q, %q) %v", input.node.LabelMajor, input.wantID, input.wantMajor, input.group, have.ID, have.LabelMajor, have.LabelMinor, ok)
+		}
+	}
+}
+
 type testcase struct {
 	md report.NodeMetadata
 	ok bool
@@ -2,7 +2,13 @@ package render
 
 import (
 	"log"
+	"strconv"
+	"time"
 
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/kubernetes"
 	"github.com/weaveworks/scope/report"
 )
 
@@ -31,9 +37,49 @@ type LeafMap struct {
 	Pseudo   PseudoFunc
 }
 
-// FilterUnconnected is a Renderer which filters out unconnected nodes.
+// FilterUnconnected is a Renderer which filters out unconnected nodes. If
+// KeepListening is set, nodes which accept connections are kept even when
+// they have no peers.
 type FilterUnconnected struct {
 	Renderer
+	KeepListening bool
+}
+
+// FilterStopped is a Renderer which filters out nodes none of whose
+// container origins are running: those which have exited, died, or were
+// never started. It works on any view of containers, such as containers
+// grouped by image. Nodes without container origins are kept.
+type FilterStopped struct {
+	Renderer
+}
+
+// FilterOrigins is a Renderer which filters out nodes none of whose origins
+// in the selected topology satisfy Keep. Nodes without origins in that
+// topology, such as pseudo nodes, are kept. Edges to the nodes filtered out
+// are dropped.
+type FilterOrigins struct {
+	Renderer
+	Selector report.TopologySelector
+	Keep     func(report.NodeMetadata) bool
+}
+
+// FilterHostLabel is a Renderer which filters out nodes none of whose
+// origins are on a host with the label Key set to Value. Like
+// FilterOrigins, nodes without origins, such as pseudo nodes, are kept, and
+// edges to the nodes filtered out are dropped.
+type FilterHostLabel struct {
+	Renderer
+	Key   string
+	Value string
+}
+
+// GroupByHostLabel is a Renderer which merges the nodes on hosts with the
+// same value for the label Key, so any view can be seen by, say,
+// environment or zone. Nodes on hosts without the label are merged into an
+// Unlabelled pseudo node.
+type GroupByHostLabel struct {
+	Renderer
+	Key string
 }
 
 // MakeReduce is the only sane way to produce a Reduce Renderer.
@@ -170,7 +216,10 @@ func (m LeafMap) Render(rpt report.Report) RenderableNodes {
 
 		origins := mapped.Origins
 		origins = origins.Add(nodeID)
-		origins = origins.Add(metadata.Metadata[report.HostNodeID])
+		if hostNodeID, ok := metadata.Metadata[report.HostNodeID]; ok {
+			// Cluster-wide nodes, such as pods, have no origin host.
+			origins = origins.Add(hostNodeID)
+		}
 		mapped.Origins = origins
 
 		nodes[mapped.ID] = mapped
@@ -246,7 +295,161 @@ func (m LeafMap) EdgeMetadata(rpt report.Report, srcRenderableID, dstRenderableI
 
 // Render produces a set of RenderableNodes given a Report
 func (f FilterUnconnected) Render(rpt report.Report) RenderableNodes {
-	return OnlyConnected(f.Renderer.Render(rpt))
+	input := f.Renderer.Render(rpt)
+	output := OnlyConnected(input)
+	if f.KeepListening {
+		for id, node := range input {
+			if _, ok := node.NodeMetadata.Metadata[endpoint.Listening]; ok {
+				output[id] = node
+			}
+		}
+	}
+	return output
+}
+
+// Render produces a set of RenderableNodes given a Report
+func (f FilterStopped) Render(rpt report.Report) RenderableNodes {
+	return FilterOrigins{Renderer: f.Renderer, Selector: report.SelectContainer, Keep: ContainerRunning}.Render(rpt)
+}
+
+// Render produces a set of RenderableNodes given a Report
+func (f FilterOrigins) Render(rpt report.Report) RenderableNodes {
+	var (
+		topology = f.Selector(rpt)
+		input    = f.Renderer.Render(rpt)
+		output   = RenderableNodes{}
+	)
+	for id, node := range input {
+		found, keep := false, false
+		for _, origin := range node.Origins {
+			if nmd, ok := topology.NodeMetadatas[origin]; ok {
+				found = true
+				if keep = f.Keep(nmd); keep {
+					break
+				}
+			}
+		}
+		if found && !keep {
+			continue
+		}
+		output[id] = node
+	}
+	return withoutDanglingEdges(output)
+}
+
+// Render produces a set of RenderableNodes given a Report
+func (f FilterHostLabel) Render(rpt report.Report) RenderableNodes {
+	output := RenderableNodes{}
+	for id, node := range f.Renderer.Render(rpt) {
+		if len(node.Origins) > 0 && !originHostLabels(rpt, node, f.Key).Contains(f.Value) {
+			continue
+		}
+		output[id] = node
+	}
+	return withoutDanglingEdges(output)
+}
+
+// Render produces a set of RenderableNodes given a Report
+func (g GroupByHostLabel) Render(rpt report.Report) RenderableNodes {
+	return g.mapped(rpt).Render(rpt)
+}
+
+// EdgeMetadata produces an EdgeMetadata for a given edge.
+func (g GroupByHostLabel) EdgeMetadata(rpt report.Report, srcRenderableID, dstRenderableID string) report.EdgeMetadata {
+	return g.mapped(rpt).EdgeMetadata(rpt, srcRenderableID, dstRenderableID)
+}
+
+// mapped is the Map which does the grouping. The host labels aren't in
+// the nodes being grouped, as mapping drops metadata, so it needs the
+// report to look them up in.
+func (g GroupByHostLabel) mapped(rpt report.Report) Map {
+	return Map{
+		Renderer: g.Renderer,
+		MapFunc: func(n RenderableNode) (RenderableNode, bool) {
+			if n.Pseudo {
+				return n, true
+			}
+			values := originHostLabels(rpt, n, g.Key)
+			if len(values) == 0 {
+				return newDerivedPseudoNode(MakePseudoNodeID(UnlabelledID, g.Key), UnlabelledMajor, n), true
+			}
+			node := newDerivedNode(MakeHostLabelID(g.Key, values[0]), n)
+			node.LabelMajor = values[0]
+			node.LabelMinor = g.Key
+			node.Rank = values[0]
+			return node, true
+		},
+	}
+}
+
+// originHostLabels returns the values, sorted, of the host label on the
+// origins of the node. The host tagger puts host labels on every node on a
+// host, so it doesn't matter which topology the origins are in. Pods are
+// cluster-wide, so aren't tagged; they take the labels of the host of the
+// Kubernetes node they're scheduled on, if it runs a probe.
+func originHostLabels(rpt report.Report, n RenderableNode, key string) report.IDList {
+	values := report.MakeIDList()
+	for _, origin := range n.Origins {
+		for _, topology := range rpt.Topologies() {
+			nmd, ok := topology.NodeMetadatas[origin]
+			if !ok {
+				continue
+			}
+			if value, ok := nmd.Metadata[host.LabelPrefix+key]; ok {
+				values = values.Add(value)
+			}
+			if nodeName, ok := nmd.Metadata[kubernetes.PodNodeName]; ok {
+				for _, hostMetadata := range rpt.Host.NodeMetadatas {
+					if hostMetadata.Metadata[host.HostName] != nodeName {
+						continue
+					}
+					if value, ok := hostMetadata.Metadata[host.LabelPrefix+key]; ok {
+						values = values.Add(value)
+					}
+				}
+			}
+		}
+	}
+	return values
+}
+
+// withoutDanglingEdges removes the edges to nodes which aren't there.
+func withoutDanglingEdges(output RenderableNodes) RenderableNodes {
+	for id, node := range output {
+		adjacency := report.MakeIDList()
+		for _, dst := range node.Adjacency {
+			if _, ok := output[dst]; ok {
+				adjacency = adjacency.Add(dst)
+			}
+		}
+		node.Adjacency = adjacency
+		output[id] = node
+	}
+	return output
+}
+
+// ImageInUse is true for container images with running containers.
+func ImageInUse(nmd report.NodeMetadata) bool {
+	running, err := strconv.Atoi(nmd.Metadata[docker.ImageRunningContainers])
+	return err == nil && running > 0
+}
+
+// ContainerRunning is true for containers which are running or paused.
+func ContainerRunning(nmd report.NodeMetadata) bool {
+	switch nmd.Metadata[docker.ContainerState] {
+	case "created", "exited", "dead":
+		return false
+	}
+	return true
+}
+
+// ImageCreatedBefore returns a predicate which is true for container images
+// created before t.
+func ImageCreatedBefore(t time.Time) func(report.NodeMetadata) bool {
+	return func(nmd report.NodeMetadata) bool {
+		created, err := time.Parse(time.RFC822, nmd.Metadata[docker.ImageCreated])
+		return err == nil && created.Before(t)
+	}
 }
 
 // OnlyConnected filters out unconnected RenderedNodes
@@ -3,7 +3,12 @@ package render_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/kubernetes"
 	"github.com/weaveworks/scope/render"
 	"github.com/weaveworks/scope/report"
 )
@@ -147,6 +152,69 @@ func TestMapEdge(t *testing.T) {
 	}
 }
 
+func TestMapEdgeDirection(t *testing.T) {
+	selector := func(_ report.Report) report.Topology {
+		return report.Topology{
+			NodeMetadatas: report.NodeMetadatas{
+				"client": report.MakeNodeMetadataWith(map[string]string{"id": "client"}),
+				"server": report.MakeNodeMetadataWith(map[string]string{"id": "server"}),
+			},
+			Adjacency: report.Adjacency{
+				">client": report.MakeIDList("server"),
+				">server": report.MakeIDList("client"),
+			},
+			EdgeMetadatas: report.EdgeMetadatas{
+				"client|server": report.EdgeMetadata{Direction: report.Outbound},
+				"server|client": report.EdgeMetadata{Direction: report.Inbound},
+			},
+		}
+	}
+
+	identity := func(nmd report.NodeMetadata) (render.RenderableNode, bool) {
+		return render.NewRenderableNode(nmd.Metadata["id"], "", "", "", nmd), true
+	}
+
+	mapper := render.Map{
+		MapFunc: func(node render.RenderableNode) (render.RenderableNode, bool) {
+			return render.RenderableNode{ID: "_" + node.ID, EdgeMetadata: node.EdgeMetadata}, true
+		},
+		Renderer: render.LeafMap{
+			Selector: selector,
+			Mapper:   identity,
+			Pseudo:   nil,
+		},
+	}
+
+	nodes := mapper.Render(report.MakeReport())
+	for id, want := range map[string]report.Direction{
+		"_client": report.Outbound,
+		"_server": report.Inbound,
+	} {
+		if have := nodes[id].EdgeMetadata.Direction; want != have {
+			t.Errorf("%s: want %s, have %s", id, want, have)
+		}
+	}
+	for _, tc := range []struct {
+		src, dst string
+		want     report.Direction
+	}{
+		{"_client", "_server", report.Outbound},
+		{"_server", "_client", report.Inbound},
+	} {
+		if have := mapper.EdgeMetadata(report.MakeReport(), tc.src, tc.dst).Direction; tc.want != have {
+			t.Errorf("%s -> %s: want %s, have %s", tc.src, tc.dst, tc.want, have)
+		}
+	}
+
+	detailed := render.MakeDetailedNode(report.MakeReport(), render.RenderableNode{
+		EdgeMetadata: report.EdgeMetadata{Direction: report.Inbound | report.Outbound},
+	})
+	want := render.Row{Key: "Direction", ValueMajor: "inbound, outbound"}
+	if len(detailed.Tables) == 0 || !reflect.DeepEqual([]render.Row{want}, detailed.Tables[0].Rows) {
+		t.Errorf("want %+v, have %+v", want, detailed.Tables)
+	}
+}
+
 func TestFilterRender(t *testing.T) {
 	renderer := render.FilterUnconnected{
 		Renderer: mockRenderer{RenderableNodes: render.RenderableNodes{
@@ -165,4 +233,189 @@ func TestFilterRender(t *testing.T) {
 	}
 }
 
+func TestFilterRenderKeepListening(t *testing.T) {
+	listening := report.MakeNodeMetadataWith(map[string]string{endpoint.Listening: "tcp"})
+	renderer := render.FilterUnconnected{
+		Renderer: mockRenderer{RenderableNodes: render.RenderableNodes{
+			"foo": {ID: "foo", Adjacency: report.MakeIDList("bar")},
+			"bar": {ID: "bar", Adjacency: report.MakeIDList("foo")},
+			"baz": {ID: "baz", Adjacency: report.MakeIDList()},
+			"qux": {ID: "qux", Adjacency: report.MakeIDList(), NodeMetadata: listening},
+		}},
+		KeepListening: true,
+	}
+	want := render.RenderableNodes{
+		"foo": {ID: "foo", Adjacency: report.MakeIDList("bar")},
+		"bar": {ID: "bar", Adjacency: report.MakeIDList("foo")},
+		"qux": {ID: "qux", Adjacency: report.MakeIDList(), NodeMetadata: listening},
+	}
+	have := renderer.Render(report.MakeReport())
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestFilterStopped(t *testing.T) {
+	rpt := report.MakeReport()
+	for id, state := range map[string]string{
+		"running": "running",
+		"paused":  "paused",
+		"exited":  "exited",
+		"created": "created",
+	} {
+		rpt.Container.NodeMetadatas[id] = report.MakeNodeMetadataWith(map[string]string{docker.ContainerState: state})
+	}
+	renderer := render.FilterStopped{
+		Renderer: mockRenderer{RenderableNodes: render.RenderableNodes{
+			"foo":    {ID: "foo", Origins: report.MakeIDList("running")},
+			"bar":    {ID: "bar", Origins: report.MakeIDList("paused")},
+			"baz":    {ID: "baz", Origins: report.MakeIDList("exited")},
+			"qux":    {ID: "qux", Origins: report.MakeIDList("created")},
+			"image":  {ID: "image", Origins: report.MakeIDList("exited", "running")},
+			"stale":  {ID: "stale", Origins: report.MakeIDList("exited", "created")},
+			"pseudo": {ID: "pseudo", Pseudo: true},
+		}},
+	}
+	want := render.RenderableNodes{
+		"foo":    {ID: "foo", Origins: report.MakeIDList("running")},
+		"bar":    {ID: "bar", Origins: report.MakeIDList("paused")},
+		"image":  {ID: "image", Origins: report.MakeIDList("exited", "running")},
+		"pseudo": {ID: "pseudo", Pseudo: true},
+	}
+	have := renderer.Render(rpt)
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestFilterOrigins(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.ContainerImage.NodeMetadatas["used"] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ImageRunningContainers: "2",
+		docker.ImageCreated:           "19 Aug 15 16:00 UTC",
+	})
+	rpt.ContainerImage.NodeMetadatas["unused"] = report.MakeNodeMetadataWith(map[string]string{
+		docker.ImageRunningContainers: "0",
+		docker.ImageCreated:           "19 Aug 15 16:00 UTC",
+	})
+	renderer := render.FilterOrigins{
+		Renderer: mockRenderer{RenderableNodes: render.RenderableNodes{
+			"foo":    {ID: "foo", Origins: report.MakeIDList("used", "unused"), Adjacency: report.MakeIDList("bar", "pseudo")},
+			"bar":    {ID: "bar", Origins: report.MakeIDList("unused"), Adjacency: report.MakeIDList("foo")},
+			"pseudo": {ID: "pseudo", Pseudo: true, Adjacency: report.MakeIDList("bar")},
+		}},
+		Selector: report.SelectContainerImage,
+		Keep:     render.ImageInUse,
+	}
+	want := render.RenderableNodes{
+		"foo":    {ID: "foo", Origins: report.MakeIDList("used", "unused"), Adjacency: report.MakeIDList("pseudo")},
+		"pseudo": {ID: "pseudo", Pseudo: true, Adjacency: report.MakeIDList()},
+	}
+	have := renderer.Render(rpt)
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+
+	for _, tc := range []struct {
+		before time.Time
+		want   bool
+	}{
+		{time.Date(2015, 8, 19, 16, 1, 0, 0, time.UTC), true},
+		{time.Date(2015, 8, 19, 16, 0, 0, 0, time.UTC), false},
+	} {
+		if have := render.ImageCreatedBefore(tc.before)(rpt.ContainerImage.NodeMetadatas["used"]); tc.want != have {
+			t.Errorf("created before %s: want %v, have %v", tc.before, tc.want, have)
+		}
+	}
+}
+
+func TestFilterHostLabel(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Process.NodeMetadatas["prod;1"] = report.MakeNodeMetadataWith(map[string]string{host.LabelPrefix + "env": "prod"})
+	rpt.Process.NodeMetadatas["staging;1"] = report.MakeNodeMetadataWith(map[string]string{host.LabelPrefix + "env": "staging"})
+	rpt.Process.NodeMetadatas["unlabelled;1"] = report.MakeNodeMetadata()
+	renderer := render.FilterHostLabel{
+		Renderer: mockRenderer{RenderableNodes: render.RenderableNodes{
+			"foo":    {ID: "foo", Origins: report.MakeIDList("prod;1"), Adjacency: report.MakeIDList("bar", "baz", "pseudo")},
+			"bar":    {ID: "bar", Origins: report.MakeIDList("staging;1"), Adjacency: report.MakeIDList("foo")},
+			"baz":    {ID: "baz", Origins: report.MakeIDList("unlabelled;1")},
+			"pseudo": {ID: "pseudo", Pseudo: true, Adjacency: report.MakeIDList("foo", "bar")},
+		}},
+		Key:   "env",
+		Value: "prod",
+	}
+	want := render.RenderableNodes{
+		"foo":    {ID: "foo", Origins: report.MakeIDList("prod;1"), Adjacency: report.MakeIDList("pseudo")},
+		"pseudo": {ID: "pseudo", Pseudo: true, Adjacency: report.MakeIDList("foo")},
+	}
+	have := renderer.Render(rpt)
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+
+	// Pods aren't tagged with a host, but take the labels of the host
+	// they're scheduled on.
+	rpt.Host.NodeMetadatas["node1;<host>"] = report.MakeNodeMetadataWith(map[string]string{
+		host.HostName:            "node1",
+		host.LabelPrefix + "env": "prod",
+	})
+	rpt.Pod.NodeMetadatas["default;web"] = report.MakeNodeMetadataWith(map[string]string{kubernetes.PodNodeName: "node1"})
+	rpt.Pod.NodeMetadatas["default;db"] = report.MakeNodeMetadataWith(map[string]string{kubernetes.PodNodeName: "node2"})
+	renderer.Renderer = mockRenderer{RenderableNodes: render.RenderableNodes{
+		"web": {ID: "web", Origins: report.MakeIDList("default;web")},
+		"db":  {ID: "db", Origins: report.MakeIDList("default;db")},
+	}}
+	want = render.RenderableNodes{
+		"web": {ID: "web", Origins: report.MakeIDList("default;web")},
+	}
+	have = renderer.Render(rpt)
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestGroupByHostLabel(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Process.NodeMetadatas["a;1"] = report.MakeNodeMetadataWith(map[string]string{host.LabelPrefix + "zone": "eu-west-1a"})
+	rpt.Process.NodeMetadatas["b;1"] = report.MakeNodeMetadataWith(map[string]string{host.LabelPrefix + "zone": "eu-west-1a"})
+	rpt.Container.NodeMetadatas["c;1"] = report.MakeNodeMetadata()
+	renderer := render.GroupByHostLabel{
+		Renderer: mockRenderer{RenderableNodes: render.RenderableNodes{
+			"foo":    {ID: "foo", Origins: report.MakeIDList("a;1"), Adjacency: report.MakeIDList("baz")},
+			"bar":    {ID: "bar", Origins: report.MakeIDList("b;1"), Adjacency: report.MakeIDList("pseudo")},
+			"baz":    {ID: "baz", Origins: report.MakeIDList("c;1")},
+			"pseudo": {ID: "pseudo", Pseudo: true},
+		}},
+		Key: "zone",
+	}
+	var (
+		zoneID       = render.MakeHostLabelID("zone", "eu-west-1a")
+		unlabelledID = render.MakePseudoNodeID(render.UnlabelledID, "zone")
+	)
+	have := renderer.Render(rpt)
+	if want, have := 3, len(have); want != have {
+		t.Fatalf("want %d nodes, have %d: %+v", want, have, renderer.Render(rpt))
+	}
+	zone := have[zoneID]
+	if want := "eu-west-1a"; zone.LabelMajor != want {
+		t.Errorf("want %q, have %q", want, zone.LabelMajor)
+	}
+	for _, tc := range []struct {
+		id                string
+		origins, adjacent report.IDList
+	}{
+		{zoneID, report.MakeIDList("a;1", "b;1"), report.MakeIDList(unlabelledID, "pseudo")},
+		{unlabelledID, report.MakeIDList("c;1"), report.MakeIDList()},
+		{"pseudo", nil, report.MakeIDList()},
+	} {
+		node := have[tc.id]
+		if !reflect.DeepEqual(tc.origins, node.Origins) {
+			t.Errorf("%s: want origins %v, have %v", tc.id, tc.origins, node.Origins)
+		}
+		if !reflect.DeepEqual(tc.adjacent, node.Adjacency) {
+			t.Errorf("%s: want adjacency %v, have %v", tc.id, tc.adjacent, node.Adjacency)
+		}
+	}
+}
+
 func newu64(value uint64) *uint64 { return &value }
@@ -1,6 +1,7 @@
 package render
 
 import (
+	"github.com/weaveworks/scope/probe/endpoint"
 	"github.com/weaveworks/scope/report"
 )
 
@@ -74,6 +75,12 @@ func NewRenderableNode(id, major, minor, rank string, nmd report.NodeMetadata) R
 }
 
 func newDerivedNode(id string, node RenderableNode) RenderableNode {
+	// Whether the node accepts connections survives mapping, so that
+	// FilterUnconnected can keep idle services around.
+	nmd := report.MakeNodeMetadata()
+	if transports, ok := node.NodeMetadata.Metadata[endpoint.Listening]; ok {
+		nmd.Metadata[endpoint.Listening] = transports
+	}
 	return RenderableNode{
 		ID:           id,
 		LabelMajor:   "",
@@ -82,7 +89,7 @@ func newDerivedNode(id string, node RenderableNode) RenderableNode {
 		Pseudo:       node.Pseudo,
 		EdgeMetadata: node.EdgeMetadata,
 		Origins:      node.Origins,
-		NodeMetadata: report.MakeNodeMetadata(),
+		NodeMetadata: nmd,
 	}
 }
 
@@ -32,6 +32,13 @@ var ProcessNameRenderer = Map{
 	Renderer: ProcessRenderer,
 }
 
+// ProcessServiceRenderer is a Renderer which produces a renderable process
+// service graph by munging the process graph.
+var ProcessServiceRenderer = Map{
+	MapFunc:  MapProcess2ServiceName,
+	Renderer: ProcessRenderer,
+}
+
 // ContainerRenderer is a Renderer which produces a renderable container
 // graph by merging the process graph and the container topology.
 var ContainerRenderer = MakeReduce(
@@ -63,6 +70,65 @@ var ContainerImageRenderer = Map{
 	),
 }
 
+// ContainerLabelRenderer returns a Renderer which produces a renderable
+// graph of containers grouped by the value of a Docker label.
+func ContainerLabelRenderer(label string) Renderer {
+	return Map{
+		MapFunc:  MapContainer2Label(label),
+		Renderer: ContainerRenderer,
+	}
+}
+
+// ContainerServiceRenderer is a Renderer which produces a renderable graph
+// of containers grouped into their Docker Compose or Swarm services.
+var ContainerServiceRenderer = Map{
+	MapFunc:  MapContainer2ServiceName,
+	Renderer: ContainerRenderer,
+}
+
+// ServiceRenderer is a Renderer which produces a renderable service graph
+// by merging the process graph and the service topology.
+var ServiceRenderer = MakeReduce(
+	Map{
+		MapFunc:  MapProcess2Service,
+		Renderer: ProcessRenderer,
+	},
+	LeafMap{
+		Selector: report.SelectService,
+		Mapper:   MapServiceIdentity,
+		Pseudo:   PanicPseudoNode,
+	},
+)
+
+// PodRenderer is a Renderer which produces a renderable pod graph by
+// merging the container graph and the pod topology.
+var PodRenderer = MakeReduce(
+	Map{
+		MapFunc:  MapContainer2Pod,
+		Renderer: ContainerRenderer,
+	},
+	LeafMap{
+		Selector: report.SelectPod,
+		Mapper:   MapPodIdentity,
+		Pseudo:   PanicPseudoNode,
+	},
+)
+
+// PodServiceRenderer is a Renderer which produces a renderable Kubernetes
+// service graph by merging the pod graph and the Kubernetes service
+// topology.
+var PodServiceRenderer = MakeReduce(
+	Map{
+		MapFunc:  MapPod2KubernetesService,
+		Renderer: PodRenderer,
+	},
+	LeafMap{
+		Selector: report.SelectKubernetesService,
+		Mapper:   MapKubernetesServiceIdentity,
+		Pseudo:   PanicPseudoNode,
+	},
+)
+
 // AddressRenderer is a Renderer which produces a renderable address
 // graph from the address topology.
 var AddressRenderer = LeafMap{
@@ -71,6 +137,15 @@ var AddressRenderer = LeafMap{
 	Pseudo:   GenericPseudoNode(report.AddressIDAddresser),
 }
 
+// OverlayRenderer is a Renderer which produces a renderable graph of the
+// peers of the overlay network, and the connections between them, from the
+// overlay topology.
+var OverlayRenderer = LeafMap{
+	Selector: report.SelectOverlay,
+	Mapper:   MapOverlayIdentity,
+	Pseudo:   PanicPseudoNode,
+}
+
 // HostRenderer is a Renderer which produces a renderable host
 // graph from the host topology and address graph.
 var HostRenderer = MakeReduce(
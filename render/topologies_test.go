@@ -35,6 +35,14 @@ func TestProcessNameRenderer(t *testing.T) {
 	}
 }
 
+func TestProcessServiceRenderer(t *testing.T) {
+	have := render.ProcessServiceRenderer.Render(test.Report)
+	have = trimNodeMetadata(have)
+	if !reflect.DeepEqual(expected.RenderedProcessServices, have) {
+		t.Error(test.Diff(expected.RenderedProcessServices, have))
+	}
+}
+
 func TestContainerRenderer(t *testing.T) {
 	have := render.ContainerRenderer.Render(test.Report)
 	have = trimNodeMetadata(have)
@@ -51,6 +59,46 @@ func TestContainerImageRenderer(t *testing.T) {
 	}
 }
 
+func TestContainerServiceRenderer(t *testing.T) {
+	have := render.ContainerServiceRenderer.Render(test.Report)
+	have = trimNodeMetadata(have)
+	if !reflect.DeepEqual(expected.RenderedContainerServices, have) {
+		t.Error(test.Diff(expected.RenderedContainerServices, have))
+	}
+}
+
+func TestServiceRenderer(t *testing.T) {
+	have := render.ServiceRenderer.Render(test.Report)
+	have = trimNodeMetadata(have)
+	if !reflect.DeepEqual(expected.RenderedServices, have) {
+		t.Error(test.Diff(expected.RenderedServices, have))
+	}
+}
+
+func TestPodRenderer(t *testing.T) {
+	have := render.PodRenderer.Render(test.Report)
+	have = trimNodeMetadata(have)
+	if !reflect.DeepEqual(expected.RenderedPods, have) {
+		t.Error(test.Diff(expected.RenderedPods, have))
+	}
+}
+
+func TestPodServiceRenderer(t *testing.T) {
+	have := render.PodServiceRenderer.Render(test.Report)
+	have = trimNodeMetadata(have)
+	if !reflect.DeepEqual(expected.RenderedPodServices, have) {
+		t.Error(test.Diff(expected.RenderedPodServices, have))
+	}
+}
+
+func TestOverlayRenderer(t *testing.T) {
+	have := render.OverlayRenderer.Render(test.Report)
+	have = trimNodeMetadata(have)
+	if !reflect.DeepEqual(expected.RenderedOverlay, have) {
+		t.Error(test.Diff(expected.RenderedOverlay, have))
+	}
+}
+
 func TestHostRenderer(t *testing.T) {
 	have := render.HostRenderer.Render(test.Report)
 	have = trimNodeMetadata(have)
@@ -1,6 +1,7 @@
 package report
 
 import (
+	"fmt"
 	"net"
 	"strings"
 )
@@ -21,6 +22,39 @@ const (
 	EdgeDelim = "|"
 )
 
+// ValidateIDComponent checks that s can be a part of a node ID, such as a
+// host ID, without being mistaken for more than one part, or for an
+// adjacency or edge ID.
+func ValidateIDComponent(s string) error {
+	if strings.Contains(s, ScopeDelim) || strings.Contains(s, EdgeDelim) {
+		return fmt.Errorf("%q contains %q or %q", s, ScopeDelim, EdgeDelim)
+	}
+	if strings.HasPrefix(s, ">") {
+		return fmt.Errorf("%q starts with %q", s, ">")
+	}
+	return nil
+}
+
+// idEscaper escapes the delimiters in the parts of node IDs which come from
+// names, such as pod and service names, so they can't be mistaken for more
+// than one part, or for an edge ID.
+var (
+	idEscaper   = strings.NewReplacer("%", "%25", ScopeDelim, "%3B", EdgeDelim, "%7C")
+	idUnescaper = strings.NewReplacer("%25", "%", "%3B", ScopeDelim, "%7C", EdgeDelim)
+)
+
+// escapeIDComponent escapes a part of a node ID which could contain the
+// delimiters. Host IDs aren't escaped; they're validated instead, with
+// ValidateIDComponent.
+func escapeIDComponent(s string) string {
+	return idEscaper.Replace(s)
+}
+
+// unescapeIDComponent reverses escapeIDComponent.
+func unescapeIDComponent(s string) string {
+	return idUnescaper.Replace(s)
+}
+
 // MakeAdjacencyID produces an adjacency ID from a node id.
 func MakeAdjacencyID(srcNodeID string) string {
 	return ">" + srcNodeID
@@ -50,28 +84,37 @@ func ParseEdgeID(edgeID string) (srcNodeID, dstNodeID string, ok bool) {
 
 // MakeEndpointNodeID produces an endpoint node ID from its composite parts.
 func MakeEndpointNodeID(hostID, address, port string) string {
-	return MakeAddressNodeID(hostID, address) + ScopeDelim + port
+	return MakeAddressNodeID(hostID, address) + ScopeDelim + escapeIDComponent(port)
 }
 
 // MakeAddressNodeID produces an address node ID from its composite parts.
 func MakeAddressNodeID(hostID, address string) string {
 	var scope string
 
-	// Loopback addresses and addresses explicity marked as
-	// local get scoped by hostID
+	// Loopback addresses, unspecified (wildcard) addresses and addresses
+	// explicity marked as local get scoped by hostID
 	addressIP := net.ParseIP(address)
 	if addressIP != nil && LocalNetworks.Contains(addressIP) {
 		scope = hostID
 	} else if isLoopback(address) {
 		scope = hostID
+	} else if addressIP != nil && addressIP.IsUnspecified() {
+		scope = hostID
 	}
 
-	return scope + ScopeDelim + address
+	return scope + ScopeDelim + escapeIDComponent(address)
+}
+
+// MakeNamespacedScope produces the scope for addresses which are private to
+// a network namespace on a host, such as loopback addresses inside a
+// container. Like a host ID, it passes ValidateIDComponent.
+func MakeNamespacedScope(hostID, namespaceID string) string {
+	return hostID + "/" + escapeIDComponent(namespaceID)
 }
 
 // MakeProcessNodeID produces a process node ID from its composite parts.
 func MakeProcessNodeID(hostID, pid string) string {
-	return hostID + ScopeDelim + pid
+	return hostID + ScopeDelim + escapeIDComponent(pid)
 }
 
 // MakeHostNodeID produces a host node ID from its composite parts.
@@ -84,23 +127,52 @@ func MakeHostNodeID(hostID string) string {
 
 // MakeContainerNodeID produces a container node ID from its composite parts.
 func MakeContainerNodeID(hostID, containerID string) string {
-	return hostID + ScopeDelim + containerID
+	return hostID + ScopeDelim + escapeIDComponent(containerID)
+}
+
+// MakeServiceNodeID produces a service node ID from its composite parts.
+func MakeServiceNodeID(hostID, unit string) string {
+	return hostID + ScopeDelim + escapeIDComponent(unit)
+}
+
+// MakePodNodeID produces a pod node ID from its composite parts. Pods are
+// cluster-wide, so aren't scoped by host.
+func MakePodNodeID(namespace, name string) string {
+	return escapeIDComponent(namespace) + ScopeDelim + escapeIDComponent(name)
+}
+
+// MakeKubernetesServiceNodeID produces a Kubernetes service node ID from its
+// composite parts.
+func MakeKubernetesServiceNodeID(namespace, name string) string {
+	return escapeIDComponent(namespace) + ScopeDelim + escapeIDComponent(name)
+}
+
+// MakeDeploymentNodeID produces a deployment node ID from its composite
+// parts.
+func MakeDeploymentNodeID(namespace, name string) string {
+	return escapeIDComponent(namespace) + ScopeDelim + escapeIDComponent(name)
+}
+
+// MakeNamespaceNodeID produces a namespace node ID from the namespace's
+// name.
+func MakeNamespaceNodeID(name string) string {
+	return escapeIDComponent(name) + ScopeDelim + "<namespace>"
 }
 
 // MakeOverlayNodeID produces an overlay topology node ID from a router peer's
 // name, which is assumed to be globally unique.
 func MakeOverlayNodeID(peerName string) string {
-	return "#" + peerName
+	return escapeIDComponent(peerName) + ScopeDelim + "<peer>"
 }
 
 // ParseNodeID produces the host ID and remainder (typically an address) from
-// a node ID. Note that hostID may be blank.
+// a node ID, unescaping the remainder. Note that hostID may be blank.
 func ParseNodeID(nodeID string) (hostID string, remainder string, ok bool) {
 	fields := strings.SplitN(nodeID, ScopeDelim, 2)
 	if len(fields) != 2 {
 		return "", "", false
 	}
-	return fields[0], fields[1], true
+	return fields[0], unescapeIDComponent(fields[1]), true
 }
 
 // ParseEndpointNodeID produces the host ID, address, and port and remainder
@@ -111,7 +183,7 @@ func ParseEndpointNodeID(endpointNodeID string) (hostID, address, port string, o
 	if len(fields) != 3 {
 		return "", "", "", false
 	}
-	return fields[0], fields[1], fields[2], true
+	return fields[0], unescapeIDComponent(fields[1]), unescapeIDComponent(fields[2]), true
 }
 
 // ExtractHostID extracts the host id from NodeMetadata
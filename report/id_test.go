@@ -96,6 +96,8 @@ func TestEndpointNodeID(t *testing.T) {
 
 	for input, want := range map[string]struct{ name, address, port string }{
 		report.MakeEndpointNodeID("host.com", "1.2.3.4", "c"): {"", "1.2.3.4", "c"},
+		report.MakeEndpointNodeID("host.com", "0.0.0.0", "c"): {"host.com", "0.0.0.0", "c"},
+		report.MakeEndpointNodeID("host.com", "::", "c"):      {"host.com", "::", "c"},
 		"a;b;c": {"a", "b", "c"},
 	} {
 		haveName, haveAddress, havePort, ok := report.ParseEndpointNodeID(input)
@@ -186,3 +188,63 @@ func TestAddressIDAddresser(t *testing.T) {
 		t.Errorf("want %s, have %s", want, have)
 	}
 }
+
+func TestValidateIDComponent(t *testing.T) {
+	for _, good := range []string{
+		"client.host.com",
+		"e2f0c9a8d5b34ea1b7c3c1f4f9a6d2e0",
+		"5b1f9b8e-4d3c-4c7e-9a9b-2b8e3f1d6c0a",
+		"",
+	} {
+		if err := report.ValidateIDComponent(good); err != nil {
+			t.Errorf("%q: %v", good, err)
+		}
+	}
+	for _, bad := range []string{
+		"client;host",
+		"client|host",
+		">client",
+	} {
+		if err := report.ValidateIDComponent(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+
+	topology := report.NewTopology()
+	topology.NodeMetadatas[report.MakeHostNodeID("client|host")] = report.MakeNodeMetadata()
+	if err := topology.Validate(); err == nil {
+		t.Errorf("expected node ID with %q to be invalid", report.EdgeDelim)
+	}
+}
+
+func TestMakeNamespacedScope(t *testing.T) {
+	for _, namespaceID := range []string{"4026532000", "net;1", "a|b", ">x"} {
+		scope := report.MakeNamespacedScope("host", namespaceID)
+		if err := report.ValidateIDComponent(scope); err != nil {
+			t.Errorf("%q: %v", namespaceID, err)
+		}
+	}
+	if a, b := report.MakeNamespacedScope("host", "1"), report.MakeNamespacedScope("host", "2"); a == b {
+		t.Errorf("namespaces 1 and 2 both have scope %q", a)
+	}
+}
+
+func TestNodeIDEscaping(t *testing.T) {
+	for _, name := range []string{"web;1", "a|b", "100%", "%3B", "plain"} {
+		nodeID := report.MakeContainerNodeID("host", name)
+		if hostID, remainder, ok := report.ParseNodeID(nodeID); !ok || hostID != "host" || remainder != name {
+			t.Errorf("%q: have %q, %q, %v", name, hostID, remainder, ok)
+		}
+	}
+
+	// Parts of IDs which come from names can't corrupt them.
+	topology := report.NewTopology()
+	topology.NodeMetadatas[report.MakePodNodeID("default", "web|1")] = report.MakeNodeMetadata()
+	topology.NodeMetadatas[report.MakeServiceNodeID("host", "weird;unit|name")] = report.MakeNodeMetadata()
+	if err := topology.Validate(); err != nil {
+		t.Error(err)
+	}
+	if a, b := report.MakePodNodeID("a;b", "c"), report.MakePodNodeID("a", "b;c"); a == b {
+		t.Errorf("pod IDs collide: %q", a)
+	}
+}
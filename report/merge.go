@@ -11,6 +11,11 @@ func (r *Report) Merge(other Report) {
 	r.Process.Merge(other.Process)
 	r.Container.Merge(other.Container)
 	r.ContainerImage.Merge(other.ContainerImage)
+	r.Service.Merge(other.Service)
+	r.Pod.Merge(other.Pod)
+	r.KubernetesService.Merge(other.KubernetesService)
+	r.Namespace.Merge(other.Namespace)
+	r.Deployment.Merge(other.Deployment)
 	r.Host.Merge(other.Host)
 	r.Overlay.Merge(other.Overlay)
 	r.Sampling.Merge(other.Sampling)
@@ -70,6 +75,8 @@ func (m *EdgeMetadata) Merge(other EdgeMetadata) {
 	m.EgressByteCount = merge(m.EgressByteCount, other.EgressByteCount, sum)
 	m.IngressByteCount = merge(m.IngressByteCount, other.IngressByteCount, sum)
 	m.MaxConnCountTCP = merge(m.MaxConnCountTCP, other.MaxConnCountTCP, max)
+	m.Direction |= other.Direction
+	m.ConnCountByState = mergeCounts(m.ConnCountByState, other.ConnCountByState, max)
 }
 
 // Flatten sums two EdgeMetadatas. Their windows should be the same duration;
@@ -82,6 +89,8 @@ func (m *EdgeMetadata) Flatten(other EdgeMetadata) {
 	// Note that summing of two maximums doesn't always give us the true
 	// maximum. But it's a best effort.
 	m.MaxConnCountTCP = merge(m.MaxConnCountTCP, other.MaxConnCountTCP, sum)
+	m.Direction |= other.Direction
+	m.ConnCountByState = mergeCounts(m.ConnCountByState, other.ConnCountByState, sum)
 }
 
 // Merge combines two sampling structures via simple addition.
@@ -101,6 +110,22 @@ func merge(dst, src *uint64, op func(uint64, uint64) uint64) *uint64 {
 	return dst
 }
 
+// mergeCounts always returns a fresh map when there's something to merge, as
+// EdgeMetadata is passed around by value and the maps would otherwise alias.
+func mergeCounts(dst, src map[string]uint64, op func(uint64, uint64) uint64) map[string]uint64 {
+	if len(src) == 0 {
+		return dst
+	}
+	result := make(map[string]uint64, len(dst)+len(src))
+	for k, v := range dst {
+		result[k] = v
+	}
+	for k, v := range src {
+		result[k] = op(result[k], v)
+	}
+	return result
+}
+
 func sum(dst, src uint64) uint64 {
 	return dst + src
 }
@@ -191,6 +191,26 @@ func TestMergeEdgeMetadatas(t *testing.T) {
 				},
 			},
 		},
+		"Direction and state merge": {
+			a: report.EdgeMetadatas{
+				"hostA|:192.168.1.1:12345|:192.168.1.2:80": report.EdgeMetadata{
+					Direction:        report.Outbound,
+					ConnCountByState: map[string]uint64{"ESTABLISHED": 3, "TIME_WAIT": 1},
+				},
+			},
+			b: report.EdgeMetadatas{
+				"hostA|:192.168.1.1:12345|:192.168.1.2:80": report.EdgeMetadata{
+					Direction:        report.Inbound,
+					ConnCountByState: map[string]uint64{"ESTABLISHED": 2, "CLOSE_WAIT": 4},
+				},
+			},
+			want: report.EdgeMetadatas{
+				"hostA|:192.168.1.1:12345|:192.168.1.2:80": report.EdgeMetadata{
+					Direction:        report.Outbound | report.Inbound,
+					ConnCountByState: map[string]uint64{"ESTABLISHED": 3, "TIME_WAIT": 1, "CLOSE_WAIT": 4},
+				},
+			},
+		},
 	} {
 		have := c.a
 		have.Merge(c.b)
@@ -201,6 +221,35 @@ func TestMergeEdgeMetadatas(t *testing.T) {
 	}
 }
 
+func TestFlattenEdgeMetadata(t *testing.T) {
+	var (
+		a = report.EdgeMetadata{
+			MaxConnCountTCP:  newu64(2),
+			Direction:        report.Outbound,
+			ConnCountByState: map[string]uint64{"ESTABLISHED": 2},
+		}
+		b = report.EdgeMetadata{
+			MaxConnCountTCP:  newu64(3),
+			Direction:        report.Outbound,
+			ConnCountByState: map[string]uint64{"ESTABLISHED": 1, "TIME_WAIT": 2},
+		}
+		want = report.EdgeMetadata{
+			MaxConnCountTCP:  newu64(5),
+			Direction:        report.Outbound,
+			ConnCountByState: map[string]uint64{"ESTABLISHED": 3, "TIME_WAIT": 2},
+		}
+	)
+
+	have := a
+	have.Flatten(b)
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want\n\t%#v, have\n\t%#v", want, have)
+	}
+	if want, have := uint64(2), a.ConnCountByState["ESTABLISHED"]; want != have {
+		t.Errorf("Flatten modified its receiver's original map: want %d, have %d", want, have)
+	}
+}
+
 func TestMergeNodeMetadatas(t *testing.T) {
 	for name, c := range map[string]struct {
 		a, b, want report.NodeMetadatas
@@ -33,6 +33,30 @@ type Report struct {
 	// Edges are not present.
 	ContainerImage Topology
 
+	// Service nodes are the systemd units (services, scopes) that processes
+	// on hosts running probes belong to. Metadata includes things like the
+	// unit's description and state. Edges are not present.
+	Service Topology
+
+	// Pod nodes are the Kubernetes pods of the cluster the probes run in.
+	// Metadata includes things like the pod's namespace, IP and phase, and
+	// the services and deployment it belongs to. Edges are not present.
+	Pod Topology
+
+	// KubernetesService nodes are the Kubernetes services of the cluster.
+	// Metadata includes things like the service's cluster IP and ports.
+	// Edges are not present.
+	KubernetesService Topology
+
+	// Namespace nodes are the Kubernetes namespaces of the cluster. Edges
+	// are not present.
+	Namespace Topology
+
+	// Deployment nodes are the Kubernetes deployments of the cluster.
+	// Metadata includes things like the desired and available replicas.
+	// Edges are not present.
+	Deployment Topology
+
 	// Host nodes are physical hosts that run probes. Metadata includes things
 	// like operating system, load, etc. The information is scraped by the
 	// probes with each published report. Edges are not present.
@@ -40,7 +64,8 @@ type Report struct {
 
 	// Overlay nodes are active peers in any software-defined network that's
 	// overlaid on the infrastructure. The information is scraped by polling
-	// their status endpoints. Edges could be present, but aren't currently.
+	// their status endpoints. Edges are the established connections between
+	// peers.
 	Overlay Topology
 
 	// Sampling data for this report.
@@ -58,15 +83,20 @@ type Report struct {
 // MakeReport makes a clean report, ready to Merge() other reports into.
 func MakeReport() Report {
 	return Report{
-		Endpoint:       NewTopology(),
-		Address:        NewTopology(),
-		Process:        NewTopology(),
-		Container:      NewTopology(),
-		ContainerImage: NewTopology(),
-		Host:           NewTopology(),
-		Overlay:        NewTopology(),
-		Sampling:       Sampling{},
-		Window:         0,
+		Endpoint:          NewTopology(),
+		Address:           NewTopology(),
+		Process:           NewTopology(),
+		Container:         NewTopology(),
+		ContainerImage:    NewTopology(),
+		Service:           NewTopology(),
+		Pod:               NewTopology(),
+		KubernetesService: NewTopology(),
+		Namespace:         NewTopology(),
+		Deployment:        NewTopology(),
+		Host:              NewTopology(),
+		Overlay:           NewTopology(),
+		Sampling:          Sampling{},
+		Window:            0,
 	}
 }
 
@@ -78,6 +108,27 @@ func (r Report) Topologies() []Topology {
 		r.Process,
 		r.Container,
 		r.ContainerImage,
+		r.Service,
+		r.Pod,
+		r.KubernetesService,
+		r.Namespace,
+		r.Deployment,
+		r.Host,
+		r.Overlay,
+	}
+}
+
+// HostTopologies returns the Topologies whose nodes are on one host, i.e.
+// all of them except the cluster-wide Kubernetes topologies, whose nodes
+// every probe in the cluster reports alike.
+func (r Report) HostTopologies() []Topology {
+	return []Topology{
+		r.Endpoint,
+		r.Address,
+		r.Process,
+		r.Container,
+		r.ContainerImage,
+		r.Service,
 		r.Host,
 		r.Overlay,
 	}
@@ -148,6 +199,36 @@ func SelectContainerImage(r Report) Topology {
 	return r.ContainerImage
 }
 
+// SelectService selects the service topology.
+func SelectService(r Report) Topology {
+	return r.Service
+}
+
+// SelectPod selects the pod topology.
+func SelectPod(r Report) Topology {
+	return r.Pod
+}
+
+// SelectKubernetesService selects the Kubernetes service topology.
+func SelectKubernetesService(r Report) Topology {
+	return r.KubernetesService
+}
+
+// SelectNamespace selects the namespace topology.
+func SelectNamespace(r Report) Topology {
+	return r.Namespace
+}
+
+// SelectDeployment selects the deployment topology.
+func SelectDeployment(r Report) Topology {
+	return r.Deployment
+}
+
+// SelectOverlay selects the overlay topology.
+func SelectOverlay(r Report) Topology {
+	return r.Overlay
+}
+
 // SelectAddress selects the address topology.
 func SelectAddress(r Report) Topology {
 	return r.Address
@@ -36,6 +36,44 @@ type EdgeMetadata struct {
 	EgressByteCount    *uint64 `json:"egress_byte_count,omitempty"`  // Transport layer
 	IngressByteCount   *uint64 `json:"ingress_byte_count,omitempty"` // Transport layer
 	MaxConnCountTCP    *uint64 `json:"max_conn_count_tcp,omitempty"`
+
+	// Direction is the direction of the connections this edge represents,
+	// from the point of view of the source node.
+	Direction Direction `json:"direction,omitempty"`
+
+	// ConnCountByState counts TCP connections by their socket state, e.g.
+	// ESTABLISHED or TIME_WAIT. Like MaxConnCountTCP, each count is the
+	// maximum observed over the window.
+	ConnCountByState map[string]uint64 `json:"conn_count_by_state,omitempty"`
+}
+
+// Direction describes which side of a connection initiated it. It's a set of
+// flags, so that an edge built from both client and server observations can
+// carry both.
+type Direction uint8
+
+// Valid Directions.
+const (
+	// Outbound means the source node of the edge initiated the connection,
+	// i.e. it's the client.
+	Outbound Direction = 1 << iota
+
+	// Inbound means the source node of the edge accepted the connection,
+	// i.e. it's the server.
+	Inbound
+)
+
+// String returns the directions in d, e.g. "inbound, outbound", or "" if
+// it's unknown.
+func (d Direction) String() string {
+	directions := []string{}
+	if d&Inbound != 0 {
+		directions = append(directions, "inbound")
+	}
+	if d&Outbound != 0 {
+		directions = append(directions, "outbound")
+	}
+	return strings.Join(directions, ", ")
 }
 
 // NodeMetadata describes a superset of the metadata that probes can collect
@@ -111,12 +149,12 @@ func (t Topology) Validate() error {
 	}
 
 	// Check all node metadatas are valid, and the keys are parseable, i.e.
-	// contain a scope.
+	// contain a scope, and can be told apart in edge IDs.
 	for nodeID := range t.NodeMetadatas {
 		if t.NodeMetadatas[nodeID].Metadata == nil {
 			errs = append(errs, fmt.Sprintf("node ID %q has nil metadata", nodeID))
 		}
-		if _, _, ok := ParseNodeID(nodeID); !ok {
+		if _, _, ok := ParseNodeID(nodeID); !ok || strings.Contains(nodeID, EdgeDelim) {
 			errs = append(errs, fmt.Sprintf("invalid node ID %q", nodeID))
 		}
 	}
@@ -5,7 +5,10 @@ import (
 
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/overlay"
 	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/probe/systemd"
 	"github.com/weaveworks/scope/report"
 )
 
@@ -42,6 +45,8 @@ var (
 	ServerComm       = "apache"
 	NonContainerComm = "bash"
 
+	ServerProcessService = "web"
+
 	ClientHostNodeID = report.MakeHostNodeID(ClientHostID)
 	ServerHostNodeID = report.MakeHostNodeID(ServerHostID)
 
@@ -70,6 +75,24 @@ var (
 	ClientContainerImageName   = "image/client"
 	ServerContainerImageName   = "image/server"
 
+	ClientServiceUnit   = "cron.service"
+	ServerServiceUnit   = "apache2.service"
+	ClientServiceNodeID = report.MakeServiceNodeID(ClientHostID, ClientServiceUnit)
+	ServerServiceNodeID = report.MakeServiceNodeID(ServerHostID, ServerServiceUnit)
+
+	KubernetesNamespace           = "default"
+	ClientPodName                 = "client-1"
+	ServerPodName                 = "server-1"
+	ClientPodNodeID               = report.MakePodNodeID(KubernetesNamespace, ClientPodName)
+	ServerPodNodeID               = report.MakePodNodeID(KubernetesNamespace, ServerPodName)
+	ServerKubernetesServiceName   = "server"
+	ServerKubernetesServiceNodeID = report.MakeKubernetesServiceNodeID(KubernetesNamespace, ServerKubernetesServiceName)
+
+	ClientPeerName      = "ce:31:e0:06:45:1a"
+	ServerPeerName      = "2e:a1:b5:3d:9e:0c"
+	ClientOverlayNodeID = report.MakeOverlayNodeID(ClientPeerName)
+	ServerOverlayNodeID = report.MakeOverlayNodeID(ServerPeerName)
+
 	ClientAddressNodeID   = report.MakeAddressNodeID(ClientHostID, "10.10.10.20")
 	ServerAddressNodeID   = report.MakeAddressNodeID(ServerHostID, "192.168.1.1")
 	UnknownAddress1NodeID = report.MakeAddressNodeID(ServerHostID, "10.10.10.10")
@@ -147,19 +170,23 @@ var (
 					process.PID:        Client1PID,
 					"comm":             Client1Comm,
 					docker.ContainerID: ClientContainerID,
+					systemd.Unit:       ClientServiceUnit,
 					report.HostNodeID:  ClientHostNodeID,
 				}),
 				ClientProcess2NodeID: report.MakeNodeMetadataWith(map[string]string{
 					process.PID:        Client2PID,
 					"comm":             Client2Comm,
 					docker.ContainerID: ClientContainerID,
+					systemd.Unit:       ClientServiceUnit,
 					report.HostNodeID:  ClientHostNodeID,
 				}),
 				ServerProcessNodeID: report.MakeNodeMetadataWith(map[string]string{
-					process.PID:        ServerPID,
-					"comm":             ServerComm,
-					docker.ContainerID: ServerContainerID,
-					report.HostNodeID:  ServerHostNodeID,
+					process.PID:         ServerPID,
+					"comm":              ServerComm,
+					process.ServiceName: ServerProcessService,
+					docker.ContainerID:  ServerContainerID,
+					systemd.Unit:        ServerServiceUnit,
+					report.HostNodeID:   ServerHostNodeID,
 				}),
 				NonContainerProcessNodeID: report.MakeNodeMetadataWith(map[string]string{
 					process.PID:       NonContainerPID,
@@ -172,16 +199,20 @@ var (
 		Container: report.Topology{
 			NodeMetadatas: report.NodeMetadatas{
 				ClientContainerNodeID: report.MakeNodeMetadataWith(map[string]string{
-					docker.ContainerID:   ClientContainerID,
-					docker.ContainerName: "client",
-					docker.ImageID:       ClientContainerImageID,
-					report.HostNodeID:    ClientHostNodeID,
+					docker.ContainerID:       ClientContainerID,
+					docker.ContainerName:     "client",
+					docker.ImageID:           ClientContainerImageID,
+					kubernetes.NamespaceName: KubernetesNamespace,
+					kubernetes.PodName:       ClientPodName,
+					report.HostNodeID:        ClientHostNodeID,
 				}),
 				ServerContainerNodeID: report.MakeNodeMetadataWith(map[string]string{
-					docker.ContainerID:   ServerContainerID,
-					docker.ContainerName: "server",
-					docker.ImageID:       ServerContainerImageID,
-					report.HostNodeID:    ServerHostNodeID,
+					docker.ContainerID:       ServerContainerID,
+					docker.ContainerName:     "server",
+					docker.ImageID:           ServerContainerImageID,
+					kubernetes.NamespaceName: KubernetesNamespace,
+					kubernetes.PodName:       ServerPodName,
+					report.HostNodeID:        ServerHostNodeID,
 				}),
 			},
 		},
@@ -199,6 +230,44 @@ var (
 				}),
 			},
 		},
+		Service: report.Topology{
+			NodeMetadatas: report.NodeMetadatas{
+				ClientServiceNodeID: report.MakeNodeMetadataWith(map[string]string{
+					systemd.Unit:            ClientServiceUnit,
+					systemd.UnitDescription: "Regular background program processing daemon",
+					report.HostNodeID:       ClientHostNodeID,
+				}),
+				ServerServiceNodeID: report.MakeNodeMetadataWith(map[string]string{
+					systemd.Unit:            ServerServiceUnit,
+					systemd.UnitDescription: "The Apache HTTP Server",
+					report.HostNodeID:       ServerHostNodeID,
+				}),
+			},
+		},
+		Pod: report.Topology{
+			NodeMetadatas: report.NodeMetadatas{
+				ClientPodNodeID: report.MakeNodeMetadataWith(map[string]string{
+					kubernetes.NamespaceName: KubernetesNamespace,
+					kubernetes.PodName:       ClientPodName,
+					kubernetes.PodPhase:      "Running",
+				}),
+				ServerPodNodeID: report.MakeNodeMetadataWith(map[string]string{
+					kubernetes.NamespaceName: KubernetesNamespace,
+					kubernetes.PodName:       ServerPodName,
+					kubernetes.PodPhase:      "Running",
+					kubernetes.PodServices:   ServerKubernetesServiceName,
+				}),
+			},
+		},
+		KubernetesService: report.Topology{
+			NodeMetadatas: report.NodeMetadatas{
+				ServerKubernetesServiceNodeID: report.MakeNodeMetadataWith(map[string]string{
+					kubernetes.NamespaceName: KubernetesNamespace,
+					kubernetes.ServiceName:   ServerKubernetesServiceName,
+					kubernetes.ServicePods:   "1",
+				}),
+			},
+		},
 		Address: report.Topology{
 			Adjacency: report.Adjacency{
 				report.MakeAdjacencyID(ClientAddressNodeID): report.MakeIDList(ServerAddressNodeID),
@@ -224,6 +293,27 @@ var (
 				},
 			},
 		},
+		Overlay: report.Topology{
+			Adjacency: report.Adjacency{
+				report.MakeAdjacencyID(ClientOverlayNodeID): report.MakeIDList(ServerOverlayNodeID),
+				report.MakeAdjacencyID(ServerOverlayNodeID): report.MakeIDList(ClientOverlayNodeID),
+			},
+			NodeMetadatas: report.NodeMetadatas{
+				ClientOverlayNodeID: report.MakeNodeMetadataWith(map[string]string{
+					overlay.WeavePeerName:                          ClientPeerName,
+					overlay.WeavePeerNickName:                      ClientHostName,
+					overlay.WeaveConnectionPrefix + ServerPeerName: "established, encrypted",
+					report.HostNodeID:                              ClientHostNodeID,
+				}),
+				ServerOverlayNodeID: report.MakeNodeMetadataWith(map[string]string{
+					overlay.WeavePeerName:                          ServerPeerName,
+					overlay.WeavePeerNickName:                      ServerHostName,
+					overlay.WeaveConnectionPrefix + ClientPeerName: "established, encrypted",
+					report.HostNodeID:                              ServerHostNodeID,
+				}),
+			},
+			EdgeMetadatas: report.EdgeMetadatas{},
+		},
 		Host: report.Topology{
 			Adjacency: report.Adjacency{},
 			NodeMetadatas: report.NodeMetadatas{